@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/filecoin-project/lotus/build"
 	"github.com/libp2p/go-libp2p-core/network"
@@ -14,7 +15,9 @@ type Permission = string
 type Common interface {
 	// Auth
 	AuthVerify(ctx context.Context, token string) ([]Permission, error)
-	AuthNew(ctx context.Context, perms []Permission) ([]byte, error)
+	// AuthNew creates a token with the given permissions. If ttl is not zero,
+	// the token expires after ttl
+	AuthNew(ctx context.Context, perms []Permission, ttl time.Duration) ([]byte, error)
 
 	// network
 
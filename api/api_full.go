@@ -7,6 +7,7 @@ import (
 	"github.com/filecoin-project/lotus/chain/vm"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-filestore"
 	"github.com/libp2p/go-libp2p-core/peer"
@@ -41,7 +42,19 @@ type FullNode interface {
 	ChainGetNode(ctx context.Context, p string) (interface{}, error)
 	ChainGetMessage(context.Context, cid.Cid) (*types.Message, error)
 	ChainGetPath(ctx context.Context, from types.TipSetKey, to types.TipSetKey) ([]*store.HeadChange, error)
-	ChainExport(context.Context, types.TipSetKey) (<-chan []byte, error)
+	// ChainExport returns a stream of bytes with CAR dump of chain data.
+	// State trees are included for the last nroots tipsets, and messages of
+	// older tipsets are left out when oldmsgskip is set
+	ChainExport(ctx context.Context, nroots uint64, oldmsgskip bool, tsk types.TipSetKey) (<-chan []byte, error)
+
+	// ChainBackfillMsgIndex adds messages on the current chain to the message
+	// index, returning the number of tipsets indexed. It has to be run again
+	// when the index misses tipsets, e.g. after the node ran without it.
+	ChainBackfillMsgIndex(context.Context) (int, error)
+
+	// ChainPrune deletes blocks unreachable from the chain head from the
+	// chain blockstore, keeping state trees of the last keepStateRoots tipsets
+	ChainPrune(ctx context.Context, keepStateRoots uint64) (*store.PruneResult, error)
 
 	// syncer
 	SyncState(context.Context) (*SyncState, error)
@@ -56,6 +69,16 @@ type FullNode interface {
 	MpoolPushMessage(context.Context, *types.Message) (*types.SignedMessage, error) // get nonce, sign, push
 	MpoolGetNonce(context.Context, address.Address) (uint64, error)
 	MpoolSub(context.Context) (<-chan MpoolUpdate, error)
+	// MpoolReplace re-signs the pending message with the given CID with a
+	// higher GasPrice and pushes it in place of the original. If the price is
+	// zero, the minimum price accepted for replacement is used.
+	MpoolReplace(context.Context, cid.Cid, types.BigInt) (*types.SignedMessage, error)
+	// MpoolEstimateGas returns a copy of the message with the pending nonce
+	// of the sender and estimates for any unset GasLimit and GasPrice
+	MpoolEstimateGas(context.Context, *types.Message) (*types.Message, error)
+	// MpoolEstimateGasPrice suggests a GasPrice based on the gas prices paid
+	// by messages in recent tipsets
+	MpoolEstimateGasPrice(context.Context) (types.BigInt, error)
 
 	// FullNodeStruct
 
@@ -81,18 +104,38 @@ type FullNode interface {
 
 	// Other
 
-	// ClientImport imports file under the specified path into filestore
-	ClientImport(ctx context.Context, path string) (cid.Cid, error)
+	// ClientImport imports a file into the filestore, or the blocks of a CAR
+	// file into the client blockstore
+	ClientImport(ctx context.Context, ref FileRef) (cid.Cid, error)
 	ClientStartDeal(ctx context.Context, data cid.Cid, addr address.Address, miner address.Address, epochPrice types.BigInt, blocksDuration uint64) (*cid.Cid, error)
 	ClientGetDealInfo(context.Context, cid.Cid) (*DealInfo, error)
-	ClientListDeals(ctx context.Context) ([]DealInfo, error)
+	// ClientListDeals lists the deals made by the client which match the filter
+	ClientListDeals(ctx context.Context, filter DealFilter) ([]DealInfo, error)
+	// ClientDealNotify returns a channel with the current state of all deals,
+	// followed by updates of deals when they change
+	ClientDealNotify(ctx context.Context) (<-chan DealInfo, error)
 	ClientHasLocal(ctx context.Context, root cid.Cid) (bool, error)
 	ClientFindData(ctx context.Context, root cid.Cid) ([]QueryOffer, error)
-	ClientRetrieve(ctx context.Context, order RetrievalOrder, path string) error
+	// ClientRetrieve retrieves the DAG under the order root and writes the
+	// data selected by the order to the referenced file, or as a CAR file if
+	// ref.IsCAR is set. The whole DAG is transferred and paid for, the order
+	// path and range only filter the output
+	ClientRetrieve(ctx context.Context, order RetrievalOrder, ref FileRef) error
+	// ClientRetrieveWithEvents is like ClientRetrieve, but streams the
+	// progress of the retrieval deal. The last event either has Event set to
+	// ClientEventComplete, once the data is written, or has Err set
+	ClientRetrieveWithEvents(ctx context.Context, order RetrievalOrder, ref FileRef) (<-chan RetrievalEvent, error)
+	// ClientRetrieveAuto retrieves data from the best ranked provider which
+	// offers it, failing over to the next one on error or stall. Events of
+	// failed attempts have Err set, the channel is closed after the
+	// ClientEventComplete event or once no provider is left to try
+	ClientRetrieveAuto(ctx context.Context, params AutoRetrievalParams, ref FileRef) (<-chan RetrievalEvent, error)
 	ClientQueryAsk(ctx context.Context, p peer.ID, miner address.Address) (*types.SignedStorageAsk, error)
 
-	// ClientUnimport removes references to the specified file from filestore
-	//ClientUnimport(path string)
+	// ClientRemoveImport removes imported data from the client blockstore,
+	// keeping blocks shared with other imports. Data of deals which may still
+	// be transferred can't be removed.
+	ClientRemoveImport(ctx context.Context, root cid.Cid) error
 
 	// ClientListImports lists imported files and their root CIDs
 	ClientListImports(ctx context.Context) ([]Import, error)
@@ -101,6 +144,10 @@ type FullNode interface {
 
 	// if tipset is nil, we'll use heaviest
 	StateCall(context.Context, *types.Message, types.TipSetKey) (*InvocResult, error)
+	// StateEstimateGas executes the message on top of the state at the end of
+	// the given tipset and returns the gas it used plus a safety margin. The
+	// message nonce must be the nonce of the sender in that state.
+	StateEstimateGas(context.Context, *types.Message, types.TipSetKey) (types.BigInt, error)
 	StateReplay(context.Context, types.TipSetKey, cid.Cid) (*InvocResult, error)
 	StateGetActor(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*types.Actor, error)
 	StateReadState(ctx context.Context, act *types.Actor, tsk types.TipSetKey) (*ActorState, error)
@@ -157,6 +204,15 @@ type Import struct {
 	Key      cid.Cid
 	FilePath string
 	Size     uint64
+
+	CAR      bool
+	Imported time.Time
+	Deals    []cid.Cid // proposal CIDs of deals made with the data
+}
+
+type FileRef struct {
+	Path  string
+	IsCAR bool // the file is a CAR file with a single root
 }
 
 type DealInfo struct {
@@ -171,6 +227,22 @@ type DealInfo struct {
 	Duration      uint64
 
 	DealID uint64
+
+	CreationTime    time.Time // zero for deals made by older versions
+	SectorID        uint64    // 0 until the sector with the deal is committed
+	ActivationEpoch uint64    // 0 until the deal is active on chain
+	Expiration      uint64    // epoch the deal ends at once active
+	Message         string    // why the deal failed
+}
+
+// DealFilter selects deals in ClientListDeals. Zero values match all deals.
+type DealFilter struct {
+	States   []DealState
+	Provider address.Address
+
+	// CreatedAfter and CreatedBefore select deals by their creation time
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
 }
 
 type MsgWait struct {
@@ -264,17 +336,54 @@ func (o *QueryOffer) Order(client address.Address) RetrievalOrder {
 }
 
 type RetrievalOrder struct {
-	// TODO: make this less unixfs specific
-	Root cid.Cid
-	Size uint64
-	// TODO: support offset
+	Root  cid.Cid
+	Size  uint64
 	Total types.BigInt
 
+	// Path selects the file or directory within a unixfs DAG which is written
+	// out, empty for the whole DAG under Root. The whole DAG is retrieved
+	// either way, as the retrieval market doesn't take selectors yet
+	Path string
+	// Offset and Length select the byte range of a unixfs file which is
+	// written out, Length 0 reads to the end of the file
+	Offset uint64
+	Length uint64
+
 	Client      address.Address
 	Miner       address.Address
 	MinerPeerID peer.ID
 }
 
+type RetrievalEvent struct {
+	Event  retrievalmarket.ClientEvent
+	Status retrievalmarket.DealStatus
+	DealID retrievalmarket.DealID
+	Miner  address.Address
+
+	Size          uint64
+	BytesReceived uint64
+	FundsSpent    types.BigInt
+
+	Err string
+}
+
+type AutoRetrievalParams struct {
+	Root cid.Cid
+
+	// Path, Offset and Length select the data like in RetrievalOrder
+	Path   string
+	Offset uint64
+	Length uint64
+
+	Client address.Address
+	// MaxPrice caps the funds spent on all attempts. Attempts abandoned on
+	// stall stop paying, but count with all the funds of their deal
+	MaxPrice types.BigInt
+	// StallTimeout is how long a provider may make no progress before the
+	// next one is tried, 0 never gives up on a provider
+	StallTimeout time.Duration
+}
+
 type InvocResult struct {
 	Msg                *types.Message
 	MsgRct             *types.MessageReceipt
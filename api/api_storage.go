@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"time"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-sectorbuilder"
@@ -24,8 +25,8 @@ const (
 	CommitWait // waiting for message to land on chain
 	FinalizeSector
 	Proving
-	_ // reserved
-	_
+	WaitDeals // waiting for more pieces (deals) to be added to the sector
+	_         // reserved
 	_
 
 	// recovery handling
@@ -46,13 +47,16 @@ const (
 	SealCommitFailed
 	CommitFailed
 	PackingFailed
-	_
+	FinalizeFailed
 	_
 	_
 
 	Faulty        // sector is corrupted or gone for some reason
 	FaultReported // sector has been declared as a fault on chain
 	FaultedFinal  // fault declared on chain
+
+	Recovering       // faulty sector is readable again, declaring recovery on chain
+	RecoveryReported // recovery has been declared on chain
 )
 
 var SectorStates = []string{
@@ -66,18 +70,23 @@ var SectorStates = []string{
 	CommitWait:           "CommitWait",
 	FinalizeSector:       "FinalizeSector",
 	Proving:              "Proving",
+	WaitDeals:            "WaitDeals",
 
 	SealFailed:       "SealFailed",
 	PreCommitFailed:  "PreCommitFailed",
 	SealCommitFailed: "SealCommitFailed",
 	CommitFailed:     "CommitFailed",
 	PackingFailed:    "PackingFailed",
+	FinalizeFailed:   "FinalizeFailed",
 
 	FailedUnrecoverable: "FailedUnrecoverable",
 
 	Faulty:        "Faulty",
 	FaultReported: "FaultReported",
 	FaultedFinal:  "FaultedFinal",
+
+	Recovering:       "Recovering",
+	RecoveryReported: "RecoveryReported",
 }
 
 // StorageMiner is a low-level interface to the Filecoin network storage miner node
@@ -101,12 +110,116 @@ type StorageMiner interface {
 
 	SectorsUpdate(context.Context, uint64, SectorState) error
 
+	// SectorsRecover checks that a faulty sector is readable again, and
+	// declares it recovered on chain
+	SectorsRecover(context.Context, uint64) error
+
+	// SectorsNotify streams sector state transitions. Updates are dropped
+	// when the client doesn't keep up with them.
+	SectorsNotify(context.Context) (<-chan SectorUpdate, error)
+
+	// SectorRemove deletes the data and the state of a failed sector which
+	// isn't committed on chain. With terminate set, a committed sector is
+	// terminated on chain first, losing its power.
+	SectorRemove(ctx context.Context, id uint64, terminate bool) error
+
 	WorkerStats(context.Context) (sectorbuilder.WorkerStats, error)
 
-	// WorkerQueue registers a remote worker
+	// WorkerQueue registers a remote worker which doesn't declare its
+	// resources. It is given one task at a time.
 	WorkerQueue(context.Context, sectorbuilder.WorkerCfg) (<-chan sectorbuilder.WorkerTask, error)
 
+	// WorkerConnect registers a remote worker with the resources it declares,
+	// and returns the tasks assigned to it
+	WorkerConnect(context.Context, WorkerInfo) (<-chan WorkerTask, error)
+
 	WorkerDone(ctx context.Context, task uint64, res sectorbuilder.SealRes) error
+
+	// WorkerList lists workers connected to the scheduler, and the tasks
+	// they are running
+	WorkerList(context.Context) ([]WorkerState, error)
+
+	// StorageAttach adds a storage path for sector data. The path can hold
+	// moved sectors right away, and is used for new sectors after a restart.
+	StorageAttach(ctx context.Context, path string, weight int, cache bool) error
+
+	// StorageDetach removes a storage path holding no sector data
+	StorageDetach(ctx context.Context, path string) error
+
+	StorageList(context.Context) ([]StoragePathInfo, error)
+
+	// StorageMove moves the sealed sector and cache of a proving sector to
+	// another storage path
+	StorageMove(ctx context.Context, sector uint64, path string) error
+
+	// ProvingCheck runs the checks of a fallback PoSt over the current
+	// proving set without submitting anything. With generate set, a full
+	// proof is generated locally to time it.
+	ProvingCheck(ctx context.Context, generate bool) (*ProvingCheck, error)
+}
+
+// WorkerTaskType is a kind of task the sealing scheduler assigns to workers
+type WorkerTaskType string
+
+const (
+	TaskPreCommit WorkerTaskType = "precommit"
+	TaskCommit    WorkerTaskType = "commit"
+	TaskUnseal    WorkerTaskType = "unseal"
+	TaskFetch     WorkerTaskType = "fetch" // sector data transfer
+)
+
+// WorkerInfo describes the resources of a worker
+type WorkerInfo struct {
+	Hostname string
+
+	Cores     uint64
+	Memory    uint64 // bytes, 0 if unknown
+	Transfers uint64 // concurrent sector transfers
+
+	Paths []WorkerPath
+
+	TaskTypes []WorkerTaskType
+}
+
+type WorkerPath struct {
+	Path string
+
+	Capacity  uint64
+	Available uint64
+}
+
+type WorkerTask struct {
+	TaskID   uint64
+	Type     WorkerTaskType
+	SectorID uint64
+
+	// Seal holds the parameters of precommit and commit tasks
+	Seal sectorbuilder.WorkerTask
+}
+
+// WorkerState is a worker as seen by the scheduler
+type WorkerState struct {
+	ID    uint64
+	Local bool // tasks run in the miner process
+	Info  WorkerInfo
+
+	CoresUsed     uint64
+	MemoryUsed    uint64
+	DiskUsed      uint64
+	TransfersUsed uint64
+
+	Tasks []WorkerTaskInfo
+}
+
+type WorkerTaskInfo struct {
+	TaskID   uint64
+	Type     WorkerTaskType
+	SectorID uint64
+	Started  time.Time
+
+	// Progress and Size are the transferred and total bytes of fetch tasks
+	Progress uint64
+	Size     uint64
 }
 
 type SectorLog struct {
@@ -125,6 +238,7 @@ type SectorInfo struct {
 	CommR    []byte
 	Proof    []byte
 	Deals    []uint64
+	Pieces   []SectorPiece
 	Ticket   sectorbuilder.SealTicket
 	Seed     sectorbuilder.SealSeed
 	Retries  uint64
@@ -134,6 +248,13 @@ type SectorInfo struct {
 	Log []SectorLog
 }
 
+// SectorPiece is a piece of data in a sector, either a deal or a filler
+type SectorPiece struct {
+	DealID uint64
+	Size   uint64
+	CommP  []byte
+}
+
 type SealedRef struct {
 	SectorID uint64
 	Offset   uint64
@@ -143,3 +264,59 @@ type SealedRef struct {
 type SealedRefs struct {
 	Refs []SealedRef
 }
+
+// SectorUpdate is a transition of the sector state machine, caused by an
+// event
+type SectorUpdate struct {
+	SectorID uint64
+	From     SectorState
+	To       SectorState
+
+	Event string // type of the event, like SectorSealed
+	Error string // set for events carrying an error
+
+	Timestamp time.Time
+}
+
+// StoragePathInfo describes a storage path of the miner, and its disk usage
+type StoragePathInfo struct {
+	Path   string
+	Weight int
+	Cache  bool
+
+	// Active is set when the sectorbuilder uses the path for new sectors.
+	// Paths attached at runtime become active after a restart.
+	Active bool
+
+	Capacity  uint64
+	Available uint64
+	Used      uint64 // bytes of sector data stored in the path
+
+	Sectors []uint64 // sectors with their sealed data in the path
+}
+
+// ProvingCheck is the result of a fallback PoSt dry run
+type ProvingCheck struct {
+	Height              uint64
+	ElectionPeriodStart uint64
+	ChallengeHeight     uint64 // epoch the challenge is drawn from
+	StartHeight         uint64 // epoch at which the proof is generated
+	Deadline            uint64 // epoch by which the proof has to land
+
+	Sectors        uint64 // in the proving set
+	DeclaredFaults []uint64
+	Faults         []ProvingFault // sectors which would be declared faulty
+
+	ScrubTime time.Duration
+	// EstimatedProofTime is based on the last generated proof, zero if no
+	// proof was generated since the miner started
+	EstimatedProofTime time.Duration
+
+	ProofTime time.Duration
+	ProofErr  string
+}
+
+type ProvingFault struct {
+	SectorID uint64
+	Err      string
+}
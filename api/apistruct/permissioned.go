@@ -3,9 +3,11 @@ package apistruct
 import (
 	"context"
 	"reflect"
+	"strings"
 
 	"golang.org/x/xerrors"
 
+	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/lotus/api"
 )
 
@@ -20,11 +22,58 @@ const (
 	PermWrite api.Permission = "write"
 	PermSign  api.Permission = "sign"  // Use wallet keys for signing
 	PermAdmin api.Permission = "admin" // Manage permissions
+
+	// Scoped permissions only give access to a small set of methods, and are
+	// implied by the basic permissions above
+
+	PermWorker    api.Permission = "worker"     // Fetch and complete seal tasks
+	PermMpoolPush api.Permission = "mpool-push" // Push signed messages
+
+	// walletPermPrefix prefixes permissions to sign with a single address
+	walletPermPrefix = "sign:"
 )
 
-var AllPermissions = []api.Permission{PermRead, PermWrite, PermSign, PermAdmin}
+var BasicPermissions = []api.Permission{PermRead, PermWrite, PermSign, PermAdmin}
+var ScopedPermissions = []api.Permission{PermWorker, PermMpoolPush}
+var AllPermissions = append(append([]api.Permission{}, BasicPermissions...), ScopedPermissions...)
 var defaultPerms = []api.Permission{PermRead}
 
+// impliedPerms lists the scoped permissions granted by basic permissions
+var impliedPerms = map[api.Permission][]api.Permission{
+	PermWrite: {PermMpoolPush},
+	PermAdmin: {PermWorker},
+}
+
+// walletScoped lists methods requiring PermSign which can also be called
+// with a wallet permission for the address passed as their first argument
+var walletScoped = map[string]bool{
+	"WalletSign":        true,
+	"WalletSignMessage": true,
+}
+
+// WalletPerm returns the permission to sign with the given address only
+func WalletPerm(addr address.Address) api.Permission {
+	return api.Permission(walletPermPrefix + addr.String())
+}
+
+// ValidPerm checks that a permission is known, or is a valid wallet permission
+func ValidPerm(perm api.Permission) error {
+	for _, p := range AllPermissions {
+		if perm == p {
+			return nil
+		}
+	}
+
+	if strings.HasPrefix(string(perm), walletPermPrefix) {
+		if _, err := address.NewFromString(strings.TrimPrefix(string(perm), walletPermPrefix)); err != nil {
+			return xerrors.Errorf("invalid address in wallet permission '%s': %w", perm, err)
+		}
+		return nil
+	}
+
+	return xerrors.Errorf("unknown permission '%s'", perm)
+}
+
 func WithPerm(ctx context.Context, perms []api.Permission) context.Context {
 	return context.WithValue(ctx, permCtxKey, perms)
 }
@@ -53,10 +102,29 @@ func HasPerm(ctx context.Context, perm api.Permission) bool {
 		if callerPerm == perm {
 			return true
 		}
+
+		for _, implied := range impliedPerms[callerPerm] {
+			if implied == perm {
+				return true
+			}
+		}
 	}
 	return false
 }
 
+func hasWalletPerm(ctx context.Context, args []reflect.Value) bool {
+	if len(args) < 2 {
+		return false
+	}
+
+	addr, ok := args[1].Interface().(address.Address)
+	if !ok {
+		return false
+	}
+
+	return HasPerm(ctx, WalletPerm(addr))
+}
+
 func permissionedAny(in interface{}, out interface{}) {
 	rint := reflect.ValueOf(out).Elem()
 	ra := reflect.ValueOf(in)
@@ -88,6 +156,10 @@ func permissionedAny(in interface{}, out interface{}) {
 				return fn.Call(args)
 			}
 
+			if walletScoped[field.Name] && hasWalletPerm(ctx, args) {
+				return fn.Call(args)
+			}
+
 			err := xerrors.Errorf("missing permission to invoke '%s' (need '%s')", field.Name, requiredPerm)
 			rerr := reflect.ValueOf(&err).Elem()
 
@@ -0,0 +1,92 @@
+package apistruct
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/api"
+)
+
+type permTestImpl struct{}
+
+func (permTestImpl) WalletSign(ctx context.Context, addr address.Address, msg []byte) error {
+	return nil
+}
+
+func (permTestImpl) MpoolPush(ctx context.Context) error {
+	return nil
+}
+
+func (permTestImpl) WorkerDone(ctx context.Context) error {
+	return nil
+}
+
+type permTestStruct struct {
+	Internal struct {
+		WalletSign func(context.Context, address.Address, []byte) error `perm:"sign"`
+		MpoolPush  func(context.Context) error                          `perm:"mpool-push"`
+		WorkerDone func(context.Context) error                          `perm:"worker"`
+	}
+}
+
+func TestScopedPermissions(t *testing.T) {
+	var out permTestStruct
+	permissionedAny(permTestImpl{}, &out.Internal)
+
+	allowed, err := address.NewIDAddress(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := address.NewIDAddress(101)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withPerms := func(perms ...api.Permission) context.Context {
+		return WithPerm(context.Background(), perms)
+	}
+
+	check := func(name string, err error, expectOk bool) {
+		t.Helper()
+		if expectOk && err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+		}
+		if !expectOk && err == nil {
+			t.Errorf("%s: expected permission error", name)
+		}
+	}
+
+	check("read cannot push", out.Internal.MpoolPush(withPerms(PermRead)), false)
+	check("write implies mpool-push", out.Internal.MpoolPush(withPerms(PermRead, PermWrite)), true)
+	check("mpool-push can push", out.Internal.MpoolPush(withPerms(PermRead, PermMpoolPush)), true)
+	check("mpool-push cannot sign", out.Internal.WalletSign(withPerms(PermRead, PermMpoolPush), allowed, nil), false)
+
+	check("admin implies worker", out.Internal.WorkerDone(withPerms(PermAdmin)), true)
+	check("worker can complete tasks", out.Internal.WorkerDone(withPerms(PermRead, PermWorker)), true)
+	check("sign doesn't imply worker", out.Internal.WorkerDone(withPerms(PermRead, PermWrite, PermSign)), false)
+
+	wctx := withPerms(PermRead, WalletPerm(allowed))
+	check("whitelisted wallet can sign", out.Internal.WalletSign(wctx, allowed, nil), true)
+	check("other wallet cannot sign", out.Internal.WalletSign(wctx, other, nil), false)
+	check("sign can use any wallet", out.Internal.WalletSign(withPerms(PermSign), other, nil), true)
+}
+
+func TestValidPerm(t *testing.T) {
+	addr, err := address.NewIDAddress(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range append(AllPermissions, WalletPerm(addr)) {
+		if err := ValidPerm(p); err != nil {
+			t.Errorf("%s: %s", p, err)
+		}
+	}
+
+	for _, p := range []api.Permission{"", "root", "sign:notanaddress"} {
+		if err := ValidPerm(p); err == nil {
+			t.Errorf("expected '%s' to be invalid", p)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package apistruct
 
 import (
 	"context"
+	"time"
 
 	sectorbuilder "github.com/filecoin-project/go-sectorbuilder"
 
@@ -21,8 +22,8 @@ var _ = AllPermissions
 
 type CommonStruct struct {
 	Internal struct {
-		AuthVerify func(ctx context.Context, token string) ([]api.Permission, error) `perm:"read"`
-		AuthNew    func(ctx context.Context, perms []api.Permission) ([]byte, error) `perm:"admin"`
+		AuthVerify func(ctx context.Context, token string) ([]api.Permission, error)                    `perm:"read"`
+		AuthNew    func(ctx context.Context, perms []api.Permission, ttl time.Duration) ([]byte, error) `perm:"admin"`
 
 		NetConnectedness func(context.Context, peer.ID) (network.Connectedness, error) `perm:"read"`
 		NetPeers         func(context.Context) ([]peer.AddrInfo, error)                `perm:"read"`
@@ -61,7 +62,9 @@ type FullNodeStruct struct {
 		ChainGetNode           func(ctx context.Context, p string) (interface{}, error)                             `perm:"read"`
 		ChainGetMessage        func(context.Context, cid.Cid) (*types.Message, error)                               `perm:"read"`
 		ChainGetPath           func(context.Context, types.TipSetKey, types.TipSetKey) ([]*store.HeadChange, error) `perm:"read"`
-		ChainExport            func(context.Context, types.TipSetKey) (<-chan []byte, error)                          `perm:"read"`
+		ChainExport            func(context.Context, uint64, bool, types.TipSetKey) (<-chan []byte, error)          `perm:"read"`
+		ChainBackfillMsgIndex  func(context.Context) (int, error)                                                   `perm:"admin"`
+		ChainPrune             func(context.Context, uint64) (*store.PruneResult, error)                            `perm:"admin"`
 
 		SyncState          func(context.Context) (*api.SyncState, error)                `perm:"read"`
 		SyncSubmitBlock    func(ctx context.Context, blk *types.BlockMsg) error         `perm:"write"`
@@ -69,11 +72,14 @@ type FullNodeStruct struct {
 		SyncMarkBad        func(ctx context.Context, bcid cid.Cid) error                `perm:"admin"`
 		SyncCheckBad       func(ctx context.Context, bcid cid.Cid) (string, error)      `perm:"read"`
 
-		MpoolPending     func(context.Context, types.TipSetKey) ([]*types.SignedMessage, error) `perm:"read"`
-		MpoolPush        func(context.Context, *types.SignedMessage) (cid.Cid, error)         `perm:"write"`
-		MpoolPushMessage func(context.Context, *types.Message) (*types.SignedMessage, error)  `perm:"sign"`
-		MpoolGetNonce    func(context.Context, address.Address) (uint64, error)               `perm:"read"`
-		MpoolSub         func(context.Context) (<-chan api.MpoolUpdate, error)                `perm:"read"`
+		MpoolPending          func(context.Context, types.TipSetKey) ([]*types.SignedMessage, error)     `perm:"read"`
+		MpoolPush             func(context.Context, *types.SignedMessage) (cid.Cid, error)               `perm:"mpool-push"`
+		MpoolPushMessage      func(context.Context, *types.Message) (*types.SignedMessage, error)        `perm:"sign"`
+		MpoolGetNonce         func(context.Context, address.Address) (uint64, error)                     `perm:"read"`
+		MpoolSub              func(context.Context) (<-chan api.MpoolUpdate, error)                      `perm:"read"`
+		MpoolReplace          func(context.Context, cid.Cid, types.BigInt) (*types.SignedMessage, error) `perm:"sign"`
+		MpoolEstimateGas      func(context.Context, *types.Message) (*types.Message, error)              `perm:"read"`
+		MpoolEstimateGasPrice func(context.Context) (types.BigInt, error)                                `perm:"read"`
 
 		MinerCreateBlock func(context.Context, address.Address, types.TipSetKey, *types.Ticket, *types.EPostProof, []*types.SignedMessage, uint64, uint64) (*types.BlockMsg, error) `perm:"write"`
 
@@ -89,36 +95,41 @@ type FullNodeStruct struct {
 		WalletExport         func(context.Context, address.Address) (*types.KeyInfo, error)                       `perm:"admin"`
 		WalletImport         func(context.Context, *types.KeyInfo) (address.Address, error)                       `perm:"admin"`
 
-		ClientImport      func(ctx context.Context, path string) (cid.Cid, error)                                                                                           `perm:"admin"`
-		ClientListImports func(ctx context.Context) ([]api.Import, error)                                                                                                   `perm:"write"`
-		ClientHasLocal    func(ctx context.Context, root cid.Cid) (bool, error)                                                                                             `perm:"write"`
-		ClientFindData    func(ctx context.Context, root cid.Cid) ([]api.QueryOffer, error)                                                                                 `perm:"read"`
-		ClientStartDeal   func(ctx context.Context, data cid.Cid, addr address.Address, miner address.Address, price types.BigInt, blocksDuration uint64) (*cid.Cid, error) `perm:"admin"`
-		ClientGetDealInfo func(context.Context, cid.Cid) (*api.DealInfo, error)                                                                                             `perm:"read"`
-		ClientListDeals   func(ctx context.Context) ([]api.DealInfo, error)                                                                                                 `perm:"write"`
-		ClientRetrieve    func(ctx context.Context, order api.RetrievalOrder, path string) error                                                                            `perm:"admin"`
-		ClientQueryAsk    func(ctx context.Context, p peer.ID, miner address.Address) (*types.SignedStorageAsk, error)                                                      `perm:"read"`
-
-		StateMinerSectors             func(context.Context, address.Address, types.TipSetKey) ([]*api.ChainSectorInfo, error)           `perm:"read"`
-		StateMinerProvingSet          func(context.Context, address.Address, types.TipSetKey) ([]*api.ChainSectorInfo, error)           `perm:"read"`
-		StateMinerPower               func(context.Context, address.Address, types.TipSetKey) (api.MinerPower, error)                   `perm:"read"`
-		StateMinerWorker              func(context.Context, address.Address, types.TipSetKey) (address.Address, error)                  `perm:"read"`
-		StateMinerPeerID              func(ctx context.Context, m address.Address, tsk types.TipSetKey) (peer.ID, error)                `perm:"read"`
-		StateMinerElectionPeriodStart func(ctx context.Context, actor address.Address, tsk types.TipSetKey) (uint64, error)             `perm:"read"`
-		StateMinerSectorSize          func(context.Context, address.Address, types.TipSetKey) (uint64, error)                           `perm:"read"`
-		StateMinerFaults              func(context.Context, address.Address, types.TipSetKey) ([]uint64, error)                         `perm:"read"`
-		StateCall                     func(context.Context, *types.Message, types.TipSetKey) (*api.InvocResult, error)                  `perm:"read"`
-		StateReplay                   func(context.Context, types.TipSetKey, cid.Cid) (*api.InvocResult, error)                         `perm:"read"`
-		StateGetActor                 func(context.Context, address.Address, types.TipSetKey) (*types.Actor, error)                     `perm:"read"`
-		StateReadState                func(context.Context, *types.Actor, types.TipSetKey) (*api.ActorState, error)                     `perm:"read"`
-		StatePledgeCollateral         func(context.Context, types.TipSetKey) (types.BigInt, error)                                      `perm:"read"`
-		StateWaitMsg                  func(context.Context, cid.Cid) (*api.MsgWait, error)                                              `perm:"read"`
-		StateListMiners               func(context.Context, types.TipSetKey) ([]address.Address, error)                                 `perm:"read"`
-		StateListActors               func(context.Context, types.TipSetKey) ([]address.Address, error)                                 `perm:"read"`
-		StateMarketBalance            func(context.Context, address.Address, types.TipSetKey) (actors.StorageParticipantBalance, error) `perm:"read"`
-		StateMarketParticipants       func(context.Context, types.TipSetKey) (map[string]actors.StorageParticipantBalance, error)       `perm:"read"`
-		StateMarketDeals              func(context.Context, types.TipSetKey) (map[string]actors.OnChainDeal, error)                     `perm:"read"`
-		StateMarketStorageDeal        func(context.Context, uint64, types.TipSetKey) (*actors.OnChainDeal, error)                       `perm:"read"`
+		ClientImport             func(ctx context.Context, ref api.FileRef) (cid.Cid, error)                                                                                       `perm:"admin"`
+		ClientListImports        func(ctx context.Context) ([]api.Import, error)                                                                                                   `perm:"write"`
+		ClientRemoveImport       func(ctx context.Context, root cid.Cid) error                                                                                                     `perm:"admin"`
+		ClientHasLocal           func(ctx context.Context, root cid.Cid) (bool, error)                                                                                             `perm:"write"`
+		ClientFindData           func(ctx context.Context, root cid.Cid) ([]api.QueryOffer, error)                                                                                 `perm:"read"`
+		ClientStartDeal          func(ctx context.Context, data cid.Cid, addr address.Address, miner address.Address, price types.BigInt, blocksDuration uint64) (*cid.Cid, error) `perm:"admin"`
+		ClientGetDealInfo        func(context.Context, cid.Cid) (*api.DealInfo, error)                                                                                             `perm:"read"`
+		ClientListDeals          func(ctx context.Context, filter api.DealFilter) ([]api.DealInfo, error)                                                                          `perm:"write"`
+		ClientDealNotify         func(ctx context.Context) (<-chan api.DealInfo, error)                                                                                            `perm:"write"`
+		ClientRetrieve           func(ctx context.Context, order api.RetrievalOrder, ref api.FileRef) error                                                                        `perm:"admin"`
+		ClientRetrieveWithEvents func(ctx context.Context, order api.RetrievalOrder, ref api.FileRef) (<-chan api.RetrievalEvent, error)                                           `perm:"admin"`
+		ClientRetrieveAuto       func(ctx context.Context, params api.AutoRetrievalParams, ref api.FileRef) (<-chan api.RetrievalEvent, error)                                     `perm:"admin"`
+		ClientQueryAsk           func(ctx context.Context, p peer.ID, miner address.Address) (*types.SignedStorageAsk, error)                                                      `perm:"read"`
+
+		StateMinerSectors             func(context.Context, address.Address, types.TipSetKey) ([]*api.ChainSectorInfo, error)              `perm:"read"`
+		StateMinerProvingSet          func(context.Context, address.Address, types.TipSetKey) ([]*api.ChainSectorInfo, error)              `perm:"read"`
+		StateMinerPower               func(context.Context, address.Address, types.TipSetKey) (api.MinerPower, error)                      `perm:"read"`
+		StateMinerWorker              func(context.Context, address.Address, types.TipSetKey) (address.Address, error)                     `perm:"read"`
+		StateMinerPeerID              func(ctx context.Context, m address.Address, tsk types.TipSetKey) (peer.ID, error)                   `perm:"read"`
+		StateMinerElectionPeriodStart func(ctx context.Context, actor address.Address, tsk types.TipSetKey) (uint64, error)                `perm:"read"`
+		StateMinerSectorSize          func(context.Context, address.Address, types.TipSetKey) (uint64, error)                              `perm:"read"`
+		StateMinerFaults              func(context.Context, address.Address, types.TipSetKey) ([]uint64, error)                            `perm:"read"`
+		StateCall                     func(context.Context, *types.Message, types.TipSetKey) (*api.InvocResult, error)                     `perm:"read"`
+		StateEstimateGas              func(context.Context, *types.Message, types.TipSetKey) (types.BigInt, error)                         `perm:"read"`
+		StateReplay                   func(context.Context, types.TipSetKey, cid.Cid) (*api.InvocResult, error)                            `perm:"read"`
+		StateGetActor                 func(context.Context, address.Address, types.TipSetKey) (*types.Actor, error)                        `perm:"read"`
+		StateReadState                func(context.Context, *types.Actor, types.TipSetKey) (*api.ActorState, error)                        `perm:"read"`
+		StatePledgeCollateral         func(context.Context, types.TipSetKey) (types.BigInt, error)                                         `perm:"read"`
+		StateWaitMsg                  func(context.Context, cid.Cid) (*api.MsgWait, error)                                                 `perm:"read"`
+		StateListMiners               func(context.Context, types.TipSetKey) ([]address.Address, error)                                    `perm:"read"`
+		StateListActors               func(context.Context, types.TipSetKey) ([]address.Address, error)                                    `perm:"read"`
+		StateMarketBalance            func(context.Context, address.Address, types.TipSetKey) (actors.StorageParticipantBalance, error)    `perm:"read"`
+		StateMarketParticipants       func(context.Context, types.TipSetKey) (map[string]actors.StorageParticipantBalance, error)          `perm:"read"`
+		StateMarketDeals              func(context.Context, types.TipSetKey) (map[string]actors.OnChainDeal, error)                        `perm:"read"`
+		StateMarketStorageDeal        func(context.Context, uint64, types.TipSetKey) (*actors.OnChainDeal, error)                          `perm:"read"`
 		StateLookupID                 func(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error)        `perm:"read"`
 		StateChangedActors            func(context.Context, cid.Cid, cid.Cid) (map[string]types.Actor, error)                           `perm:"read"`
 		StateGetReceipt               func(context.Context, cid.Cid, types.TipSetKey) (*types.MessageReceipt, error)                      `perm:"read"`
@@ -159,15 +170,27 @@ type StorageMinerStruct struct {
 
 		PledgeSector func(context.Context) error `perm:"write"`
 
-		SectorsStatus func(context.Context, uint64) (api.SectorInfo, error)     `perm:"read"`
-		SectorsList   func(context.Context) ([]uint64, error)                   `perm:"read"`
-		SectorsRefs   func(context.Context) (map[string][]api.SealedRef, error) `perm:"read"`
-		SectorsUpdate func(context.Context, uint64, api.SectorState) error      `perm:"write"`
+		SectorsStatus  func(context.Context, uint64) (api.SectorInfo, error)      `perm:"read"`
+		SectorsList    func(context.Context) ([]uint64, error)                    `perm:"read"`
+		SectorsRefs    func(context.Context) (map[string][]api.SealedRef, error)  `perm:"read"`
+		SectorsUpdate  func(context.Context, uint64, api.SectorState) error       `perm:"write"`
+		SectorsRecover func(context.Context, uint64) error                        `perm:"write"`
+		SectorsNotify  func(context.Context) (<-chan api.SectorUpdate, error)     `perm:"read"`
+		SectorRemove   func(ctx context.Context, id uint64, terminate bool) error `perm:"admin"`
 
 		WorkerStats func(context.Context) (sectorbuilder.WorkerStats, error) `perm:"read"`
 
-		WorkerQueue func(ctx context.Context, cfg sectorbuilder.WorkerCfg) (<-chan sectorbuilder.WorkerTask, error) `perm:"admin"` // TODO: worker perm
-		WorkerDone  func(ctx context.Context, task uint64, res sectorbuilder.SealRes) error                         `perm:"admin"`
+		WorkerQueue   func(ctx context.Context, cfg sectorbuilder.WorkerCfg) (<-chan sectorbuilder.WorkerTask, error) `perm:"worker"`
+		WorkerConnect func(ctx context.Context, info api.WorkerInfo) (<-chan api.WorkerTask, error)                   `perm:"worker"`
+		WorkerDone    func(ctx context.Context, task uint64, res sectorbuilder.SealRes) error                         `perm:"worker"`
+		WorkerList    func(context.Context) ([]api.WorkerState, error)                                                `perm:"read"`
+
+		StorageAttach func(ctx context.Context, path string, weight int, cache bool) error `perm:"admin"`
+		StorageDetach func(ctx context.Context, path string) error                         `perm:"admin"`
+		StorageList   func(context.Context) ([]api.StoragePathInfo, error)                 `perm:"read"`
+		StorageMove   func(ctx context.Context, sector uint64, path string) error          `perm:"admin"`
+
+		ProvingCheck func(ctx context.Context, generate bool) (*api.ProvingCheck, error) `perm:"write"`
 	}
 }
 
@@ -175,8 +198,8 @@ func (c *CommonStruct) AuthVerify(ctx context.Context, token string) ([]api.Perm
 	return c.Internal.AuthVerify(ctx, token)
 }
 
-func (c *CommonStruct) AuthNew(ctx context.Context, perms []api.Permission) ([]byte, error) {
-	return c.Internal.AuthNew(ctx, perms)
+func (c *CommonStruct) AuthNew(ctx context.Context, perms []api.Permission, ttl time.Duration) ([]byte, error) {
+	return c.Internal.AuthNew(ctx, perms, ttl)
 }
 
 func (c *CommonStruct) NetConnectedness(ctx context.Context, pid peer.ID) (network.Connectedness, error) {
@@ -225,8 +248,12 @@ func (c *FullNodeStruct) ClientListImports(ctx context.Context) ([]api.Import, e
 	return c.Internal.ClientListImports(ctx)
 }
 
-func (c *FullNodeStruct) ClientImport(ctx context.Context, path string) (cid.Cid, error) {
-	return c.Internal.ClientImport(ctx, path)
+func (c *FullNodeStruct) ClientRemoveImport(ctx context.Context, root cid.Cid) error {
+	return c.Internal.ClientRemoveImport(ctx, root)
+}
+
+func (c *FullNodeStruct) ClientImport(ctx context.Context, ref api.FileRef) (cid.Cid, error) {
+	return c.Internal.ClientImport(ctx, ref)
 }
 
 func (c *FullNodeStruct) ClientHasLocal(ctx context.Context, root cid.Cid) (bool, error) {
@@ -244,12 +271,24 @@ func (c *FullNodeStruct) ClientGetDealInfo(ctx context.Context, deal cid.Cid) (*
 	return c.Internal.ClientGetDealInfo(ctx, deal)
 }
 
-func (c *FullNodeStruct) ClientListDeals(ctx context.Context) ([]api.DealInfo, error) {
-	return c.Internal.ClientListDeals(ctx)
+func (c *FullNodeStruct) ClientListDeals(ctx context.Context, filter api.DealFilter) ([]api.DealInfo, error) {
+	return c.Internal.ClientListDeals(ctx, filter)
+}
+
+func (c *FullNodeStruct) ClientDealNotify(ctx context.Context) (<-chan api.DealInfo, error) {
+	return c.Internal.ClientDealNotify(ctx)
 }
 
-func (c *FullNodeStruct) ClientRetrieve(ctx context.Context, order api.RetrievalOrder, path string) error {
-	return c.Internal.ClientRetrieve(ctx, order, path)
+func (c *FullNodeStruct) ClientRetrieve(ctx context.Context, order api.RetrievalOrder, ref api.FileRef) error {
+	return c.Internal.ClientRetrieve(ctx, order, ref)
+}
+
+func (c *FullNodeStruct) ClientRetrieveWithEvents(ctx context.Context, order api.RetrievalOrder, ref api.FileRef) (<-chan api.RetrievalEvent, error) {
+	return c.Internal.ClientRetrieveWithEvents(ctx, order, ref)
+}
+
+func (c *FullNodeStruct) ClientRetrieveAuto(ctx context.Context, params api.AutoRetrievalParams, ref api.FileRef) (<-chan api.RetrievalEvent, error) {
+	return c.Internal.ClientRetrieveAuto(ctx, params, ref)
 }
 
 func (c *FullNodeStruct) ClientQueryAsk(ctx context.Context, p peer.ID, miner address.Address) (*types.SignedStorageAsk, error) {
@@ -272,6 +311,18 @@ func (c *FullNodeStruct) MpoolSub(ctx context.Context) (<-chan api.MpoolUpdate,
 	return c.Internal.MpoolSub(ctx)
 }
 
+func (c *FullNodeStruct) MpoolReplace(ctx context.Context, mcid cid.Cid, gasPrice types.BigInt) (*types.SignedMessage, error) {
+	return c.Internal.MpoolReplace(ctx, mcid, gasPrice)
+}
+
+func (c *FullNodeStruct) MpoolEstimateGas(ctx context.Context, msg *types.Message) (*types.Message, error) {
+	return c.Internal.MpoolEstimateGas(ctx, msg)
+}
+
+func (c *FullNodeStruct) MpoolEstimateGasPrice(ctx context.Context) (types.BigInt, error) {
+	return c.Internal.MpoolEstimateGasPrice(ctx)
+}
+
 func (c *FullNodeStruct) MinerCreateBlock(ctx context.Context, addr address.Address, base types.TipSetKey, ticket *types.Ticket, eproof *types.EPostProof, msgs []*types.SignedMessage, height, ts uint64) (*types.BlockMsg, error) {
 	return c.Internal.MinerCreateBlock(ctx, addr, base, ticket, eproof, msgs, height, ts)
 }
@@ -392,8 +443,16 @@ func (c *FullNodeStruct) ChainGetPath(ctx context.Context, from types.TipSetKey,
 	return c.Internal.ChainGetPath(ctx, from, to)
 }
 
-func (c *FullNodeStruct) ChainExport(ctx context.Context, tsk types.TipSetKey) (<-chan []byte, error) {
-	return c.Internal.ChainExport(ctx, tsk)
+func (c *FullNodeStruct) ChainExport(ctx context.Context, nroots uint64, oldmsgskip bool, tsk types.TipSetKey) (<-chan []byte, error) {
+	return c.Internal.ChainExport(ctx, nroots, oldmsgskip, tsk)
+}
+
+func (c *FullNodeStruct) ChainBackfillMsgIndex(ctx context.Context) (int, error) {
+	return c.Internal.ChainBackfillMsgIndex(ctx)
+}
+
+func (c *FullNodeStruct) ChainPrune(ctx context.Context, keepStateRoots uint64) (*store.PruneResult, error) {
+	return c.Internal.ChainPrune(ctx, keepStateRoots)
 }
 
 func (c *FullNodeStruct) SyncState(ctx context.Context) (*api.SyncState, error) {
@@ -452,6 +511,10 @@ func (c *FullNodeStruct) StateCall(ctx context.Context, msg *types.Message, tsk
 	return c.Internal.StateCall(ctx, msg, tsk)
 }
 
+func (c *FullNodeStruct) StateEstimateGas(ctx context.Context, msg *types.Message, tsk types.TipSetKey) (types.BigInt, error) {
+	return c.Internal.StateEstimateGas(ctx, msg, tsk)
+}
+
 func (c *FullNodeStruct) StateReplay(ctx context.Context, tsk types.TipSetKey, mc cid.Cid) (*api.InvocResult, error) {
 	return c.Internal.StateReplay(ctx, tsk, mc)
 }
@@ -601,6 +664,18 @@ func (c *StorageMinerStruct) SectorsUpdate(ctx context.Context, id uint64, state
 	return c.Internal.SectorsUpdate(ctx, id, state)
 }
 
+func (c *StorageMinerStruct) SectorsRecover(ctx context.Context, id uint64) error {
+	return c.Internal.SectorsRecover(ctx, id)
+}
+
+func (c *StorageMinerStruct) SectorsNotify(ctx context.Context) (<-chan api.SectorUpdate, error) {
+	return c.Internal.SectorsNotify(ctx)
+}
+
+func (c *StorageMinerStruct) SectorRemove(ctx context.Context, id uint64, terminate bool) error {
+	return c.Internal.SectorRemove(ctx, id, terminate)
+}
+
 func (c *StorageMinerStruct) WorkerStats(ctx context.Context) (sectorbuilder.WorkerStats, error) {
 	return c.Internal.WorkerStats(ctx)
 }
@@ -609,10 +684,38 @@ func (c *StorageMinerStruct) WorkerQueue(ctx context.Context, cfg sectorbuilder.
 	return c.Internal.WorkerQueue(ctx, cfg)
 }
 
+func (c *StorageMinerStruct) WorkerConnect(ctx context.Context, info api.WorkerInfo) (<-chan api.WorkerTask, error) {
+	return c.Internal.WorkerConnect(ctx, info)
+}
+
 func (c *StorageMinerStruct) WorkerDone(ctx context.Context, task uint64, res sectorbuilder.SealRes) error {
 	return c.Internal.WorkerDone(ctx, task, res)
 }
 
+func (c *StorageMinerStruct) WorkerList(ctx context.Context) ([]api.WorkerState, error) {
+	return c.Internal.WorkerList(ctx)
+}
+
+func (c *StorageMinerStruct) StorageAttach(ctx context.Context, path string, weight int, cache bool) error {
+	return c.Internal.StorageAttach(ctx, path, weight, cache)
+}
+
+func (c *StorageMinerStruct) StorageDetach(ctx context.Context, path string) error {
+	return c.Internal.StorageDetach(ctx, path)
+}
+
+func (c *StorageMinerStruct) StorageList(ctx context.Context) ([]api.StoragePathInfo, error) {
+	return c.Internal.StorageList(ctx)
+}
+
+func (c *StorageMinerStruct) StorageMove(ctx context.Context, sector uint64, path string) error {
+	return c.Internal.StorageMove(ctx, sector, path)
+}
+
+func (c *StorageMinerStruct) ProvingCheck(ctx context.Context, generate bool) (*api.ProvingCheck, error) {
+	return c.Internal.ProvingCheck(ctx, generate)
+}
+
 var _ api.Common = &CommonStruct{}
 var _ api.FullNode = &FullNodeStruct{}
 var _ api.StorageMiner = &StorageMinerStruct{}
@@ -3,42 +3,53 @@ package client
 import (
 	"github.com/filecoin-project/lotus/api/apistruct"
 	"net/http"
+	"reflect"
 
 	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/lib/jsonrpc"
 )
 
 // NewCommonRPC creates a new http jsonrpc client.
-func NewCommonRPC(addr string, requestHeader http.Header) (api.Common, jsonrpc.ClientCloser, error) {
+func NewCommonRPC(addr string, requestHeader http.Header, opts ...jsonrpc.Option) (api.Common, jsonrpc.ClientCloser, error) {
 	var res apistruct.CommonStruct
 	closer, err := jsonrpc.NewMergeClient(addr, "Filecoin",
 		[]interface{}{
 			&res.Internal,
-		}, requestHeader)
+		}, requestHeader, clientOpts(opts)...)
 
 	return &res, closer, err
 }
 
 // NewFullNodeRPC creates a new http jsonrpc client.
-func NewFullNodeRPC(addr string, requestHeader http.Header) (api.FullNode, jsonrpc.ClientCloser, error) {
+func NewFullNodeRPC(addr string, requestHeader http.Header, opts ...jsonrpc.Option) (api.FullNode, jsonrpc.ClientCloser, error) {
 	var res apistruct.FullNodeStruct
 	closer, err := jsonrpc.NewMergeClient(addr, "Filecoin",
 		[]interface{}{
 			&res.CommonStruct.Internal,
 			&res.Internal,
-		}, requestHeader)
+		}, requestHeader, clientOpts(opts)...)
 
 	return &res, closer, err
 }
 
 // NewStorageMinerRPC creates a new http jsonrpc client for storage miner
-func NewStorageMinerRPC(addr string, requestHeader http.Header) (api.StorageMiner, jsonrpc.ClientCloser, error) {
+func NewStorageMinerRPC(addr string, requestHeader http.Header, opts ...jsonrpc.Option) (api.StorageMiner, jsonrpc.ClientCloser, error) {
 	var res apistruct.StorageMinerStruct
 	closer, err := jsonrpc.NewMergeClient(addr, "Filecoin",
 		[]interface{}{
 			&res.CommonStruct.Internal,
 			&res.Internal,
-		}, requestHeader)
+		}, requestHeader, clientOpts(opts)...)
 
 	return &res, closer, err
 }
+
+// clientOpts makes calls with read permission retryable after reconnecting,
+// as they don't change node state
+func clientOpts(opts []jsonrpc.Option) []jsonrpc.Option {
+	return append([]jsonrpc.Option{
+		jsonrpc.WithRetryFilter(func(method reflect.StructField) bool {
+			return method.Tag.Get("perm") == string(apistruct.PermRead)
+		}),
+	}, opts...)
+}
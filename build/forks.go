@@ -7,3 +7,7 @@ const ForkFrigidHeight = 7950
 const ForkBootyBayHeight = 11000
 
 const ForkMissingSnowballs = 34000
+
+const ForkWintergraspHeight = 51000
+
+const ForkIcecrownHeight = 62000
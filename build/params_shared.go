@@ -61,6 +61,12 @@ const SealRandomnessLookbackLimit = SealRandomnessLookback + 2000
 // Maximum lookback that randomness can be sourced from for a seal proof submission
 const MaxSealLookback = SealRandomnessLookbackLimit + 2000
 
+// Faulty sectors are removed from the sector set at the first PoSt after the
+// oldest of them has been faulty for this long
+//
+// Epochs
+const MaxFaultAge = 10 * SlashablePowerDelay
+
 // /////
 // Mining
 
@@ -107,4 +113,8 @@ const BlsSignatureCacheSize = 40000
 // Limits
 
 const BlockMessageLimit = 512
+
+// BlockGasLimit is the maximum sum of GasLimit of all messages miners
+// will pack into a single block
+const BlockGasLimit = 100000000
 const MinerMaxSectors = 1 << 48
@@ -63,6 +63,16 @@ type StorageMinerActorState struct {
 	SlashedAt uint64
 
 	ElectionPeriodStart uint64
+
+	// FaultDeclarations records when the sectors in FaultSet were declared
+	// faulty, oldest first. Only tracked after ForkWintergraspHeight, when
+	// faults are kept across PoSts.
+	FaultDeclarations []FaultDeclaration
+}
+
+type FaultDeclaration struct {
+	Epoch   uint64
+	Sectors types.BitField
 }
 
 type MinerInfo struct {
@@ -127,9 +137,11 @@ type maMethods struct {
 	DeclareFaults        uint64
 	SlashConsensusFault  uint64
 	SubmitElectionPoSt   uint64
+	DeclareRecoveries    uint64
+	TerminateSectors     uint64
 }
 
-var MAMethods = maMethods{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}
+var MAMethods = maMethods{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22}
 
 func (sma StorageMinerActor) Exports() []interface{} {
 	return []interface{}{
@@ -45,6 +45,8 @@ func (sma StorageMinerActor2) Exports() []interface{} {
 		18: sma.DeclareFaults,
 		19: sma.SlashConsensusFault,
 		20: sma.SubmitElectionPoSt,
+		21: sma.DeclareRecoveries,
+		22: sma.TerminateSectors,
 	}
 }
 
@@ -686,6 +688,36 @@ func (sma StorageMinerActor2) DeclareFaults(act *types.Actor, vmctx types.VMCont
 		return nil, aerrors.Newf(3, "too many declared faults: %d > %d", cf, 2*ss.Count)
 	}
 
+	// FORK
+	// faults are kept across PoSts after ForkWintergraspHeight, remember when
+	// each one was declared to drop sectors which stay faulty for too long
+	if vmctx.BlockHeight() >= build.ForkWintergraspHeight {
+		oldFaults, nerr := self.FaultSet.AllMap(2 * ss.Count)
+		if nerr != nil {
+			return nil, aerrors.Absorb(nerr, 2, "could not decode fault set")
+		}
+
+		declared, nerr := params.Faults.All(2 * ss.Count)
+		if nerr != nil {
+			return nil, aerrors.Absorb(nerr, 2, "could not decode faults")
+		}
+
+		var newFaults []uint64
+		for _, id := range declared {
+			if !oldFaults[id] {
+				oldFaults[id] = true // skip duplicates
+				newFaults = append(newFaults, id)
+			}
+		}
+
+		if len(newFaults) > 0 {
+			self.FaultDeclarations = append(self.FaultDeclarations, FaultDeclaration{
+				Epoch:   vmctx.BlockHeight(),
+				Sectors: types.BitFieldFromSet(newFaults),
+			})
+		}
+	}
+
 	self.FaultSet = nfaults
 
 	self.LastFaultSubmission = vmctx.BlockHeight()
@@ -701,6 +733,308 @@ func (sma StorageMinerActor2) DeclareFaults(act *types.Actor, vmctx types.VMCont
 	return nil, nil
 }
 
+type DeclareRecoveriesParams struct {
+	Recoveries types.BitField
+}
+
+// DeclareRecoveries removes sectors from the fault set, so that they count
+// towards miner power again after the next PoSt
+func (sma StorageMinerActor2) DeclareRecoveries(act *types.Actor, vmctx types.VMContext, params *DeclareRecoveriesParams) ([]byte, ActorError) {
+	// FORK
+	if vmctx.BlockHeight() < build.ForkWintergraspHeight {
+		return nil, aerrors.Newf(255, "no method %d on actor", MAMethods.DeclareRecoveries)
+	}
+
+	oldstate, self, aerr := loadState(vmctx)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	mi, aerr := loadMinerInfo(vmctx, self)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	if vmctx.Message().From != mi.Worker {
+		return nil, aerrors.New(1, "not authorized to declare recoveries for miner")
+	}
+
+	ss, nerr := amt2.LoadAMT(types.WrapStorage(vmctx.Storage()), self.Sectors)
+	if nerr != nil {
+		return nil, aerrors.HandleExternalError(nerr, "failed to load sector set")
+	}
+
+	faults, nerr := self.FaultSet.AllMap(2 * ss.Count)
+	if nerr != nil {
+		return nil, aerrors.Absorb(nerr, 2, "could not decode fault set")
+	}
+
+	recoveries, nerr := params.Recoveries.All(2 * ss.Count)
+	if nerr != nil {
+		return nil, aerrors.Absorb(nerr, 2, "could not decode recoveries")
+	}
+
+	for _, id := range recoveries {
+		if !faults[id] {
+			return nil, aerrors.Newf(3, "sector %d is not faulty", id)
+		}
+		delete(faults, id)
+	}
+
+	nfaults := types.NewBitField()
+	for id := range faults {
+		nfaults.Set(id)
+	}
+	self.FaultSet = nfaults
+
+	self.FaultDeclarations, nerr = removeFaultDeclarations(self.FaultDeclarations, recoveries, 2*ss.Count)
+	if nerr != nil {
+		return nil, aerrors.Absorb(nerr, 2, "could not decode fault declarations")
+	}
+
+	nstate, aerr := vmctx.Storage().Put(self)
+	if aerr != nil {
+		return nil, aerr
+	}
+	if err := vmctx.Storage().Commit(oldstate, nstate); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// removeFaultDeclarations removes sectors from fault declarations, dropping
+// declarations which don't have any faulty sectors left
+func removeFaultDeclarations(decls []FaultDeclaration, ids []uint64, max uint64) ([]FaultDeclaration, error) {
+	remove := map[uint64]bool{}
+	for _, id := range ids {
+		remove[id] = true
+	}
+
+	var out []FaultDeclaration
+	for _, decl := range decls {
+		sectors, err := decl.Sectors.All(max)
+		if err != nil {
+			return nil, err
+		}
+
+		var left []uint64
+		for _, id := range sectors {
+			if !remove[id] {
+				left = append(left, id)
+			}
+		}
+
+		if len(left) == len(sectors) {
+			out = append(out, decl)
+			continue
+		}
+		if len(left) > 0 {
+			out = append(out, FaultDeclaration{
+				Epoch:   decl.Epoch,
+				Sectors: types.BitFieldFromSet(left),
+			})
+		}
+	}
+
+	return out, nil
+}
+
+type TerminateSectorsParams struct {
+	Sectors types.BitField
+	Deals   []SectorDeals // deals in each of the sectors
+}
+
+type SectorDeals struct {
+	SectorID uint64
+	DealIDs  []uint64
+}
+
+// TerminateSectors removes committed sectors from the sector and proving sets,
+// taking away the power they added. Sectors storing client deals which didn't
+// expire yet can't be terminated.
+func (sma StorageMinerActor2) TerminateSectors(act *types.Actor, vmctx types.VMContext, params *TerminateSectorsParams) ([]byte, ActorError) {
+	// FORK
+	if vmctx.BlockHeight() < build.ForkIcecrownHeight {
+		return nil, aerrors.Newf(255, "no method %d on actor", MAMethods.TerminateSectors)
+	}
+
+	oldstate, self, aerr := loadState(vmctx)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	mi, aerr := loadMinerInfo(vmctx, self)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	if vmctx.Message().From != mi.Worker {
+		return nil, aerrors.New(1, "not authorized to terminate sectors for miner")
+	}
+
+	ss, nerr := amt2.LoadAMT(types.WrapStorage(vmctx.Storage()), self.Sectors)
+	if nerr != nil {
+		return nil, aerrors.HandleExternalError(nerr, "failed to load sector set")
+	}
+
+	pss, nerr := amt2.LoadAMT(types.WrapStorage(vmctx.Storage()), self.ProvingSet)
+	if nerr != nil {
+		return nil, aerrors.HandleExternalError(nerr, "failed to load proving set")
+	}
+
+	ids, nerr := params.Sectors.All(ss.Count)
+	if nerr != nil {
+		return nil, aerrors.Absorb(nerr, 2, "could not decode sectors")
+	}
+	if len(ids) == 0 {
+		return nil, aerrors.New(2, "no sectors to terminate")
+	}
+
+	faults, nerr := self.FaultSet.AllMap(2 * ss.Count)
+	if nerr != nil {
+		return nil, aerrors.Absorb(nerr, 2, "could not decode fault set")
+	}
+
+	sectorDeals := map[uint64][]uint64{}
+	for _, sd := range params.Deals {
+		sectorDeals[sd.SectorID] = sd.DealIDs
+	}
+
+	// sectors in the proving set which weren't faulty count towards power
+	var active uint64
+	for _, id := range ids {
+		var comms [][]byte
+		if err := ss.Get(id, &comms); err != nil {
+			if _, ok := err.(*amt2.ErrNotFound); ok {
+				return nil, aerrors.Newf(3, "sector %d is not in the sector set", id)
+			}
+			return nil, aerrors.HandleExternalError(err, "failed to find sector in sector set")
+		}
+
+		if aerr := checkDealsExpired(vmctx, mi.SectorSize, id, comms[1], sectorDeals[id]); aerr != nil {
+			return nil, aerr
+		}
+
+		if err := pss.Get(id, &comms); err != nil {
+			var notfound *amt2.ErrNotFound
+			if !xerrors.As(err, &notfound) {
+				return nil, aerrors.HandleExternalError(err, "failed to find sector in proving set")
+			}
+		} else if !faults[id] {
+			active++
+		}
+
+		delete(faults, id)
+	}
+
+	self.Sectors, aerr = RemoveFromSectorSet2(vmctx.Context(), vmctx.Storage(), self.Sectors, ids)
+	if aerr != nil {
+		return nil, aerr
+	}
+	self.ProvingSet, aerr = RemoveFromSectorSet2(vmctx.Context(), vmctx.Storage(), self.ProvingSet, ids)
+	if aerr != nil {
+		return nil, aerr
+	}
+
+	nfaults := types.NewBitField()
+	for id := range faults {
+		nfaults.Set(id)
+	}
+	self.FaultSet = nfaults
+
+	self.FaultDeclarations, nerr = removeFaultDeclarations(self.FaultDeclarations, ids, 2*ss.Count)
+	if nerr != nil {
+		return nil, aerrors.Absorb(nerr, 2, "could not decode fault declarations")
+	}
+
+	oldPower := self.Power
+	lost := types.BigMul(types.NewInt(active), types.NewInt(mi.SectorSize))
+	newPower := types.NewInt(0)
+	if oldPower.GreaterThan(lost) {
+		newPower = types.BigSub(oldPower, lost)
+	}
+
+	// If below the minimum size requirement, miners have zero power
+	if newPower.LessThan(types.NewInt(build.MinimumMinerPower)) {
+		newPower = types.NewInt(0)
+	}
+
+	self.Power = newPower
+
+	// slashed miners have their power removed from the total already
+	delta := types.BigSub(newPower, oldPower)
+	if self.SlashedAt == 0 && !delta.IsZero() {
+		// the slashing deadline doesn't change, only the total storage is
+		// updated
+		deadline := self.ElectionPeriodStart + build.SlashablePowerDelay
+		enc, err := SerializeParams(&UpdateStorageParams{
+			Delta:                 delta,
+			NextSlashDeadline:     deadline,
+			PreviousSlashDeadline: deadline,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = vmctx.Send(StoragePowerAddress, SPAMethods.UpdateStorage, types.NewInt(0), enc)
+		if err != nil {
+			return nil, aerrors.Wrap(err, "updating storage failed")
+		}
+	}
+
+	nstate, aerr := vmctx.Storage().Put(self)
+	if aerr != nil {
+		return nil, aerr
+	}
+	if err := vmctx.Storage().Commit(oldstate, nstate); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// checkDealsExpired makes sure that the given deals are the ones stored in a
+// sector, and that none of them is still active
+func checkDealsExpired(vmctx types.VMContext, ssize uint64, id uint64, commD []byte, dealIDs []uint64) ActorError {
+	if len(dealIDs) == 0 {
+		return aerrors.Newf(4, "no deals given for sector %d", id)
+	}
+
+	enc, aerr := SerializeParams(&ComputeDataCommitmentParams{
+		DealIDs:    dealIDs,
+		SectorSize: ssize,
+	})
+	if aerr != nil {
+		return aerrors.Wrap(aerr, "failed to serialize ComputeDataCommitmentParams")
+	}
+
+	dealsCommD, aerr := vmctx.Send(StorageMarketAddress, SMAMethods.ComputeDataCommitment, types.NewInt(0), enc)
+	if aerr != nil {
+		return aerrors.Wrapf(aerr, "failed to compute data commitment (sector %d, deals: %v)", id, dealIDs)
+	}
+
+	if !bytes.Equal(dealsCommD, commD) {
+		return aerrors.Newf(4, "deals %v aren't the deals in sector %d", dealIDs, id)
+	}
+
+	enc, aerr = SerializeParams(&GetLastExpirationFromDealIDsParams{DealIDs: dealIDs})
+	if aerr != nil {
+		return aerr
+	}
+
+	ret, aerr := vmctx.Send(StorageMarketAddress, SMAMethods.GetLastExpirationFromDealIDs, types.NewInt(0), enc)
+	if aerr != nil {
+		return aerrors.Wrapf(aerr, "failed to get deal expiration (sector %d)", id)
+	}
+
+	if last := types.BigFromBytes(ret); types.BigCmp(last, types.NewInt(vmctx.BlockHeight())) >= 0 {
+		return aerrors.Newf(5, "sector %d has deals active until epoch %s", id, last)
+	}
+
+	return nil
+}
+
 func (sma StorageMinerActor2) SlashConsensusFault(act *types.Actor, vmctx types.VMContext, params *MinerSlashConsensusFault) ([]byte, ActorError) {
 	if vmctx.Message().From != StoragePowerAddress {
 		return nil, aerrors.New(1, "SlashConsensusFault may only be called by the storage market actor")
@@ -847,7 +1181,38 @@ func onSuccessfulPoSt2(self *StorageMinerActorState, vmctx types.VMContext, acti
 		return aerrors.Absorb(nerr, 1, "invalid bitfield (fatal?)")
 	}
 
-	self.FaultSet = types.NewBitField()
+	// FORK
+	if vmctx.BlockHeight() >= build.ForkWintergraspHeight {
+		// faulty sectors are kept in the sector set (without power) until
+		// they are declared recovered, or until they have been faulty for
+		// too long
+		expired, nerr := expiredFaults(self, vmctx.BlockHeight(), faults, 2*ss.Count)
+		if nerr != nil {
+			return aerrors.Absorb(nerr, 1, "invalid fault declarations (fatal?)")
+		}
+
+		self.FaultDeclarations, nerr = removeFaultDeclarations(self.FaultDeclarations, expired, 2*ss.Count)
+		if nerr != nil {
+			return aerrors.Absorb(nerr, 1, "invalid fault declarations (fatal?)")
+		}
+
+		isExpired := map[uint64]bool{}
+		for _, id := range expired {
+			isExpired[id] = true
+		}
+
+		var left []uint64
+		for _, id := range faults {
+			if !isExpired[id] {
+				left = append(left, id)
+			}
+		}
+		self.FaultSet = types.BitFieldFromSet(left)
+
+		faults = expired
+	} else {
+		self.FaultSet = types.NewBitField()
+	}
 
 	oldPower := self.Power
 	newPower := types.BigMul(types.NewInt(pss.Count-activeFaults), types.NewInt(mi.SectorSize))
@@ -901,3 +1266,34 @@ func onSuccessfulPoSt2(self *StorageMinerActorState, vmctx types.VMContext, acti
 	self.ProvingSet = ncid
 	return nil
 }
+
+// expiredFaults returns the faulty sectors which were declared faulty more
+// than MaxFaultAge epochs ago. Faults declared before ForkWintergraspHeight
+// aren't tracked, their age is counted from the fork.
+func expiredFaults(self *StorageMinerActorState, height uint64, faults []uint64, max uint64) ([]uint64, error) {
+	declaredAt := map[uint64]uint64{}
+	for _, decl := range self.FaultDeclarations {
+		sectors, err := decl.Sectors.All(max)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range sectors {
+			declaredAt[id] = decl.Epoch
+		}
+	}
+
+	var out []uint64
+	for _, id := range faults {
+		epoch, ok := declaredAt[id]
+		if !ok {
+			epoch = build.ForkWintergraspHeight
+		}
+
+		if height-epoch > build.MaxFaultAge {
+			out = append(out, id)
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,339 @@
+package actors
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	cbg "github.com/whyrusleeping/cbor-gen"
+	xerrors "golang.org/x/xerrors"
+)
+
+// StorageMinerActorState is encoded by hand, FaultDeclarations is only
+// written when it's not empty, so states from before it was added keep
+// their encoding. Otherwise this is what cbor-gen generates.
+
+func (t *StorageMinerActorState) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	// states without fault declarations keep the encoding used before
+	// ForkWintergraspHeight
+	header := []byte{138}
+	if len(t.FaultDeclarations) > 0 {
+		header = []byte{139}
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	// t.PreCommittedSectors (map[string]*actors.PreCommittedSector) (map)
+	{
+		if len(t.PreCommittedSectors) > 4096 {
+			return xerrors.Errorf("cannot marshal t.PreCommittedSectors map too large")
+		}
+
+		if err := cbg.CborWriteHeader(w, cbg.MajMap, uint64(len(t.PreCommittedSectors))); err != nil {
+			return err
+		}
+
+		keys := make([]string, 0, len(t.PreCommittedSectors))
+		for k := range t.PreCommittedSectors {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v := t.PreCommittedSectors[k]
+
+			if len(k) > cbg.MaxLength {
+				return xerrors.Errorf("Value in field k was too long")
+			}
+
+			if _, err := w.Write(cbg.CborEncodeMajorType(cbg.MajTextString, uint64(len(k)))); err != nil {
+				return err
+			}
+			if _, err := w.Write([]byte(k)); err != nil {
+				return err
+			}
+
+			if err := v.MarshalCBOR(w); err != nil {
+				return err
+			}
+
+		}
+	}
+
+	// t.Sectors (cid.Cid) (struct)
+
+	if err := cbg.WriteCid(w, t.Sectors); err != nil {
+		return xerrors.Errorf("failed to write cid field t.Sectors: %w", err)
+	}
+
+	// t.ProvingSet (cid.Cid) (struct)
+
+	if err := cbg.WriteCid(w, t.ProvingSet); err != nil {
+		return xerrors.Errorf("failed to write cid field t.ProvingSet: %w", err)
+	}
+
+	// t.Info (cid.Cid) (struct)
+
+	if err := cbg.WriteCid(w, t.Info); err != nil {
+		return xerrors.Errorf("failed to write cid field t.Info: %w", err)
+	}
+
+	// t.FaultSet (types.BitField) (struct)
+	if err := t.FaultSet.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.LastFaultSubmission (uint64) (uint64)
+	if _, err := w.Write(cbg.CborEncodeMajorType(cbg.MajUnsignedInt, uint64(t.LastFaultSubmission))); err != nil {
+		return err
+	}
+
+	// t.Power (types.BigInt) (struct)
+	if err := t.Power.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.Active (bool) (bool)
+	if err := cbg.WriteBool(w, t.Active); err != nil {
+		return err
+	}
+
+	// t.SlashedAt (uint64) (uint64)
+	if _, err := w.Write(cbg.CborEncodeMajorType(cbg.MajUnsignedInt, uint64(t.SlashedAt))); err != nil {
+		return err
+	}
+
+	// t.ElectionPeriodStart (uint64) (uint64)
+	if _, err := w.Write(cbg.CborEncodeMajorType(cbg.MajUnsignedInt, uint64(t.ElectionPeriodStart))); err != nil {
+		return err
+	}
+
+	if len(t.FaultDeclarations) == 0 {
+		return nil
+	}
+
+	// t.FaultDeclarations ([]actors.FaultDeclaration) (slice)
+	if len(t.FaultDeclarations) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.FaultDeclarations was too long")
+	}
+
+	if _, err := w.Write(cbg.CborEncodeMajorType(cbg.MajArray, uint64(len(t.FaultDeclarations)))); err != nil {
+		return err
+	}
+	for _, v := range t.FaultDeclarations {
+		if err := v.MarshalCBOR(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *StorageMinerActorState) UnmarshalCBOR(r io.Reader) error {
+	br := cbg.GetPeeker(r)
+
+	maj, extra, err := cbg.CborReadHeader(br)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 10 && extra != 11 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+	fields := extra
+
+	// t.PreCommittedSectors (map[string]*actors.PreCommittedSector) (map)
+
+	maj, extra, err = cbg.CborReadHeader(br)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajMap {
+		return fmt.Errorf("expected a map (major type 5)")
+	}
+	if extra > 4096 {
+		return fmt.Errorf("t.PreCommittedSectors: map too large")
+	}
+
+	t.PreCommittedSectors = make(map[string]*PreCommittedSector, extra)
+
+	for i, l := 0, int(extra); i < l; i++ {
+
+		var k string
+
+		{
+			sval, err := cbg.ReadString(br)
+			if err != nil {
+				return err
+			}
+
+			k = string(sval)
+		}
+
+		var v *PreCommittedSector
+
+		{
+
+			pb, err := br.PeekByte()
+			if err != nil {
+				return err
+			}
+			if pb == cbg.CborNull[0] {
+				var nbuf [1]byte
+				if _, err := br.Read(nbuf[:]); err != nil {
+					return err
+				}
+			} else {
+				v = new(PreCommittedSector)
+				if err := v.UnmarshalCBOR(br); err != nil {
+					return err
+				}
+			}
+
+		}
+
+		t.PreCommittedSectors[k] = v
+
+	}
+	// t.Sectors (cid.Cid) (struct)
+
+	{
+
+		c, err := cbg.ReadCid(br)
+		if err != nil {
+			return xerrors.Errorf("failed to read cid field t.Sectors: %w", err)
+		}
+
+		t.Sectors = c
+
+	}
+	// t.ProvingSet (cid.Cid) (struct)
+
+	{
+
+		c, err := cbg.ReadCid(br)
+		if err != nil {
+			return xerrors.Errorf("failed to read cid field t.ProvingSet: %w", err)
+		}
+
+		t.ProvingSet = c
+
+	}
+	// t.Info (cid.Cid) (struct)
+
+	{
+
+		c, err := cbg.ReadCid(br)
+		if err != nil {
+			return xerrors.Errorf("failed to read cid field t.Info: %w", err)
+		}
+
+		t.Info = c
+
+	}
+	// t.FaultSet (types.BitField) (struct)
+
+	{
+
+		if err := t.FaultSet.UnmarshalCBOR(br); err != nil {
+			return err
+		}
+
+	}
+	// t.LastFaultSubmission (uint64) (uint64)
+
+	maj, extra, err = cbg.CborReadHeader(br)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajUnsignedInt {
+		return fmt.Errorf("wrong type for uint64 field")
+	}
+	t.LastFaultSubmission = uint64(extra)
+	// t.Power (types.BigInt) (struct)
+
+	{
+
+		if err := t.Power.UnmarshalCBOR(br); err != nil {
+			return err
+		}
+
+	}
+	// t.Active (bool) (bool)
+
+	maj, extra, err = cbg.CborReadHeader(br)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajOther {
+		return fmt.Errorf("booleans must be major type 7")
+	}
+	switch extra {
+	case 20:
+		t.Active = false
+	case 21:
+		t.Active = true
+	default:
+		return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
+	}
+	// t.SlashedAt (uint64) (uint64)
+
+	maj, extra, err = cbg.CborReadHeader(br)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajUnsignedInt {
+		return fmt.Errorf("wrong type for uint64 field")
+	}
+	t.SlashedAt = uint64(extra)
+	// t.ElectionPeriodStart (uint64) (uint64)
+
+	maj, extra, err = cbg.CborReadHeader(br)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajUnsignedInt {
+		return fmt.Errorf("wrong type for uint64 field")
+	}
+	t.ElectionPeriodStart = uint64(extra)
+
+	t.FaultDeclarations = nil
+	if fields == 10 {
+		return nil
+	}
+
+	// t.FaultDeclarations ([]actors.FaultDeclaration) (slice)
+
+	maj, extra, err = cbg.CborReadHeader(br)
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("t.FaultDeclarations: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+	if extra > 0 {
+		t.FaultDeclarations = make([]FaultDeclaration, extra)
+	}
+	for i := 0; i < int(extra); i++ {
+
+		var v FaultDeclaration
+		if err := v.UnmarshalCBOR(br); err != nil {
+			return err
+		}
+
+		t.FaultDeclarations[i] = v
+	}
+
+	return nil
+}
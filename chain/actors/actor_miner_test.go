@@ -169,7 +169,275 @@ func TestMinerSubmitBadFault(t *testing.T) {
 
 }
 
-func addSectorToMiner(h *Harness, t *testing.T, minerAddr, worker, client address.Address, sid uint64) {
+func TestMinerTerminateSectors(t *testing.T) {
+	oldSS, oldMin := build.SectorSizes, build.MinimumMinerPower
+	build.SectorSizes, build.MinimumMinerPower = []uint64{1024}, 1024
+	defer func() {
+		build.SectorSizes, build.MinimumMinerPower = oldSS, oldMin
+	}()
+
+	var worker, client address.Address
+	opts := []HarnessOpt{
+		HarnessAddr(&worker, 1000000),
+		HarnessAddr(&client, 1000000),
+	}
+
+	h := NewHarness(t, opts...)
+	h.vm.Syscalls.ValidatePoRep = func(ctx context.Context, maddr address.Address, ssize uint64, commD, commR, ticket, proof, seed []byte, sectorID uint64) (bool, aerrors.ActorError) {
+		// all proofs are valid
+		return true, nil
+	}
+
+	// create a miner with the second miner actor
+	h.BlockHeight = build.ForkFrigidHeight + 1
+	ret, _ := h.InvokeWithValue(t, worker, actors.StoragePowerAddress, actors.SPAMethods.CreateStorageMiner, types.NewInt(3000),
+		&actors.StorageMinerConstructorParams{
+			Owner:      worker,
+			Worker:     worker,
+			SectorSize: 1024,
+			PeerID:     "fakepeerid",
+		})
+	ApplyOK(t, ret)
+	minerAddr, err := address.NewFromBytes(ret.Return)
+	assert.NoError(t, err)
+
+	ret, _ = h.SendFunds(t, worker, minerAddr, types.NewInt(100000))
+	ApplyOK(t, ret)
+
+	ret, _ = h.InvokeWithValue(t, client, actors.StorageMarketAddress, actors.SMAMethods.AddBalance, types.NewInt(4000), nil)
+	ApplyOK(t, ret)
+
+	addSectorToMiner(h, t, minerAddr, worker, client, 1)
+	deal2 := addSectorToMiner(h, t, minerAddr, worker, client, 2)
+
+	// the second sector is only proven after the proving set catches up
+	// with the sector set
+	h.BlockHeight = build.ForkBootyBayHeight
+	ret, _ = h.Invoke(t, actors.NetworkAddress, minerAddr, actors.MAMethods.SubmitElectionPoSt, nil)
+	ApplyOK(t, ret)
+	ret, _ = h.Invoke(t, actors.NetworkAddress, minerAddr, actors.MAMethods.SubmitElectionPoSt, nil)
+	ApplyOK(t, ret)
+
+	st, err := getMinerState(context.TODO(), h.vm.StateTree(), h.bs, minerAddr)
+	assert.NoError(t, err)
+	if types.BigCmp(st.Power, types.NewInt(2048)) != 0 {
+		t.Fatalf("Expected power of 2048, got %s", st.Power)
+	}
+
+	bf := types.NewBitField()
+	bf.Set(2)
+
+	ret, _ = h.Invoke(t, worker, minerAddr, actors.MAMethods.TerminateSectors, &actors.TerminateSectorsParams{Sectors: bf})
+	if ret.ExitCode != 255 {
+		t.Fatalf("expected exit code 255 before the fork, got %d: %+v", ret.ExitCode, ret.ActorErr)
+	}
+
+	h.BlockHeight = build.ForkIcecrownHeight
+
+	ret, _ = h.Invoke(t, client, minerAddr, actors.MAMethods.TerminateSectors, &actors.TerminateSectorsParams{Sectors: bf})
+	if ret.ExitCode != 1 {
+		t.Fatalf("expected exit code 1, got %d: %+v", ret.ExitCode, ret.ActorErr)
+	}
+
+	unknown := types.NewBitField()
+	unknown.Set(3)
+	ret, _ = h.Invoke(t, worker, minerAddr, actors.MAMethods.TerminateSectors, &actors.TerminateSectorsParams{Sectors: unknown})
+	if ret.ExitCode != 3 {
+		t.Fatalf("expected exit code 3, got %d: %+v", ret.ExitCode, ret.ActorErr)
+	}
+
+	ret, _ = h.Invoke(t, worker, minerAddr, actors.MAMethods.TerminateSectors, &actors.TerminateSectorsParams{Sectors: bf})
+	if ret.ExitCode != 4 {
+		t.Fatalf("expected exit code 4 without deals, got %d: %+v", ret.ExitCode, ret.ActorErr)
+	}
+
+	deals := []actors.SectorDeals{{SectorID: 2, DealIDs: []uint64{deal2}}}
+	ret, _ = h.Invoke(t, worker, minerAddr, actors.MAMethods.TerminateSectors, &actors.TerminateSectorsParams{Sectors: bf, Deals: deals})
+	ApplyOK(t, ret)
+	assertSectorIDs(h, t, minerAddr, []uint64{1})
+
+	st, err = getMinerState(context.TODO(), h.vm.StateTree(), h.bs, minerAddr)
+	assert.NoError(t, err)
+	if types.BigCmp(st.Power, types.NewInt(1024)) != 0 {
+		t.Errorf("Expected power of 1024, got %s", st.Power)
+	}
+
+	pset, err := stmgr.LoadSectorsFromSet(context.TODO(), h.bs, st.ProvingSet)
+	assert.NoError(t, err)
+	if len(pset) != 1 || pset[0].SectorID != 1 {
+		t.Errorf("expected only sector 1 in the proving set, got %+v", pset)
+	}
+
+	// a client deal which is still active keeps its sector
+	ret, _ = h.InvokeWithValue(t, worker, actors.StorageMarketAddress, actors.SMAMethods.AddBalance, types.NewInt(4000), nil)
+	ApplyOK(t, ret)
+
+	deal3 := addSectorToMiner(h, t, minerAddr, worker, client, 3)
+	deal4 := addSectorToMiner(h, t, minerAddr, worker, worker, 4)
+
+	bf = types.NewBitField()
+	bf.Set(3)
+	deals = []actors.SectorDeals{{SectorID: 3, DealIDs: []uint64{deal4}}}
+	ret, _ = h.Invoke(t, worker, minerAddr, actors.MAMethods.TerminateSectors, &actors.TerminateSectorsParams{Sectors: bf, Deals: deals})
+	if ret.ExitCode != 4 {
+		t.Fatalf("expected exit code 4 with deals of another sector, got %d: %+v", ret.ExitCode, ret.ActorErr)
+	}
+
+	deals = []actors.SectorDeals{{SectorID: 3, DealIDs: []uint64{deal3}}}
+	ret, _ = h.Invoke(t, worker, minerAddr, actors.MAMethods.TerminateSectors, &actors.TerminateSectorsParams{Sectors: bf, Deals: deals})
+	if ret.ExitCode != 5 {
+		t.Fatalf("expected exit code 5 with an active deal, got %d: %+v", ret.ExitCode, ret.ActorErr)
+	}
+
+	// deals with the miner's own worker don't keep a sector
+	bf = types.NewBitField()
+	bf.Set(4)
+	deals = []actors.SectorDeals{{SectorID: 4, DealIDs: []uint64{deal4}}}
+	ret, _ = h.Invoke(t, worker, minerAddr, actors.MAMethods.TerminateSectors, &actors.TerminateSectorsParams{Sectors: bf, Deals: deals})
+	ApplyOK(t, ret)
+	assertSectorIDs(h, t, minerAddr, []uint64{1, 3})
+}
+
+func TestMinerFaultAge(t *testing.T) {
+	oldSS, oldMin := build.SectorSizes, build.MinimumMinerPower
+	build.SectorSizes, build.MinimumMinerPower = []uint64{1024}, 1024
+	defer func() {
+		build.SectorSizes, build.MinimumMinerPower = oldSS, oldMin
+	}()
+
+	var worker, client address.Address
+	opts := []HarnessOpt{
+		HarnessAddr(&worker, 1000000),
+		HarnessAddr(&client, 1000000),
+	}
+
+	h := NewHarness(t, opts...)
+	h.vm.Syscalls.ValidatePoRep = func(ctx context.Context, maddr address.Address, ssize uint64, commD, commR, ticket, proof, seed []byte, sectorID uint64) (bool, aerrors.ActorError) {
+		// all proofs are valid
+		return true, nil
+	}
+
+	// create a miner with the second miner actor
+	h.BlockHeight = build.ForkFrigidHeight + 1
+	ret, _ := h.InvokeWithValue(t, worker, actors.StoragePowerAddress, actors.SPAMethods.CreateStorageMiner, types.NewInt(3000),
+		&actors.StorageMinerConstructorParams{
+			Owner:      worker,
+			Worker:     worker,
+			SectorSize: 1024,
+			PeerID:     "fakepeerid",
+		})
+	ApplyOK(t, ret)
+	minerAddr, err := address.NewFromBytes(ret.Return)
+	assert.NoError(t, err)
+
+	ret, _ = h.SendFunds(t, worker, minerAddr, types.NewInt(100000))
+	ApplyOK(t, ret)
+
+	ret, _ = h.InvokeWithValue(t, client, actors.StorageMarketAddress, actors.SMAMethods.AddBalance, types.NewInt(4000), nil)
+	ApplyOK(t, ret)
+
+	addSectorToMiner(h, t, minerAddr, worker, client, 1)
+	addSectorToMiner(h, t, minerAddr, worker, client, 2)
+
+	h.BlockHeight = build.ForkWintergraspHeight
+	ret, _ = h.Invoke(t, actors.NetworkAddress, minerAddr, actors.MAMethods.SubmitElectionPoSt, nil)
+	ApplyOK(t, ret)
+
+	one, two := types.NewBitField(), types.NewBitField()
+	one.Set(1)
+	two.Set(2)
+
+	ret, _ = h.Invoke(t, worker, minerAddr, actors.MAMethods.DeclareFaults, &actors.DeclareFaultsParams{Faults: two})
+	ApplyOK(t, ret)
+
+	ret, _ = h.Invoke(t, actors.NetworkAddress, minerAddr, actors.MAMethods.SubmitElectionPoSt, nil)
+	ApplyOK(t, ret)
+	assertSectorIDs(h, t, minerAddr, []uint64{1, 2})
+
+	st, err := getMinerState(context.TODO(), h.vm.StateTree(), h.bs, minerAddr)
+	assert.NoError(t, err)
+	if types.BigCmp(st.Power, types.NewInt(1024)) != 0 {
+		t.Fatalf("Expected power of 1024, got %s", st.Power)
+	}
+
+	// a later fault doesn't extend the age of the earlier one, and isn't
+	// dropped with it
+	h.BlockHeight += build.MaxFaultAge
+	ret, _ = h.Invoke(t, worker, minerAddr, actors.MAMethods.DeclareFaults, &actors.DeclareFaultsParams{Faults: one})
+	ApplyOK(t, ret)
+
+	h.BlockHeight++
+	ret, _ = h.Invoke(t, actors.NetworkAddress, minerAddr, actors.MAMethods.SubmitElectionPoSt, nil)
+	ApplyOK(t, ret)
+	assertSectorIDs(h, t, minerAddr, []uint64{1})
+
+	st, err = getMinerState(context.TODO(), h.vm.StateTree(), h.bs, minerAddr)
+	assert.NoError(t, err)
+	faults, err := st.FaultSet.All(4)
+	assert.NoError(t, err)
+	if len(faults) != 1 || faults[0] != 1 {
+		t.Fatalf("expected only sector 1 to stay faulty, got %v", faults)
+	}
+	if len(st.FaultDeclarations) != 1 || st.FaultDeclarations[0].Epoch != h.BlockHeight-1 {
+		t.Fatalf("expected the declaration of sector 1 only, got %+v", st.FaultDeclarations)
+	}
+
+	ret, _ = h.Invoke(t, worker, minerAddr, actors.MAMethods.DeclareRecoveries, &actors.DeclareRecoveriesParams{Recoveries: one})
+	ApplyOK(t, ret)
+
+	ret, _ = h.Invoke(t, actors.NetworkAddress, minerAddr, actors.MAMethods.SubmitElectionPoSt, nil)
+	ApplyOK(t, ret)
+	assertSectorIDs(h, t, minerAddr, []uint64{1})
+
+	st, err = getMinerState(context.TODO(), h.vm.StateTree(), h.bs, minerAddr)
+	assert.NoError(t, err)
+	if types.BigCmp(st.Power, types.NewInt(1024)) != 0 {
+		t.Errorf("Expected power of 1024, got %s", st.Power)
+	}
+	if n, err := st.FaultSet.Count(); err != nil || n != 0 {
+		t.Errorf("expected no faults left, got %d (err: %v)", n, err)
+	}
+	if len(st.FaultDeclarations) != 0 {
+		t.Errorf("expected no fault declarations left, got %+v", st.FaultDeclarations)
+	}
+}
+
+func TestMinerStateEncoding(t *testing.T) {
+	st := actors.StorageMinerActorState{
+		PreCommittedSectors: map[string]*actors.PreCommittedSector{},
+		Sectors:             fakeCid(t, 1),
+		ProvingSet:          fakeCid(t, 1),
+		Info:                fakeCid(t, 1),
+		FaultSet:            types.NewBitField(),
+		Power:               types.NewInt(0),
+	}
+
+	// states without fault declarations keep the old encoding
+	buf := new(bytes.Buffer)
+	assert.NoError(t, st.MarshalCBOR(buf))
+	assert.Equal(t, byte(138), buf.Bytes()[0])
+
+	var out actors.StorageMinerActorState
+	assert.NoError(t, out.UnmarshalCBOR(bytes.NewReader(buf.Bytes())))
+	assert.Empty(t, out.FaultDeclarations)
+
+	st.FaultDeclarations = []actors.FaultDeclaration{{Epoch: 10, Sectors: types.BitFieldFromSet([]uint64{1, 3})}}
+
+	buf.Reset()
+	assert.NoError(t, st.MarshalCBOR(buf))
+	assert.Equal(t, byte(139), buf.Bytes()[0])
+
+	out = actors.StorageMinerActorState{}
+	assert.NoError(t, out.UnmarshalCBOR(bytes.NewReader(buf.Bytes())))
+	if assert.Len(t, out.FaultDeclarations, 1) {
+		assert.Equal(t, uint64(10), out.FaultDeclarations[0].Epoch)
+		sectors, err := out.FaultDeclarations[0].Sectors.All(4)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint64{1, 3}, sectors)
+	}
+}
+
+func addSectorToMiner(h *Harness, t *testing.T, minerAddr, worker, client address.Address, sid uint64) uint64 {
 	t.Helper()
 	s := sectorbuilder.UserBytesForSectorSize(1024)
 	deal := h.makeFakeDeal(t, minerAddr, worker, client, s)
@@ -189,7 +457,7 @@ func addSectorToMiner(h *Harness, t *testing.T, minerAddr, worker, client addres
 		&actors.SectorPreCommitInfo{
 			SectorNumber: sid,
 			CommR:        []byte("cats"),
-			SealEpoch:    10,
+			SealEpoch:    h.BlockHeight,
 			DealIDs:      []uint64{dealid},
 		})
 	ApplyOK(t, ret)
@@ -202,6 +470,8 @@ func addSectorToMiner(h *Harness, t *testing.T, minerAddr, worker, client addres
 			DealIDs:  []uint64{dealid}, // TODO: weird that i have to pass this again
 		})
 	ApplyOK(t, ret)
+
+	return dealid
 }
 
 func assertSectorIDs(h *Harness, t *testing.T, maddr address.Address, ids []uint64) {
@@ -266,7 +536,7 @@ func (h *Harness) makeFakeDeal(t *testing.T, miner, worker, client address.Addre
 		Client:   client,
 		Provider: miner,
 
-		ProposalExpiration: 10000,
+		ProposalExpiration: h.BlockHeight + 10000,
 		Duration:           150,
 
 		StoragePricePerEpoch: types.NewInt(1),
@@ -50,7 +50,7 @@ func (sma StorageMarketActor) Exports() []interface{} {
 		// 7: sma.SettleExpiredDeals,
 		// 8: sma.ProcessStorageDealsPayment,
 		// 9: sma.SlashStorageDealCollateral,
-		// 10: sma.GetLastExpirationFromDealIDs,
+		10: sma.GetLastExpirationFromDealIDs,
 		11: sma.ActivateStorageDeals, // TODO: move under PublishStorageDeals after specs team approves
 		12: sma.ComputeDataCommitment,
 	}
@@ -658,6 +658,65 @@ func (sma StorageMarketActor) ComputeDataCommitment(act *types.Actor, vmctx type
 	return commd[:], nil
 }
 
+type GetLastExpirationFromDealIDsParams struct {
+	DealIDs []uint64
+}
+
+// GetLastExpirationFromDealIDs returns the epoch at which the last of the
+// caller's deals ends. Deals the provider made with its own worker (pledges)
+// are skipped, so sectors with only those deals get 0.
+func (sma StorageMarketActor) GetLastExpirationFromDealIDs(act *types.Actor, vmctx types.VMContext, params *GetLastExpirationFromDealIDsParams) ([]byte, ActorError) {
+	// FORK
+	if vmctx.BlockHeight() < build.ForkIcecrownHeight {
+		return nil, aerrors.Newf(255, "no method %d on actor", SMAMethods.GetLastExpirationFromDealIDs)
+	}
+
+	var self StorageMarketState
+	old := vmctx.Storage().GetHead()
+	if err := vmctx.Storage().Get(old, &self); err != nil {
+		return nil, err
+	}
+
+	deals, err := amt.LoadAMT(types.WrapStorage(vmctx.Storage()), self.Deals)
+	if err != nil {
+		return nil, aerrors.HandleExternalError(err, "loading deals amt")
+	}
+
+	workerBytes, aerr := vmctx.Send(vmctx.Message().From, MAMethods.GetWorkerAddr, types.NewInt(0), nil)
+	if aerr != nil {
+		return nil, aerr
+	}
+	providerWorker, err := address.NewFromBytes(workerBytes)
+	if err != nil {
+		return nil, aerrors.HandleExternalError(err, "parsing provider worker address bytes")
+	}
+
+	var last uint64
+	for _, deal := range params.DealIDs {
+		var dealInfo OnChainDeal
+		if err := deals.Get(deal, &dealInfo); err != nil {
+			if _, is := err.(*amt.ErrNotFound); is {
+				return nil, aerrors.New(2, "deal not found")
+			}
+			return nil, aerrors.HandleExternalError(err, "getting deal info failed")
+		}
+
+		if dealInfo.Provider != vmctx.Message().From {
+			return nil, aerrors.New(3, "GetLastExpirationFromDealIDs can only be called by deal provider")
+		}
+
+		if dealInfo.Client == providerWorker {
+			continue
+		}
+
+		if end := dealInfo.ActivationEpoch + dealInfo.Duration; end > last {
+			last = end
+		}
+	}
+
+	return types.NewInt(last).Bytes(), nil
+}
+
 /*
 func (sma StorageMarketActor) HandleCronAction(act *types.Actor, vmctx types.VMContext, params *struct{}) ([]byte, ActorError) {
 
@@ -671,7 +730,4 @@ func (sma StorageMarketActor) SlashStorageDealCollateral(act *types.Actor, vmctx
 
 }
 
-func (sma StorageMarketActor) GetLastExpirationFromDealIDs(act *types.Actor, vmctx types.VMContext, params *struct{}) ([]byte, ActorError) {
-
-}
 */
@@ -16,6 +16,8 @@ import (
 	logging "github.com/ipfs/go-log/v2"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	lps "github.com/whyrusleeping/pubsub"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
 	"go.uber.org/multierr"
 	"golang.org/x/xerrors"
 
@@ -26,6 +28,7 @@ import (
 	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/lib/sigs"
+	"github.com/filecoin-project/lotus/metrics"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 )
 
@@ -41,6 +44,12 @@ var (
 	ErrNotEnoughFunds = errors.New("not enough funds to execute transaction")
 
 	ErrInvalidToAddr = errors.New("message had invalid to address")
+
+	ErrRBFTooLowPremium = errors.New("replace by fee has too low GasPrice")
+
+	ErrTooManyPendingMessages = errors.New("too many pending messages for actor")
+
+	ErrMpoolFull = errors.New("message pool is full")
 )
 
 const (
@@ -51,6 +60,29 @@ const (
 	localUpdates = "update"
 )
 
+// Config holds the tunable parameters of the message pool
+type Config struct {
+	// ReplaceByFeeRatio is the minimum GasPrice increase, in percent, that a
+	// message with an already pending nonce needs to replace the pending one
+	ReplaceByFeeRatio uint64
+
+	// SizeLimit is the maximum number of pending messages. When the pool is
+	// full, the lowest paying messages from non-local senders are evicted.
+	SizeLimit int
+
+	// SenderPendingLimit is the maximum number of pending messages from a
+	// single non-local sender
+	SenderPendingLimit int
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		ReplaceByFeeRatio:  25,
+		SizeLimit:          5000,
+		SenderPendingLimit: 100,
+	}
+}
+
 type MessagePool struct {
 	lk sync.Mutex
 
@@ -68,7 +100,11 @@ type MessagePool struct {
 
 	minGasPrice types.BigInt
 
-	maxTxPoolSize int
+	maxTxPoolSize    int
+	maxSenderPending int
+	currentSize      int
+
+	rbfRatio uint64
 
 	blsSigCache *lru.TwoQueueCache
 
@@ -88,19 +124,44 @@ func newMsgSet() *msgSet {
 	}
 }
 
-func (ms *msgSet) add(m *types.SignedMessage) error {
+// add adds the message to the set. If a different message with the same nonce
+// is already pending, it is only replaced when the new message pays at least
+// rbfRatio percent more per unit of gas; the replaced message is returned.
+func (ms *msgSet) add(m *types.SignedMessage, rbfRatio uint64) (*types.SignedMessage, error) {
+	exms, has := ms.msgs[m.Message.Nonce]
+	if has {
+		if m.Cid() == exms.Cid() {
+			return nil, nil
+		}
+
+		minPrice := replaceByFeePrice(exms.Message.GasPrice, rbfRatio)
+		if m.Message.GasPrice.LessThan(minPrice) {
+			return nil, xerrors.Errorf("message from %s with nonce %d already in mpool, GasPrice needs to be at least %s (was %s): %w",
+				m.Message.From, m.Message.Nonce, minPrice, m.Message.GasPrice, ErrRBFTooLowPremium)
+		}
+
+		log.Infow("replacing pending message", "from", m.Message.From, "nonce", m.Message.Nonce, "old", exms.Cid(), "new", m.Cid())
+	}
+
 	if len(ms.msgs) == 0 || m.Message.Nonce >= ms.nextNonce {
 		ms.nextNonce = m.Message.Nonce + 1
 	}
-	if _, has := ms.msgs[m.Message.Nonce]; has {
-		if m.Cid() != ms.msgs[m.Message.Nonce].Cid() {
-			log.Error("Add with duplicate nonce")
-			return xerrors.Errorf("message to %s with nonce %d already in mpool", m.Message.To, m.Message.Nonce)
-		}
-	}
 	ms.msgs[m.Message.Nonce] = m
 
-	return nil
+	return exms, nil
+}
+
+// replaceByFeePrice returns the minimum GasPrice a message needs to replace
+// a pending message with the given GasPrice
+func replaceByFeePrice(cur types.BigInt, rbfRatio uint64) types.BigInt {
+	inc := types.BigDiv(types.BigMul(cur, types.NewInt(rbfRatio)), types.NewInt(100))
+	if inc.IsZero() {
+		// always require some increase, so that two messages with the same
+		// price can't keep replacing each other
+		inc = types.NewInt(1)
+	}
+
+	return types.BigAdd(cur, inc)
 }
 
 type Provider interface {
@@ -151,19 +212,21 @@ func (mpp *mpoolProvider) LoadTipSet(tsk types.TipSetKey) (*types.TipSet, error)
 	return mpp.sm.ChainStore().LoadTipSet(tsk)
 }
 
-func New(api Provider, ds dtypes.MetadataDS) (*MessagePool, error) {
+func New(api Provider, ds dtypes.MetadataDS, cfg *Config) (*MessagePool, error) {
 	cache, _ := lru.New2Q(build.BlsSignatureCacheSize)
 	mp := &MessagePool{
-		closer:        make(chan struct{}),
-		repubTk:       time.NewTicker(build.BlockDelay * 10 * time.Second),
-		localAddrs:    make(map[address.Address]struct{}),
-		pending:       make(map[address.Address]*msgSet),
-		minGasPrice:   types.NewInt(0),
-		maxTxPoolSize: 5000,
-		blsSigCache:   cache,
-		changes:       lps.New(50),
-		localMsgs:     namespace.Wrap(ds, datastore.NewKey(localMsgsDs)),
-		api:           api,
+		closer:           make(chan struct{}),
+		repubTk:          time.NewTicker(build.BlockDelay * 10 * time.Second),
+		localAddrs:       make(map[address.Address]struct{}),
+		pending:          make(map[address.Address]*msgSet),
+		minGasPrice:      types.NewInt(0),
+		maxTxPoolSize:    cfg.SizeLimit,
+		maxSenderPending: cfg.SenderPendingLimit,
+		rbfRatio:         cfg.ReplaceByFeeRatio,
+		blsSigCache:      cache,
+		changes:          lps.New(50),
+		localMsgs:        namespace.Wrap(ds, datastore.NewKey(localMsgsDs)),
+		api:              api,
 	}
 
 	if err := mp.loadLocal(); err != nil {
@@ -270,9 +333,12 @@ func (mp *MessagePool) Push(m *types.SignedMessage) (cid.Cid, error) {
 		return cid.Undef, err
 	}
 
-	if err := mp.Add(m); err != nil {
+	mp.curTsLk.Lock()
+	if err := mp.addTs(m, mp.curTs, true); err != nil {
+		mp.curTsLk.Unlock()
 		return cid.Undef, err
 	}
+	mp.curTsLk.Unlock()
 
 	mp.lk.Lock()
 	if err := mp.addLocal(m, msgb); err != nil {
@@ -287,10 +353,10 @@ func (mp *MessagePool) Push(m *types.SignedMessage) (cid.Cid, error) {
 func (mp *MessagePool) Add(m *types.SignedMessage) error {
 	mp.curTsLk.Lock()
 	defer mp.curTsLk.Unlock()
-	return mp.addTs(m, mp.curTs)
+	return mp.addTs(m, mp.curTs, false)
 }
 
-func (mp *MessagePool) addTs(m *types.SignedMessage, curTs *types.TipSet) error {
+func (mp *MessagePool) addTs(m *types.SignedMessage, curTs *types.TipSet, local bool) error {
 	// big messages are bad, anti DOS
 	if m.Size() > 32*1024 {
 		return xerrors.Errorf("mpool message too large (%dB): %w", m.Size(), ErrMessageTooBig)
@@ -330,18 +396,34 @@ func (mp *MessagePool) addTs(m *types.SignedMessage, curTs *types.TipSet) error
 	mp.lk.Lock()
 	defer mp.lk.Unlock()
 
-	return mp.addLocked(m)
+	return mp.addLocked(m, local)
 }
 
+// addSkipChecks re-adds messages from reverted blocks. They were already in
+// the pool or on chain, so they aren't subject to the pool limits either.
 func (mp *MessagePool) addSkipChecks(m *types.SignedMessage) error {
 	mp.lk.Lock()
 	defer mp.lk.Unlock()
 
-	return mp.addLocked(m)
+	_, local := mp.localAddrs[m.Message.From]
+	return mp.insertLocked(m, local)
 }
 
-func (mp *MessagePool) addLocked(m *types.SignedMessage) error {
-	log.Debugf("mpooladd: %s %s", m.Message.From, m.Message.Nonce)
+func (mp *MessagePool) addLocked(m *types.SignedMessage, local bool) error {
+	if _, ok := mp.localAddrs[m.Message.From]; ok {
+		local = true
+	}
+
+	if err := mp.checkLimits(m, local); err != nil {
+		return err
+	}
+
+	return mp.insertLocked(m, local)
+}
+
+func (mp *MessagePool) insertLocked(m *types.SignedMessage, local bool) error {
+	log.Debugf("mpooladd: %s %d", m.Message.From, m.Message.Nonce)
+
 	if m.Signature.Type == types.KTBLS {
 		mp.blsSigCache.Add(m.Cid(), m.Signature)
 	}
@@ -362,8 +444,29 @@ func (mp *MessagePool) addLocked(m *types.SignedMessage) error {
 		mp.pending[m.Message.From] = mset
 	}
 
-	if err := mset.add(m); err != nil {
-		log.Error(err)
+	before := len(mset.msgs)
+	replaced, err := mset.add(m, mp.rbfRatio)
+	if err != nil {
+		return err
+	}
+	mp.currentSize += len(mset.msgs) - before
+	stats.Record(context.TODO(), metrics.MpoolSize.M(int64(mp.currentSize)))
+
+	if local {
+		mp.localAddrs[m.Message.From] = struct{}{}
+	}
+
+	if replaced != nil {
+		if _, local := mp.localAddrs[m.Message.From]; local {
+			if err := mp.localMsgs.Delete(datastore.NewKey(string(replaced.Cid().Bytes()))); err != nil {
+				log.Warnf("removing replaced local message: %s", err)
+			}
+		}
+
+		mp.changes.Pub(api.MpoolUpdate{
+			Type:    api.MpoolRemove,
+			Message: replaced,
+		}, localUpdates)
 	}
 
 	mp.changes.Pub(api.MpoolUpdate{
@@ -373,6 +476,91 @@ func (mp *MessagePool) addLocked(m *types.SignedMessage) error {
 	return nil
 }
 
+// checkLimits makes sure there is room for the message in the pool. Local
+// messages are always accepted; when the pool is full, a non-local message is
+// only accepted if it pays more than the message evicted in its place.
+func (mp *MessagePool) checkLimits(m *types.SignedMessage, local bool) error {
+	mset, ok := mp.pending[m.Message.From]
+	if ok {
+		if _, replacing := mset.msgs[m.Message.Nonce]; replacing {
+			return nil
+		}
+	}
+
+	if local {
+		return nil
+	}
+
+	if ok && len(mset.msgs) >= mp.maxSenderPending {
+		recordRejected("sender_limit")
+		return xerrors.Errorf("sender %s has %d pending messages: %w", m.Message.From, len(mset.msgs), ErrTooManyPendingMessages)
+	}
+
+	for mp.currentSize >= mp.maxTxPoolSize {
+		victim := mp.evictionCandidate(m.Message.From)
+		if victim == nil || !m.Message.GasPrice.GreaterThan(victim.Message.GasPrice) {
+			recordRejected("pool_full")
+			return xerrors.Errorf("mpool has %d messages: %w", mp.currentSize, ErrMpoolFull)
+		}
+
+		mp.evict(victim)
+	}
+
+	return nil
+}
+
+// evictionCandidate returns the lowest paying message among the last pending
+// messages of non-local senders. Only the last message of each sender is
+// considered, so that eviction never leaves a nonce gap in the pool.
+func (mp *MessagePool) evictionCandidate(skip address.Address) *types.SignedMessage {
+	var out *types.SignedMessage
+	for a, mset := range mp.pending {
+		if a == skip {
+			continue
+		}
+		if _, local := mp.localAddrs[a]; local {
+			continue
+		}
+
+		var last *types.SignedMessage
+		for _, m := range mset.msgs {
+			if last == nil || m.Message.Nonce > last.Message.Nonce {
+				last = m
+			}
+		}
+
+		if out == nil || last.Message.GasPrice.LessThan(out.Message.GasPrice) {
+			out = last
+		}
+	}
+
+	return out
+}
+
+func (mp *MessagePool) evict(m *types.SignedMessage) {
+	log.Debugw("evicting message from mpool", "from", m.Message.From, "nonce", m.Message.Nonce, "cid", m.Cid())
+
+	mset := mp.pending[m.Message.From]
+	delete(mset.msgs, m.Message.Nonce)
+	mset.nextNonce = m.Message.Nonce
+	if len(mset.msgs) == 0 {
+		delete(mp.pending, m.Message.From)
+	}
+	mp.currentSize--
+
+	stats.Record(context.TODO(), metrics.MpoolEvicted.M(1), metrics.MpoolSize.M(int64(mp.currentSize)))
+
+	mp.changes.Pub(api.MpoolUpdate{
+		Type:    api.MpoolRemove,
+		Message: m,
+	}, localUpdates)
+}
+
+func recordRejected(reason string) {
+	ctx, _ := tag.New(context.TODO(), tag.Insert(metrics.FailureType, reason))
+	stats.Record(ctx, metrics.MpoolRejected.M(1))
+}
+
 func (mp *MessagePool) GetNonce(addr address.Address) (uint64, error) {
 	mp.curTsLk.Lock()
 	defer mp.curTsLk.Unlock()
@@ -472,7 +660,7 @@ func (mp *MessagePool) PushWithNonce(addr address.Address, cb func(uint64) (*typ
 		return nil, err
 	}
 
-	if err := mp.addLocked(msg); err != nil {
+	if err := mp.addLocked(msg, true); err != nil {
 		return nil, err
 	}
 	if err := mp.addLocal(msg, msgb); err != nil {
@@ -492,6 +680,7 @@ func (mp *MessagePool) Remove(from address.Address, nonce uint64) {
 	}
 
 	if m, ok := mset.msgs[nonce]; ok {
+		mp.currentSize--
 		mp.changes.Pub(api.MpoolUpdate{
 			Type:    api.MpoolRemove,
 			Message: m,
@@ -519,6 +708,28 @@ func (mp *MessagePool) Remove(from address.Address, nonce uint64) {
 	}
 }
 
+// PendingByCid returns the pending message with the given CID, if any
+func (mp *MessagePool) PendingByCid(c cid.Cid) (*types.SignedMessage, bool) {
+	mp.lk.Lock()
+	defer mp.lk.Unlock()
+
+	for _, mset := range mp.pending {
+		for _, m := range mset.msgs {
+			if m.Cid() == c {
+				return m, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// ReplaceByFeePrice returns the minimum GasPrice needed to replace a pending
+// message with the given GasPrice
+func (mp *MessagePool) ReplaceByFeePrice(cur types.BigInt) types.BigInt {
+	return replaceByFeePrice(cur, mp.rbfRatio)
+}
+
 func (mp *MessagePool) Pending() ([]*types.SignedMessage, *types.TipSet) {
 	mp.curTsLk.Lock()
 	defer mp.curTsLk.Unlock()
@@ -534,6 +745,34 @@ func (mp *MessagePool) Pending() ([]*types.SignedMessage, *types.TipSet) {
 	return out, mp.curTs
 }
 
+// PendingFor returns the pending messages of the given sender, ordered by
+// nonce, and the tipset the pool is currently based on
+func (mp *MessagePool) PendingFor(a address.Address) ([]*types.SignedMessage, *types.TipSet) {
+	mp.curTsLk.Lock()
+	defer mp.curTsLk.Unlock()
+
+	mp.lk.Lock()
+	defer mp.lk.Unlock()
+
+	return mp.pendingFor(a), mp.curTs
+}
+
+// PendingCids returns the CIDs under which pending messages are stored in the
+// chain blockstore, for both the signed and unsigned forms
+func (mp *MessagePool) PendingCids() []cid.Cid {
+	mp.lk.Lock()
+	defer mp.lk.Unlock()
+
+	var out []cid.Cid
+	for _, mset := range mp.pending {
+		for _, m := range mset.msgs {
+			out = append(out, m.Cid(), m.Message.Cid())
+		}
+	}
+
+	return out
+}
+
 func (mp *MessagePool) pendingFor(a address.Address) []*types.SignedMessage {
 	mset := mp.pending[a]
 	if mset == nil || len(mset.msgs) == 0 {
@@ -708,7 +947,11 @@ func (mp *MessagePool) loadLocal() error {
 			return xerrors.Errorf("unmarshaling local message: %w", err)
 		}
 
-		if err := mp.Add(&sm); err != nil {
+		mp.curTsLk.Lock()
+		err := mp.addTs(&sm, mp.curTs, true)
+		mp.curTsLk.Unlock()
+
+		if err != nil {
 			if xerrors.Is(err, ErrNonceTooLow) {
 				continue // todo: drop the message from local cache (if above certain confidence threshold)
 			}
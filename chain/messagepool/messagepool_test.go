@@ -1,6 +1,7 @@
 package messagepool
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -13,6 +14,7 @@ import (
 	_ "github.com/filecoin-project/lotus/lib/sigs/secp"
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
+	"golang.org/x/xerrors"
 )
 
 type testMpoolApi struct {
@@ -122,6 +124,26 @@ func assertNonce(t *testing.T, mp *MessagePool, addr address.Address, val uint64
 	}
 }
 
+func mkGasMessage(w *wallet.Wallet, from, to address.Address, nonce uint64, gasPrice uint64) *types.SignedMessage {
+	msg := &types.Message{
+		To:       to,
+		From:     from,
+		Value:    types.NewInt(1),
+		Nonce:    nonce,
+		GasLimit: types.NewInt(1),
+		GasPrice: types.NewInt(gasPrice),
+	}
+
+	sig, err := w.Sign(context.TODO(), from, msg.Cid().Bytes())
+	if err != nil {
+		panic(err)
+	}
+	return &types.SignedMessage{
+		Message:   *msg,
+		Signature: *sig,
+	}
+}
+
 func mustAdd(t *testing.T, mp *MessagePool, msg *types.SignedMessage) {
 	t.Helper()
 	if err := mp.Add(msg); err != nil {
@@ -139,7 +161,7 @@ func TestMessagePool(t *testing.T) {
 
 	ds := datastore.NewMapDatastore()
 
-	mp, err := New(tma, ds)
+	mp, err := New(tma, ds, DefaultConfig())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -180,7 +202,7 @@ func TestRevertMessages(t *testing.T) {
 
 	ds := datastore.NewMapDatastore()
 
-	mp, err := New(tma, ds)
+	mp, err := New(tma, ds, DefaultConfig())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -225,3 +247,149 @@ func TestRevertMessages(t *testing.T) {
 	}
 
 }
+
+func TestReplaceByFee(t *testing.T) {
+	tma := newTestMpoolApi()
+
+	w, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds := datastore.NewMapDatastore()
+
+	mp, err := New(tma, ds, DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sender, err := w.GenerateKey(types.KTBLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := mock.Address(1001)
+
+	mkMsg := func(gasPrice uint64) *types.SignedMessage {
+		msg := &types.Message{
+			To:       target,
+			From:     sender,
+			Value:    types.NewInt(1),
+			Nonce:    0,
+			GasLimit: types.NewInt(1),
+			GasPrice: types.NewInt(gasPrice),
+		}
+
+		sig, err := w.Sign(context.TODO(), sender, msg.Cid().Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &types.SignedMessage{
+			Message:   *msg,
+			Signature: *sig,
+		}
+	}
+
+	orig := mkMsg(100)
+	mustAdd(t, mp, orig)
+
+	// 20% increase is below the default 25% ratio
+	if err := mp.Add(mkMsg(120)); !xerrors.Is(err, ErrRBFTooLowPremium) {
+		t.Fatalf("expected ErrRBFTooLowPremium, got %v", err)
+	}
+
+	repl := mkMsg(125)
+	mustAdd(t, mp, repl)
+
+	p, _ := mp.Pending()
+	if len(p) != 1 {
+		t.Fatalf("expected one message in mempool, got %d", len(p))
+	}
+	if p[0].Cid() != repl.Cid() {
+		t.Fatal("expected the pending message to be replaced")
+	}
+	if _, ok := mp.PendingByCid(orig.Cid()); ok {
+		t.Fatal("replaced message still in mempool")
+	}
+
+	assertNonce(t, mp, sender, 1)
+}
+
+func TestMpoolLimits(t *testing.T) {
+	tma := newTestMpoolApi()
+
+	w, err := wallet.NewWallet(wallet.NewMemKeyStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds := datastore.NewMapDatastore()
+
+	cfg := DefaultConfig()
+	cfg.SizeLimit = 4
+	cfg.SenderPendingLimit = 2
+
+	mp, err := New(tma, ds, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := mock.Address(1001)
+
+	var senders []address.Address
+	for i := 0; i < 3; i++ {
+		sender, err := w.GenerateKey(types.KTSecp256k1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		senders = append(senders, sender)
+	}
+
+	mustAdd(t, mp, mkGasMessage(w, senders[0], target, 0, 1))
+	mustAdd(t, mp, mkGasMessage(w, senders[0], target, 1, 1))
+
+	if err := mp.Add(mkGasMessage(w, senders[0], target, 2, 1)); !xerrors.Is(err, ErrTooManyPendingMessages) {
+		t.Fatalf("expected ErrTooManyPendingMessages, got %v", err)
+	}
+
+	mustAdd(t, mp, mkGasMessage(w, senders[1], target, 0, 5))
+	mustAdd(t, mp, mkGasMessage(w, senders[1], target, 1, 5))
+
+	// pool is full, and the cheapest message pays as much as this one
+	if err := mp.Add(mkGasMessage(w, senders[2], target, 0, 1)); !xerrors.Is(err, ErrMpoolFull) {
+		t.Fatalf("expected ErrMpoolFull, got %v", err)
+	}
+
+	// evicts the last message of senders[0]
+	mustAdd(t, mp, mkGasMessage(w, senders[2], target, 0, 3))
+
+	p, _ := mp.Pending()
+	if len(p) != 4 {
+		t.Fatalf("expected 4 messages in mempool, got %d", len(p))
+	}
+	assertNonce(t, mp, senders[0], 1)
+
+	// local messages are never rejected because of the pool size
+	if _, err := mp.Push(mkGasMessage(w, senders[2], target, 1, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	p, _ = mp.Pending()
+	if len(p) != 5 {
+		t.Fatalf("expected 5 messages in mempool, got %d", len(p))
+	}
+
+	// messages from reverted blocks are re-added regardless of the limits
+	a := mock.MkBlock(nil, 1, 1)
+	b := mock.MkBlock(mock.TipSet(a), 1, 1)
+	tma.setBlockMessages(a)
+	tma.setBlockMessages(b, mkGasMessage(w, senders[0], target, 1, 1), mkGasMessage(w, senders[0], target, 2, 1))
+	tma.applyBlock(t, a)
+	tma.applyBlock(t, b)
+	tma.revertBlock(t, b)
+
+	p, _ = mp.Pending()
+	if len(p) != 7 {
+		t.Fatalf("expected 7 messages in mempool, got %d", len(p))
+	}
+	assertNonce(t, mp, senders[0], 3)
+}
@@ -0,0 +1,7 @@
+package msgindex
+
+import "github.com/filecoin-project/lotus/chain/types"
+
+func (mi *MsgIndex) HeadChange(rev, app []*types.TipSet) error {
+	return mi.headChange(rev, app)
+}
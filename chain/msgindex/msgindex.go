@@ -0,0 +1,405 @@
+package msgindex
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+var log = logging.Logger("msgindex")
+
+var ErrNotFound = xerrors.New("message not found in index")
+
+const (
+	msgPrefix  = "/m"
+	addrPrefix = "/a"
+
+	// headKey is the last tipset applied to the index
+	headKey     = "/head"
+	completeKey = "/complete"
+)
+
+// MsgInfo records where a message landed on the chain
+type MsgInfo struct {
+	// Included is the key of the tipset the message was included in
+	Included []cid.Cid
+	// Executed is the key of the tipset whose parent receipts contain the
+	// receipt of the message
+	Executed []cid.Cid
+	// Height is the height of the Included tipset
+	Height uint64
+	// Index is the position of the message in the Included tipset, and of
+	// its receipt in the Executed tipset
+	Index uint64
+}
+
+// MsgIndex maps message CIDs to the tipsets they were included and executed
+// in. The index follows the heaviest chain through head change notifications,
+// so entries of reverted tipsets are removed on reorgs.
+//
+// The index is complete after a Backfill, until a tipset is applied which
+// doesn't build on the last indexed one, e.g. after the node ran with the index
+// disabled.
+type MsgIndex struct {
+	cs *store.ChainStore
+	ds datastore.Batching
+
+	// lk is held while the head of the index changes, resets counts the
+	// times the index stopped being complete
+	lk     sync.Mutex
+	resets uint64
+}
+
+func New(cs *store.ChainStore, ds datastore.Batching) *MsgIndex {
+	mi := &MsgIndex{
+		cs: cs,
+		ds: namespace.Wrap(ds, datastore.NewKey("/msgindex")),
+	}
+
+	if err := mi.checkHead(cs.GetHeaviestTipSet()); err != nil {
+		log.Errorf("checking message index head: %s", err)
+	}
+
+	cs.SubscribeHeadChanges(mi.headChange)
+
+	return mi
+}
+
+// checkHead resets the index when the chain moved on without it
+func (mi *MsgIndex) checkHead(ts *types.TipSet) error {
+	head, err := mi.indexedHead()
+	if err != nil || head.IsEmpty() || ts == nil || head == ts.Key() {
+		return err
+	}
+
+	log.Warnw("message index is behind the chain, it has to be backfilled again", "height", ts.Height())
+
+	mi.resets++
+	if err := mi.ds.Delete(datastore.NewKey(completeKey)); err != nil {
+		return err
+	}
+	return mi.ds.Delete(datastore.NewKey(headKey))
+}
+
+func (mi *MsgIndex) headChange(rev, app []*types.TipSet) error {
+	mi.lk.Lock()
+	defer mi.lk.Unlock()
+
+	for _, ts := range rev {
+		batch, err := mi.ds.Batch()
+		if err != nil {
+			return err
+		}
+
+		if err := mi.revert(batch, ts); err != nil {
+			return xerrors.Errorf("reverting tipset %s in message index: %w", ts.Cids(), err)
+		}
+		if err := batch.Put(datastore.NewKey(headKey), ts.Parents().Bytes()); err != nil {
+			return err
+		}
+
+		if err := batch.Commit(); err != nil {
+			return err
+		}
+	}
+
+	for _, ts := range app {
+		head, err := mi.indexedHead()
+		if err != nil {
+			return err
+		}
+
+		batch, err := mi.ds.Batch()
+		if err != nil {
+			return err
+		}
+
+		if !head.IsEmpty() && head != ts.Parents() {
+			log.Warnw("message index missed tipsets, it has to be backfilled again", "height", ts.Height())
+
+			mi.resets++
+			if err := batch.Delete(datastore.NewKey(completeKey)); err != nil {
+				return err
+			}
+		}
+
+		if err := mi.apply(batch, ts); err != nil {
+			return xerrors.Errorf("applying tipset %s to message index: %w", ts.Cids(), err)
+		}
+		if err := batch.Put(datastore.NewKey(headKey), ts.Key().Bytes()); err != nil {
+			return err
+		}
+
+		if err := batch.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// indexedHead returns the key of the last applied tipset, which is empty if
+// nothing was indexed yet
+func (mi *MsgIndex) indexedHead() (types.TipSetKey, error) {
+	b, err := mi.ds.Get(datastore.NewKey(headKey))
+	if err == datastore.ErrNotFound {
+		return types.EmptyTSK, nil
+	}
+	if err != nil {
+		return types.EmptyTSK, xerrors.Errorf("reading message index head: %w", err)
+	}
+
+	return types.TipSetKeyFromBytes(b)
+}
+
+// apply indexes the messages executed by ts, which are the messages included
+// in its parent tipset
+func (mi *MsgIndex) apply(batch datastore.Batch, ts *types.TipSet) error {
+	if ts.Height() == 0 {
+		return nil
+	}
+
+	pts, err := mi.cs.LoadTipSet(ts.Parents())
+	if err != nil {
+		return xerrors.Errorf("loading parent tipset: %w", err)
+	}
+
+	msgs, err := mi.cs.MessagesForTipset(pts)
+	if err != nil {
+		return xerrors.Errorf("loading messages: %w", err)
+	}
+
+	for i, m := range msgs {
+		info := &MsgInfo{
+			Included: pts.Cids(),
+			Executed: ts.Cids(),
+			Height:   pts.Height(),
+			Index:    uint64(i),
+		}
+
+		buf := new(bytes.Buffer)
+		if err := info.MarshalCBOR(buf); err != nil {
+			return xerrors.Errorf("serializing message info: %w", err)
+		}
+
+		if err := batch.Put(msgKey(m.Cid()), buf.Bytes()); err != nil {
+			return err
+		}
+
+		vmm := m.VMMessage()
+		if err := batch.Put(addrKey(vmm.From, m.Cid()), nil); err != nil {
+			return err
+		}
+		if err := batch.Put(addrKey(vmm.To, m.Cid()), nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revert removes the messages executed by ts from the index, unless they were
+// already re-indexed as executed in a different tipset
+func (mi *MsgIndex) revert(batch datastore.Batch, ts *types.TipSet) error {
+	if ts.Height() == 0 {
+		return nil
+	}
+
+	pts, err := mi.cs.LoadTipSet(ts.Parents())
+	if err != nil {
+		return xerrors.Errorf("loading parent tipset: %w", err)
+	}
+
+	msgs, err := mi.cs.MessagesForTipset(pts)
+	if err != nil {
+		return xerrors.Errorf("loading messages: %w", err)
+	}
+
+	for _, m := range msgs {
+		info, err := mi.GetMsgInfo(m.Cid())
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if !types.CidArrsEqual(info.Executed, ts.Cids()) {
+			continue
+		}
+
+		if err := batch.Delete(msgKey(m.Cid())); err != nil {
+			return err
+		}
+
+		vmm := m.VMMessage()
+		if err := batch.Delete(addrKey(vmm.From, m.Cid())); err != nil {
+			return err
+		}
+		if err := batch.Delete(addrKey(vmm.To, m.Cid())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetMsgInfo returns the index entry of the message, or ErrNotFound
+func (mi *MsgIndex) GetMsgInfo(m cid.Cid) (*MsgInfo, error) {
+	b, err := mi.ds.Get(msgKey(m))
+	if err == datastore.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("reading message info: %w", err)
+	}
+
+	var info MsgInfo
+	if err := info.UnmarshalCBOR(bytes.NewReader(b)); err != nil {
+		return nil, xerrors.Errorf("parsing message info: %w", err)
+	}
+
+	return &info, nil
+}
+
+// MessagesFor returns the CIDs of indexed messages sent from or to addr,
+// ordered by inclusion height, newest first
+func (mi *MsgIndex) MessagesFor(addr address.Address) ([]cid.Cid, error) {
+	res, err := mi.ds.Query(query.Query{
+		Prefix:   datastore.NewKey(addrPrefix).ChildString(addr.String()).String() + "/",
+		KeysOnly: true,
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("querying address index: %w", err)
+	}
+
+	type entry struct {
+		c cid.Cid
+		h uint64
+	}
+	var entries []entry
+
+	for r := range res.Next() {
+		if r.Error != nil {
+			return nil, xerrors.Errorf("address index query: %w", r.Error)
+		}
+
+		c, err := cid.Decode(datastore.NewKey(r.Key).BaseNamespace())
+		if err != nil {
+			return nil, xerrors.Errorf("parsing indexed message cid: %w", err)
+		}
+
+		info, err := mi.GetMsgInfo(c)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry{c: c, h: info.Height})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].h > entries[j].h
+	})
+
+	out := make([]cid.Cid, len(entries))
+	for i, e := range entries {
+		out[i] = e.c
+	}
+
+	return out, nil
+}
+
+// Backfill indexes all messages on the chain ending at ts. Once it reaches
+// the genesis the index is marked as complete, unless the index missed
+// tipsets in the meantime.
+func (mi *MsgIndex) Backfill(ctx context.Context, ts *types.TipSet) (int, error) {
+	mi.lk.Lock()
+	resets := mi.resets
+	mi.lk.Unlock()
+
+	head := ts.Key()
+
+	var n int
+	for ts.Height() > 0 {
+		select {
+		case <-ctx.Done():
+			return n, ctx.Err()
+		default:
+		}
+
+		batch, err := mi.ds.Batch()
+		if err != nil {
+			return n, err
+		}
+		if err := mi.apply(batch, ts); err != nil {
+			return n, xerrors.Errorf("indexing tipset at height %d: %w", ts.Height(), err)
+		}
+		if err := batch.Commit(); err != nil {
+			return n, err
+		}
+		n++
+
+		if ts.Height()%1000 == 0 {
+			log.Infow("backfilling message index", "height", ts.Height())
+		}
+
+		pts, err := mi.cs.LoadTipSet(ts.Parents())
+		if err != nil {
+			return n, xerrors.Errorf("loading parent tipset: %w", err)
+		}
+		ts = pts
+	}
+
+	mi.lk.Lock()
+	defer mi.lk.Unlock()
+
+	if mi.resets != resets {
+		return n, xerrors.Errorf("message index missed tipsets while backfilling, backfill it again")
+	}
+
+	// head changes applied since the backfill started build on its head
+	indexed, err := mi.indexedHead()
+	if err != nil {
+		return n, err
+	}
+	if indexed.IsEmpty() {
+		if err := mi.ds.Put(datastore.NewKey(headKey), head.Bytes()); err != nil {
+			return n, xerrors.Errorf("recording message index head: %w", err)
+		}
+	}
+
+	if err := mi.ds.Put(datastore.NewKey(completeKey), []byte{1}); err != nil {
+		return n, xerrors.Errorf("marking index as complete: %w", err)
+	}
+
+	return n, nil
+}
+
+// Complete returns whether the index covers the whole chain, which is the
+// case after a successful Backfill until the index misses a tipset
+func (mi *MsgIndex) Complete() (bool, error) {
+	return mi.ds.Has(datastore.NewKey(completeKey))
+}
+
+func msgKey(c cid.Cid) datastore.Key {
+	return datastore.NewKey(msgPrefix).ChildString(c.String())
+}
+
+func addrKey(addr address.Address, c cid.Cid) datastore.Key {
+	return datastore.NewKey(addrPrefix).ChildString(addr.String()).ChildString(c.String())
+}
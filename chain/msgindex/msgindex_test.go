@@ -0,0 +1,135 @@
+package msgindex_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/gen"
+	"github.com/filecoin-project/lotus/chain/msgindex"
+	"github.com/filecoin-project/lotus/chain/types"
+	_ "github.com/filecoin-project/lotus/lib/sigs/bls"
+	_ "github.com/filecoin-project/lotus/lib/sigs/secp"
+)
+
+func init() {
+	build.SectorSizes = []uint64{1024}
+	build.MinimumMinerPower = 1024
+}
+
+func TestMsgIndex(t *testing.T) {
+	cg, err := gen.NewGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tss []*types.TipSet
+	for i := 0; i < 5; i++ {
+		mts, err := cg.NextTipSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tss = append(tss, mts.TipSet.TipSet())
+	}
+
+	cs := cg.ChainStore()
+	mi := msgindex.New(cs, dssync.MutexWrap(datastore.NewMapDatastore()))
+
+	if complete, err := mi.Complete(); err != nil || complete {
+		t.Fatalf("expected incomplete index before backfill (err: %v)", err)
+	}
+
+	last := tss[len(tss)-1]
+	if _, err := mi.Backfill(context.TODO(), last); err != nil {
+		t.Fatal(err)
+	}
+
+	if complete, err := mi.Complete(); err != nil || !complete {
+		t.Fatalf("expected complete index after backfill (err: %v)", err)
+	}
+
+	var indexed int
+	for i, ts := range tss[:len(tss)-1] {
+		msgs, err := cs.MessagesForTipset(ts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for j, m := range msgs {
+			info, err := mi.GetMsgInfo(m.Cid())
+			if err != nil {
+				t.Fatalf("message %s: %s", m.Cid(), err)
+			}
+
+			if !types.CidArrsEqual(info.Included, ts.Cids()) {
+				t.Errorf("message %s: wrong inclusion tipset", m.Cid())
+			}
+			if !types.CidArrsEqual(info.Executed, tss[i+1].Cids()) {
+				t.Errorf("message %s: wrong execution tipset", m.Cid())
+			}
+			if info.Height != ts.Height() || info.Index != uint64(j) {
+				t.Errorf("message %s: expected height %d index %d, got %d %d", m.Cid(), ts.Height(), j, info.Height, info.Index)
+			}
+		}
+		indexed += len(msgs)
+	}
+
+	if indexed == 0 {
+		t.Fatal("expected generated chain to contain messages")
+	}
+
+	banked, err := mi.MessagesFor(cg.Banker())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(banked) != indexed {
+		t.Errorf("expected %d messages from the banker, got %d", indexed, len(banked))
+	}
+
+	// reverting the head un-indexes the messages it executed
+	if err := mi.HeadChange([]*types.TipSet{last}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, err := cs.MessagesForTipset(tss[len(tss)-2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range msgs {
+		if _, err := mi.GetMsgInfo(m.Cid()); err != msgindex.ErrNotFound {
+			t.Errorf("expected reverted message %s to be removed from the index, got %v", m.Cid(), err)
+		}
+	}
+
+	// and applying it again restores them
+	if err := mi.HeadChange(nil, []*types.TipSet{last}); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range msgs {
+		if _, err := mi.GetMsgInfo(m.Cid()); err != nil {
+			t.Errorf("message %s: %s", m.Cid(), err)
+		}
+	}
+
+	if complete, err := mi.Complete(); err != nil || !complete {
+		t.Fatalf("expected the index to stay complete across a reorg (err: %v)", err)
+	}
+
+	// a tipset which doesn't build on the indexed head leaves a gap
+	if err := mi.HeadChange(nil, []*types.TipSet{tss[1]}); err != nil {
+		t.Fatal(err)
+	}
+	if complete, err := mi.Complete(); err != nil || complete {
+		t.Fatalf("expected incomplete index after a gap (err: %v)", err)
+	}
+
+	if _, err := mi.Backfill(context.TODO(), tss[1]); err != nil {
+		t.Fatal(err)
+	}
+	if complete, err := mi.Complete(); err != nil || !complete {
+		t.Fatalf("expected complete index after backfilling again (err: %v)", err)
+	}
+}
@@ -70,6 +70,53 @@ func (sm *StateManager) CallRaw(ctx context.Context, msg *types.Message, bstate
 
 }
 
+// CallWithGas executes msg on top of the state computed for ts, after
+// applying priorMsgs, as if they were all included in the next tipset. Unlike
+// CallRaw, the nonce and gas fields of the message are used as they are.
+func (sm *StateManager) CallWithGas(ctx context.Context, msg *types.Message, priorMsgs []*types.Message, ts *types.TipSet) (*api.InvocResult, error) {
+	ctx, span := trace.StartSpan(ctx, "statemanager.CallWithGas")
+	defer span.End()
+
+	if ts == nil {
+		ts = sm.cs.GetHeaviestTipSet()
+	}
+
+	state, _, err := sm.TipSetState(ctx, ts)
+	if err != nil {
+		return nil, xerrors.Errorf("computing tipset state: %w", err)
+	}
+
+	r := store.NewChainRand(sm.cs, ts.Cids(), ts.Height())
+
+	vmi, err := vm.NewVM(state, ts.Height()+1, r, actors.NetworkAddress, sm.cs.Blockstore(), sm.cs.VMSys())
+	if err != nil {
+		return nil, xerrors.Errorf("failed to set up vm: %w", err)
+	}
+
+	for i, m := range priorMsgs {
+		if _, err := vmi.ApplyMessage(ctx, m); err != nil {
+			return nil, xerrors.Errorf("applying prior message %d (nonce %d): %w", i, m.Nonce, err)
+		}
+	}
+
+	ret, err := vmi.ApplyMessage(ctx, msg)
+	if err != nil {
+		return nil, xerrors.Errorf("apply message failed: %w", err)
+	}
+
+	var errs string
+	if ret.ActorErr != nil {
+		errs = ret.ActorErr.Error()
+	}
+
+	return &api.InvocResult{
+		Msg:                msg,
+		MsgRct:             &ret.MessageReceipt,
+		InternalExecutions: ret.InternalExecutions,
+		Error:              errs,
+	}, nil
+}
+
 func (sm *StateManager) Call(ctx context.Context, msg *types.Message, ts *types.TipSet) (*api.InvocResult, error) {
 	if ts == nil {
 		ts = sm.cs.GetHeaviestTipSet()
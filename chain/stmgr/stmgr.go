@@ -8,6 +8,7 @@ import (
 	"github.com/filecoin-project/go-address"
 	amt "github.com/filecoin-project/go-amt-ipld"
 	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/msgindex"
 	"github.com/filecoin-project/lotus/chain/state"
 	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
@@ -32,6 +33,8 @@ type StateManager struct {
 	compWait map[string]chan struct{}
 	stlk     sync.Mutex
 	newVM    func(cid.Cid, uint64, vm.Rand, address.Address, blockstore.Blockstore, *types.VMSyscalls) (*vm.VM, error)
+
+	msgIndex *msgindex.MsgIndex
 }
 
 func NewStateManager(cs *store.ChainStore) *StateManager {
@@ -43,6 +46,33 @@ func NewStateManager(cs *store.ChainStore) *StateManager {
 	}
 }
 
+// SetMsgIndex makes message lookups consult the index before searching
+// back through the chain
+func (sm *StateManager) SetMsgIndex(mi *msgindex.MsgIndex) {
+	sm.msgIndex = mi
+}
+
+// MsgIndex returns the message index, or nil if it isn't enabled
+func (sm *StateManager) MsgIndex() *msgindex.MsgIndex {
+	return sm.msgIndex
+}
+
+// PruneChain garbage collects the chain blockstore, keeping the state trees of
+// the last keepStateRoots tipsets and all states cached by the state manager
+func (sm *StateManager) PruneChain(ctx context.Context, keepStateRoots uint64, extraRoots func() []cid.Cid) (*store.PruneResult, error) {
+	return sm.cs.Prune(ctx, nil, keepStateRoots, func() []cid.Cid {
+		roots := extraRoots()
+
+		sm.stlk.Lock()
+		defer sm.stlk.Unlock()
+
+		for _, c := range sm.stCache {
+			roots = append(roots, c...)
+		}
+		return roots
+	})
+}
+
 func cidsToKey(cids []cid.Cid) string {
 	var out string
 	for _, c := range cids {
@@ -395,6 +425,15 @@ func (sm *StateManager) GetReceipt(ctx context.Context, msg cid.Cid, ts *types.T
 		return r, nil
 	}
 
+	_, r, found, err := sm.searchIndexForMsg(ts, msg)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to look up message in index: %w", err)
+	}
+
+	if found {
+		return r, nil
+	}
+
 	_, r, err = sm.searchBackForMsg(ctx, ts, m)
 	if err != nil {
 		return nil, fmt.Errorf("failed to look back through chain for message: %w", err)
@@ -436,6 +475,15 @@ func (sm *StateManager) WaitForMessage(ctx context.Context, mcid cid.Cid) (*type
 		return head[0].Val, r, nil
 	}
 
+	fts, r, found, err := sm.searchIndexForMsg(head[0].Val, mcid)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("failed to look up message in index: %w", err)
+	}
+
+	if found {
+		return fts, r, nil
+	}
+
 	var backTs *types.TipSet
 	var backRcp *types.MessageReceipt
 	backSearchWait := make(chan struct{})
@@ -482,6 +530,39 @@ func (sm *StateManager) WaitForMessage(ctx context.Context, mcid cid.Cid) (*type
 	}
 }
 
+// searchIndexForMsg looks up the tipset which executed the message in the
+// message index. The index follows the heaviest chain, so it's only consulted
+// for lookups starting at the current head
+func (sm *StateManager) searchIndexForMsg(from *types.TipSet, mcid cid.Cid) (*types.TipSet, *types.MessageReceipt, bool, error) {
+	if sm.msgIndex == nil || !from.Equals(sm.cs.GetHeaviestTipSet()) {
+		return nil, nil, false, nil
+	}
+
+	info, err := sm.msgIndex.GetMsgInfo(mcid)
+	if err == msgindex.ErrNotFound {
+		return nil, nil, false, nil
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	if info.Height >= from.Height() {
+		return nil, nil, false, nil
+	}
+
+	ts, err := sm.cs.LoadTipSet(types.NewTipSetKey(info.Executed...))
+	if err != nil {
+		return nil, nil, false, xerrors.Errorf("loading execution tipset: %w", err)
+	}
+
+	r, err := sm.cs.GetParentReceipt(ts.Blocks()[0], int(info.Index))
+	if err != nil {
+		return nil, nil, false, xerrors.Errorf("loading receipt: %w", err)
+	}
+
+	return ts, r, true, nil
+}
+
 func (sm *StateManager) searchBackForMsg(ctx context.Context, from *types.TipSet, m store.ChainMsg) (*types.TipSet, *types.MessageReceipt, error) {
 
 	cur := from
@@ -0,0 +1,342 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// PruneResult summarizes a chain blockstore garbage collection
+type PruneResult struct {
+	Reachable uint64
+	Removed   uint64
+}
+
+// Prune deletes all blocks from the chain blockstore which aren't reachable
+// from ts: block headers back to genesis, all messages and receipts, the state
+// trees of the last keepStateRoots tipsets and the genesis state. Blocks linked
+// from the roots returned by extraRoots are also kept.
+//
+// Prune can run while the node is online. Blocks written or read through the
+// chain store blockstore while it runs are never deleted, so bitswap and state
+// computation don't need to wait for it. Chains pinned by syncs when it starts
+// are kept like the chain of ts.
+func (cs *ChainStore) Prune(ctx context.Context, ts *types.TipSet, keepStateRoots uint64, extraRoots func() []cid.Cid) (*PruneResult, error) {
+	cs.pruneLk.Lock()
+	defer cs.pruneLk.Unlock()
+
+	if ts == nil {
+		ts = cs.GetHeaviestTipSet()
+	}
+
+	// blocks of chains pinned later are tracked when they are accessed
+	cs.gcLk.Lock()
+	roots := [][]cid.Cid{ts.Cids()}
+	for _, pin := range cs.pins {
+		roots = append(roots, pin)
+	}
+	cs.gcbs.startTracking()
+	cs.gcLk.Unlock()
+
+	defer cs.gcbs.stopTracking()
+
+	live := cid.NewSet()
+	for _, root := range roots {
+		if err := cs.markChain(ctx, root, keepStateRoots, live); err != nil {
+			return nil, xerrors.Errorf("marking chain: %w", err)
+		}
+	}
+
+	// extra roots are collected after tracking starts, so that blocks they
+	// link to and which are written later are tracked
+	for _, c := range extraRoots() {
+		if err := cs.markLinks(c, live); err != nil {
+			return nil, xerrors.Errorf("marking extra root %s: %w", c, err)
+		}
+	}
+
+	keys, err := cs.gcbs.Blockstore.AllKeysChan(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("listing blockstore keys: %w", err)
+	}
+
+	res := &PruneResult{Reachable: uint64(live.Len())}
+	for c := range keys {
+		if live.Has(c) {
+			continue
+		}
+
+		deleted, err := cs.gcbs.deleteUntracked(c)
+		if err != nil {
+			return nil, xerrors.Errorf("deleting block %s: %w", c, err)
+		}
+		if deleted {
+			res.Removed++
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cs.pruneTipsetTracker(live)
+
+	log.Infow("chain blockstore pruned", "reachable", res.Reachable, "removed", res.Removed)
+	return res, nil
+}
+
+// Pin keeps the chain of ts from being pruned until the returned function is
+// called. It is held while syncing, as blocks of a chain which isn't reachable
+// from the heaviest tipset yet can't be deleted while they are validated.
+func (cs *ChainStore) Pin(ts *types.TipSet) func() {
+	cs.gcLk.Lock()
+	defer cs.gcLk.Unlock()
+
+	cs.pinCtr++
+	id := cs.pinCtr
+	cs.pins[id] = ts.Cids()
+
+	return func() {
+		cs.gcLk.Lock()
+		defer cs.gcLk.Unlock()
+
+		delete(cs.pins, id)
+	}
+}
+
+// markChain marks the tipsets from root back to genesis. Once it reaches
+// tipsets marked from another root, it only continues while state trees are
+// kept.
+func (cs *ChainStore) markChain(ctx context.Context, root []cid.Cid, keepStateRoots uint64, live *cid.Set) error {
+	cur := root
+
+	for i := uint64(0); len(cur) > 0; i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		recent := i < keepStateRoots
+		marked := true
+		var parents []cid.Cid
+
+		for _, bc := range cur {
+			data, err := cs.gcbs.Blockstore.Get(bc)
+			if err == bstore.ErrNotFound {
+				// headers of pinned chains may not be fetched yet, they are
+				// tracked once they are written
+				continue
+			}
+			if err != nil {
+				return xerrors.Errorf("getting block header %s: %w", bc, err)
+			}
+
+			var b types.BlockHeader
+			if err := b.UnmarshalCBOR(bytes.NewReader(data.RawData())); err != nil {
+				return xerrors.Errorf("unmarshaling block header %s: %w", bc, err)
+			}
+
+			parents = b.Parents
+
+			if live.Visit(bc) {
+				marked = false
+
+				if err := cs.markLinks(b.Messages, live); err != nil {
+					return xerrors.Errorf("marking messages: %w", err)
+				}
+
+				if err := cs.markLinks(b.ParentMessageReceipts, live); err != nil {
+					return xerrors.Errorf("marking receipts: %w", err)
+				}
+			}
+
+			if recent || b.Height == 0 {
+				if err := cs.markLinks(b.ParentStateRoot, live); err != nil {
+					return xerrors.Errorf("marking state tree: %w", err)
+				}
+			}
+		}
+
+		if marked && !recent {
+			return nil
+		}
+
+		cur = parents
+	}
+
+	return nil
+}
+
+func (cs *ChainStore) markLinks(root cid.Cid, live *cid.Set) error {
+	toWalk := []cid.Cid{root}
+
+	for len(toWalk) > 0 {
+		c := toWalk[len(toWalk)-1]
+		toWalk = toWalk[:len(toWalk)-1]
+
+		if !live.Visit(c) {
+			continue
+		}
+
+		if c.Prefix().Codec != cid.DagCBOR {
+			continue
+		}
+
+		data, err := cs.gcbs.Blockstore.Get(c)
+		if err == bstore.ErrNotFound {
+			// not all data has to be present, e.g. on nodes started from a
+			// snapshot
+			continue
+		}
+		if err != nil {
+			return xerrors.Errorf("getting block %s: %w", c, err)
+		}
+
+		links, err := cbg.ScanForLinks(bytes.NewReader(data.RawData()))
+		if err != nil {
+			return xerrors.Errorf("scanning links of %s: %w", c, err)
+		}
+
+		toWalk = append(toWalk, links...)
+	}
+
+	return nil
+}
+
+// pruneTipsetTracker drops deleted block headers from the tipset tracker
+func (cs *ChainStore) pruneTipsetTracker(live *cid.Set) {
+	cs.tstLk.Lock()
+	defer cs.tstLk.Unlock()
+
+	for h, bcs := range cs.tipsets {
+		var keep []cid.Cid
+		for _, c := range bcs {
+			if live.Has(c) {
+				keep = append(keep, c)
+			}
+		}
+
+		if len(keep) == 0 {
+			delete(cs.tipsets, h)
+			continue
+		}
+		cs.tipsets[h] = keep
+	}
+}
+
+// gcBlockstore records blocks accessed while a garbage collection is running,
+// protecting them from being deleted by it
+type gcBlockstore struct {
+	bstore.Blockstore
+
+	// lk is held for reading by accesses and for writing by deletions, so
+	// that a block can't be accessed between the check and the deletion
+	lk       sync.RWMutex
+	tracking bool
+
+	touchLk sync.Mutex
+	touched map[cid.Cid]struct{}
+}
+
+func newGCBlockstore(bs bstore.Blockstore) *gcBlockstore {
+	return &gcBlockstore{Blockstore: bs}
+}
+
+func (bs *gcBlockstore) startTracking() {
+	bs.lk.Lock()
+	defer bs.lk.Unlock()
+
+	bs.tracking = true
+	bs.touched = map[cid.Cid]struct{}{}
+}
+
+func (bs *gcBlockstore) stopTracking() {
+	bs.lk.Lock()
+	defer bs.lk.Unlock()
+
+	bs.tracking = false
+	bs.touched = nil
+}
+
+// touch must be called with lk held for reading
+func (bs *gcBlockstore) touch(cids ...cid.Cid) {
+	if !bs.tracking {
+		return
+	}
+
+	bs.touchLk.Lock()
+	for _, c := range cids {
+		bs.touched[c] = struct{}{}
+	}
+	bs.touchLk.Unlock()
+}
+
+// deleteUntracked deletes the block unless it was accessed since tracking
+// started
+func (bs *gcBlockstore) deleteUntracked(c cid.Cid) (bool, error) {
+	bs.lk.Lock()
+	defer bs.lk.Unlock()
+
+	if _, ok := bs.touched[c]; ok {
+		return false, nil
+	}
+
+	if err := bs.Blockstore.DeleteBlock(c); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (bs *gcBlockstore) Has(c cid.Cid) (bool, error) {
+	bs.lk.RLock()
+	defer bs.lk.RUnlock()
+
+	bs.touch(c)
+	return bs.Blockstore.Has(c)
+}
+
+func (bs *gcBlockstore) Get(c cid.Cid) (blocks.Block, error) {
+	bs.lk.RLock()
+	defer bs.lk.RUnlock()
+
+	bs.touch(c)
+	return bs.Blockstore.Get(c)
+}
+
+func (bs *gcBlockstore) GetSize(c cid.Cid) (int, error) {
+	bs.lk.RLock()
+	defer bs.lk.RUnlock()
+
+	bs.touch(c)
+	return bs.Blockstore.GetSize(c)
+}
+
+func (bs *gcBlockstore) Put(b blocks.Block) error {
+	bs.lk.RLock()
+	defer bs.lk.RUnlock()
+
+	bs.touch(b.Cid())
+	return bs.Blockstore.Put(b)
+}
+
+func (bs *gcBlockstore) PutMany(blks []blocks.Block) error {
+	bs.lk.RLock()
+	defer bs.lk.RUnlock()
+
+	for _, b := range blks {
+		bs.touch(b.Cid())
+	}
+	return bs.Blockstore.PutMany(blks)
+}
+
+var _ bstore.Blockstore = (*gcBlockstore)(nil)
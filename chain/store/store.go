@@ -23,16 +23,14 @@ import (
 
 	lru "github.com/hashicorp/golang-lru"
 	block "github.com/ipfs/go-block-format"
-	"github.com/ipfs/go-blockservice"
 	car "github.com/ipfs/go-car"
+	carutil "github.com/ipfs/go-car/util"
 	"github.com/ipfs/go-cid"
 	dstore "github.com/ipfs/go-datastore"
 	hamt "github.com/ipfs/go-hamt-ipld"
-	blockstore "github.com/ipfs/go-ipfs-blockstore"
 	bstore "github.com/ipfs/go-ipfs-blockstore"
-	format "github.com/ipfs/go-ipld-format"
+	cbor "github.com/ipfs/go-ipld-cbor"
 	logging "github.com/ipfs/go-log/v2"
-	dag "github.com/ipfs/go-merkledag"
 	cbg "github.com/whyrusleeping/cbor-gen"
 	pubsub "github.com/whyrusleeping/pubsub"
 	"golang.org/x/xerrors"
@@ -43,8 +41,16 @@ var log = logging.Logger("chainstore")
 var chainHeadKey = dstore.NewKey("head")
 
 type ChainStore struct {
-	bs bstore.Blockstore
-	ds dstore.Datastore
+	bs   bstore.Blockstore
+	gcbs *gcBlockstore
+	ds   dstore.Datastore
+
+	pruneLk sync.Mutex
+
+	// gcLk guards pins, and the start of prune
+	gcLk   sync.Mutex
+	pins   map[uint64][]cid.Cid
+	pinCtr uint64
 
 	heaviestLk sync.Mutex
 	heaviest   *types.TipSet
@@ -67,11 +73,14 @@ type ChainStore struct {
 func NewChainStore(bs bstore.Blockstore, ds dstore.Batching, vmcalls *types.VMSyscalls) *ChainStore {
 	c, _ := lru.NewARC(2048)
 	tsc, _ := lru.NewARC(4096)
+	gcbs := newGCBlockstore(bs)
 	cs := &ChainStore{
-		bs:       bs,
+		bs:       gcbs,
+		gcbs:     gcbs,
 		ds:       ds,
 		bestTips: pubsub.New(64),
 		tipsets:  make(map[uint64][]cid.Cid),
+		pins:     make(map[uint64][]cid.Cid),
 		mmCache:  c,
 		tsCache:  tsc,
 		vmcalls:  vmcalls,
@@ -934,68 +943,118 @@ func (cs *ChainStore) GetTipsetByHeight(ctx context.Context, h uint64, ts *types
 	}
 }
 
-func recurseLinks(bs blockstore.Blockstore, root cid.Cid, in []cid.Cid) ([]cid.Cid, error) {
-	data, err := bs.Get(root)
-	if err != nil {
-		return nil, err
-	}
+// exportLinks writes root and all blocks reachable from it to w, skipping
+// blocks already in seen
+func (cs *ChainStore) exportLinks(root cid.Cid, seen *cid.Set, w io.Writer) error {
+	toWalk := []cid.Cid{root}
 
-	top, err := cbg.ScanForLinks(bytes.NewReader(data.RawData()))
-	if err != nil {
-		return nil, err
-	}
+	for len(toWalk) > 0 {
+		c := toWalk[len(toWalk)-1]
+		toWalk = toWalk[:len(toWalk)-1]
 
-	in = append(in, top...)
-	for _, c := range top {
-		var err error
-		in, err = recurseLinks(bs, c, in)
+		if !seen.Visit(c) {
+			continue
+		}
+
+		data, err := cs.bs.Get(c)
 		if err != nil {
-			return nil, err
+			return xerrors.Errorf("getting block %s: %w", c, err)
 		}
+
+		if err := carutil.LdWrite(w, c.Bytes(), data.RawData()); err != nil {
+			return xerrors.Errorf("writing block %s: %w", c, err)
+		}
+
+		if c.Prefix().Codec != cid.DagCBOR {
+			continue
+		}
+
+		links, err := cbg.ScanForLinks(bytes.NewReader(data.RawData()))
+		if err != nil {
+			return xerrors.Errorf("scanning links of %s: %w", c, err)
+		}
+
+		toWalk = append(toWalk, links...)
 	}
 
-	return in, nil
+	return nil
 }
 
-func (cs *ChainStore) Export(ctx context.Context, ts *types.TipSet, w io.Writer) error {
+// Export writes the chain ending at ts to w as a CAR file. Block headers are
+// always exported back to genesis. State trees and receipts are only exported
+// for the genesis and the last inclRecentRoots tipsets, making the
+// CAR usable as a snapshot to sync from. If skipOldMsgs is set, messages of
+// older tipsets are left out as well.
+func (cs *ChainStore) Export(ctx context.Context, ts *types.TipSet, inclRecentRoots uint64, skipOldMsgs bool, w io.Writer) error {
 	if ts == nil {
 		ts = cs.GetHeaviestTipSet()
 	}
-	bsrv := blockservice.New(cs.bs, nil)
-	dserv := dag.NewDAGService(bsrv)
-	return car.WriteCarWithWalker(ctx, dserv, ts.Cids(), w, func(nd format.Node) ([]*format.Link, error) {
-		var b types.BlockHeader
-		if err := b.UnmarshalCBOR(bytes.NewBuffer(nd.RawData())); err != nil {
-			return nil, err
-		}
 
-		var out []*format.Link
-		for _, p := range b.Parents {
-			out = append(out, &format.Link{Cid: p})
-		}
+	hb, err := cbor.DumpObject(&car.CarHeader{
+		Roots:   ts.Cids(),
+		Version: 1,
+	})
+	if err != nil {
+		return xerrors.Errorf("serializing car header: %w", err)
+	}
 
-		cids, err := recurseLinks(cs.bs, b.Messages, nil)
-		if err != nil {
-			return nil, err
-		}
+	if err := carutil.LdWrite(w, hb); err != nil {
+		return xerrors.Errorf("writing car header: %w", err)
+	}
+
+	seen := cid.NewSet()
 
-		for _, c := range cids {
-			out = append(out, &format.Link{Cid: c})
+	// walk tipsets rather than heights, null rounds don't count towards
+	// inclRecentRoots
+	for cur, i := ts, uint64(0); ; i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
 		}
 
-		if b.Height == 0 {
-			cids, err := recurseLinks(cs.bs, b.ParentStateRoot, nil)
+		recent := i < inclRecentRoots
+
+		for _, b := range cur.Blocks() {
+			if !seen.Visit(b.Cid()) {
+				continue
+			}
+
+			data, err := cs.bs.Get(b.Cid())
 			if err != nil {
-				return nil, err
+				return xerrors.Errorf("getting block header %s: %w", b.Cid(), err)
 			}
 
-			for _, c := range cids {
-				out = append(out, &format.Link{Cid: c})
+			if err := carutil.LdWrite(w, b.Cid().Bytes(), data.RawData()); err != nil {
+				return xerrors.Errorf("writing block header %s: %w", b.Cid(), err)
+			}
+
+			if recent || !skipOldMsgs {
+				if err := cs.exportLinks(b.Messages, seen, w); err != nil {
+					return xerrors.Errorf("exporting messages: %w", err)
+				}
+			}
+
+			if recent || b.Height == 0 {
+				if err := cs.exportLinks(b.ParentStateRoot, seen, w); err != nil {
+					return xerrors.Errorf("exporting state tree: %w", err)
+				}
+
+				if err := cs.exportLinks(b.ParentMessageReceipts, seen, w); err != nil {
+					return xerrors.Errorf("exporting receipts: %w", err)
+				}
 			}
 		}
 
-		return out, nil
-	})
+		if cur.Height() == 0 {
+			return nil
+		}
+
+		cur, err = cs.LoadTipSet(cur.Parents())
+		if err != nil {
+			return xerrors.Errorf("loading parent tipset: %w", err)
+		}
+	}
 }
 
 func (cs *ChainStore) Import(r io.Reader) (*types.TipSet, error) {
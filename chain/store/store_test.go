@@ -1,6 +1,7 @@
 package store_test
 
 import (
+	"bytes"
 	"context"
 	"testing"
 
@@ -8,7 +9,12 @@ import (
 	"github.com/filecoin-project/lotus/chain/gen"
 	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
+	_ "github.com/filecoin-project/lotus/lib/sigs/bls"
+	_ "github.com/filecoin-project/lotus/lib/sigs/secp"
 	"github.com/filecoin-project/lotus/node/repo"
+	block "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
 	blockstore "github.com/ipfs/go-ipfs-blockstore"
 )
 
@@ -66,3 +72,181 @@ func BenchmarkGetRandomness(b *testing.B) {
 		}
 	}
 }
+
+func TestChainExportSnapshot(t *testing.T) {
+	cg, err := gen.NewGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tss []*types.TipSet
+	for i := 0; i < 10; i++ {
+		ts, err := cg.NextTipSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tss = append(tss, ts.TipSet.TipSet())
+	}
+	last := tss[len(tss)-1]
+
+	buf := new(bytes.Buffer)
+	if err := cg.ChainStore().Export(context.TODO(), last, 3, true, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	bs := blockstore.NewBlockstore(datastore.NewMapDatastore())
+	cs := store.NewChainStore(bs, datastore.NewMapDatastore(), nil)
+
+	root, err := cs.Import(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !root.Equals(last) {
+		t.Fatal("imported root doesn't match exported tipset")
+	}
+
+	for i, ts := range tss {
+		recent := i >= len(tss)-3
+
+		for _, b := range ts.Blocks() {
+			has, err := bs.Has(b.Cid())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !has {
+				t.Fatalf("missing block header at height %d", ts.Height())
+			}
+
+			has, err = bs.Has(b.ParentStateRoot)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the first tipset shares its parent state with the genesis,
+			// which is always exported
+			if has != recent && i > 0 {
+				t.Errorf("state root at height %d: expected present=%t", ts.Height(), recent)
+			}
+
+			has, err = bs.Has(b.Messages)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if has != recent {
+				t.Errorf("messages at height %d: expected present=%t", ts.Height(), recent)
+			}
+		}
+	}
+}
+
+func TestChainPrune(t *testing.T) {
+	cg, err := gen.NewGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tss []*types.TipSet
+	for i := 0; i < 10; i++ {
+		ts, err := cg.NextTipSet()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tss = append(tss, ts.TipSet.TipSet())
+	}
+	last := tss[len(tss)-1]
+
+	buf := new(bytes.Buffer)
+	if err := cg.ChainStore().Export(context.TODO(), last, uint64(len(tss)), false, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// a sync target whose blocks aren't fetched yet
+	next, err := cg.NextTipSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bs := blockstore.NewBlockstore(datastore.NewMapDatastore())
+	cs := store.NewChainStore(bs, datastore.NewMapDatastore(), nil)
+	if _, err := cs.Import(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	garbage := block.NewBlock([]byte("unreachable"))
+	if err := bs.Put(garbage); err != nil {
+		t.Fatal(err)
+	}
+
+	kept := block.NewBlock([]byte("extra root"))
+	if err := bs.Put(kept); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := cs.Prune(context.TODO(), last, 3, func() []cid.Cid {
+		return []cid.Cid{kept.Cid()}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Removed == 0 {
+		t.Fatal("expected prune to remove blocks")
+	}
+
+	expect := func(c cid.Cid, present bool, what string) {
+		has, err := bs.Has(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if has != present {
+			t.Errorf("%s: expected present=%t", what, present)
+		}
+	}
+
+	expect(garbage.Cid(), false, "unreachable block")
+	expect(kept.Cid(), true, "extra root")
+
+	for i, ts := range tss {
+		for _, b := range ts.Blocks() {
+			expect(b.Cid(), true, "block header")
+			expect(b.Messages, true, "messages")
+			expect(b.ParentMessageReceipts, true, "receipts")
+
+			// the first tipset shares its parent state with the genesis
+			if i > 0 {
+				expect(b.ParentStateRoot, i >= len(tss)-3, "state root")
+			}
+		}
+	}
+
+	// chains pinned by syncs are kept, without waiting for the sync
+	unpin := cs.Pin(last)
+	unpinNext := cs.Pin(next.TipSet.TipSet())
+	defer unpinNext()
+
+	if _, err := cs.Prune(context.TODO(), tss[4], 3, func() []cid.Cid { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, ts := range tss {
+		for _, b := range ts.Blocks() {
+			expect(b.Cid(), true, "pinned block header")
+			if i > 0 {
+				expect(b.ParentStateRoot, i >= len(tss)-3, "pinned state root")
+			}
+		}
+	}
+
+	unpin()
+	if _, err := cs.Prune(context.TODO(), tss[4], 3, func() []cid.Cid { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ts := range tss[5:] {
+		for _, b := range ts.Blocks() {
+			expect(b.Cid(), false, "unpinned block header")
+		}
+	}
+}
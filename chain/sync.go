@@ -404,6 +404,10 @@ func (syncer *Syncer) Sync(ctx context.Context, maybeHead *types.TipSet) error {
 		return nil
 	}
 
+	// blocks of the new chain aren't reachable from the heaviest tipset until
+	// it is put, don't let them be pruned before
+	defer syncer.store.Pin(maybeHead)()
+
 	if err := syncer.collectChain(ctx, maybeHead); err != nil {
 		span.AddAttributes(trace.StringAttribute("col_error", err.Error()))
 		span.SetStatus(trace.Status{
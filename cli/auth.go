@@ -6,6 +6,8 @@ import (
 
 	"gopkg.in/urfave/cli.v2"
 
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/api/apistruct"
 )
 
@@ -23,7 +25,15 @@ var authCreateAdminToken = &cli.Command{
 	Flags: []cli.Flag{
 		&cli.StringFlag{
 			Name:  "perm",
-			Usage: "permission to assign to the token, one of: read, write, sign, admin",
+			Usage: "permission to assign to the token, one of: read, write, sign, admin, worker, mpool-push",
+		},
+		&cli.StringSliceFlag{
+			Name:  "wallet",
+			Usage: "allow signing with the given wallet address only, can be repeated",
+		},
+		&cli.DurationFlag{
+			Name:  "expiry",
+			Usage: "duration after which the token expires",
 		},
 	},
 
@@ -36,24 +46,42 @@ var authCreateAdminToken = &cli.Command{
 
 		ctx := ReqContext(cctx)
 
-		if !cctx.IsSet("perm") {
-			return errors.New("--perm flag not set")
+		if !cctx.IsSet("perm") && !cctx.IsSet("wallet") {
+			return errors.New("--perm or --wallet flag not set")
 		}
 
-		perm := cctx.String("perm")
-		idx := 0
-		for i, p := range apistruct.AllPermissions {
-			if perm == p {
-				idx = i + 1
+		perms := []api.Permission{apistruct.PermRead}
+
+		if cctx.IsSet("perm") {
+			perm := cctx.String("perm")
+			idx := 0
+			for i, p := range apistruct.BasicPermissions {
+				if perm == p {
+					idx = i + 1
+				}
+			}
+
+			switch {
+			case idx > 0:
+				// slice on [:idx] so for example: 'sign' gives you [read, write, sign]
+				perms = append([]api.Permission{}, apistruct.BasicPermissions[:idx]...)
+			case perm == apistruct.PermWorker || perm == apistruct.PermMpoolPush:
+				perms = append(perms, perm)
+			default:
+				return fmt.Errorf("--perm flag has to be one of: %s", apistruct.AllPermissions)
 			}
 		}
 
-		if idx == 0 {
-			return fmt.Errorf("--perm flag has to be one of: %s", apistruct.AllPermissions)
+		for _, w := range cctx.StringSlice("wallet") {
+			addr, err := address.NewFromString(w)
+			if err != nil {
+				return fmt.Errorf("parsing wallet address: %w", err)
+			}
+
+			perms = append(perms, apistruct.WalletPerm(addr))
 		}
 
-		// slice on [:idx] so for example: 'sign' gives you [read, write, sign]
-		token, err := napi.AuthNew(ctx, apistruct.AllPermissions[:idx])
+		token, err := napi.AuthNew(ctx, perms, cctx.Duration("expiry"))
 		if err != nil {
 			return err
 		}
@@ -16,6 +16,7 @@ import (
 	"gopkg.in/urfave/cli.v2"
 
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/actors"
 	types "github.com/filecoin-project/lotus/chain/types"
 )
@@ -33,6 +34,8 @@ var chainCmd = &cli.Command{
 		chainGetCmd,
 		chainBisectCmd,
 		chainExportCmd,
+		chainBackfillMsgIndexCmd,
+		chainPruneCmd,
 		slashConsensusFault,
 	},
 }
@@ -518,6 +521,14 @@ var chainExportCmd = &cli.Command{
 		&cli.StringFlag{
 			Name: "tipset",
 		},
+		&cli.Uint64Flag{
+			Name:  "recent-stateroots",
+			Usage: "specify the number of recent state roots to include in the export",
+		},
+		&cli.BoolFlag{
+			Name:  "skip-old-msgs",
+			Usage: "only include messages of tipsets within the recent state roots range",
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		api, closer, err := GetFullNodeAPI(cctx)
@@ -531,6 +542,11 @@ var chainExportCmd = &cli.Command{
 			return fmt.Errorf("must specify filename to export chain to")
 		}
 
+		rsrs := cctx.Uint64("recent-stateroots")
+		if cctx.Bool("skip-old-msgs") && rsrs == 0 {
+			return fmt.Errorf("must pass --recent-stateroots along with --skip-old-msgs")
+		}
+
 		fi, err := os.Create(cctx.Args().First())
 		if err != nil {
 			return err
@@ -542,7 +558,7 @@ var chainExportCmd = &cli.Command{
 			return err
 		}
 
-		stream, err := api.ChainExport(ctx, ts.Key())
+		stream, err := api.ChainExport(ctx, rsrs, cctx.Bool("skip-old-msgs"), ts.Key())
 		if err != nil {
 			return err
 		}
@@ -558,6 +574,55 @@ var chainExportCmd = &cli.Command{
 	},
 }
 
+var chainBackfillMsgIndexCmd = &cli.Command{
+	Name:  "backfill-msgindex",
+	Usage: "index messages on the current chain in the message index",
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		n, err := api.ChainBackfillMsgIndex(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("indexed messages executed in %d tipsets\n", n)
+		return nil
+	},
+}
+
+var chainPruneCmd = &cli.Command{
+	Name:  "prune",
+	Usage: "delete blocks unreachable from the chain head from the chain blockstore",
+	Flags: []cli.Flag{
+		&cli.Uint64Flag{
+			Name:  "recent-stateroots",
+			Usage: "number of recent tipsets to keep state trees for",
+			Value: 2 * build.Finality,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		res, err := api.ChainPrune(ctx, cctx.Uint64("recent-stateroots"))
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("removed %d blocks, %d reachable blocks kept\n", res.Removed, res.Reachable)
+		return nil
+	},
+}
+
 var slashConsensusFault = &cli.Command{
 	Name:  "slash-consensus",
 	Usage: "Report consensus fault",
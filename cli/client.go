@@ -5,14 +5,18 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/ipfs/go-cid"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"golang.org/x/xerrors"
+	"gopkg.in/cheggaaa/pb.v1"
 	"gopkg.in/urfave/cli.v2"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
 	lapi "github.com/filecoin-project/lotus/api"
 	actors "github.com/filecoin-project/lotus/chain/actors"
 	"github.com/filecoin-project/lotus/chain/types"
@@ -24,6 +28,7 @@ var clientCmd = &cli.Command{
 	Subcommands: []*cli.Command{
 		clientImportCmd,
 		clientLocalCmd,
+		clientDropCmd,
 		clientDealCmd,
 		clientFindCmd,
 		clientRetrieveCmd,
@@ -33,8 +38,15 @@ var clientCmd = &cli.Command{
 }
 
 var clientImportCmd = &cli.Command{
-	Name:  "import",
-	Usage: "Import data",
+	Name:      "import",
+	Usage:     "Import data",
+	ArgsUsage: "[inputPath]",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "car",
+			Usage: "import from a car file instead of a regular file",
+		},
+	},
 	Action: func(cctx *cli.Context) error {
 		api, closer, err := GetFullNodeAPI(cctx)
 		if err != nil {
@@ -47,7 +59,11 @@ var clientImportCmd = &cli.Command{
 			return err
 		}
 
-		c, err := api.ClientImport(ctx, absPath)
+		ref := lapi.FileRef{
+			Path:  absPath,
+			IsCAR: cctx.Bool("car"),
+		}
+		c, err := api.ClientImport(ctx, ref)
 		if err != nil {
 			return err
 		}
@@ -71,8 +87,49 @@ var clientLocalCmd = &cli.Command{
 		if err != nil {
 			return err
 		}
+
+		w := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "Root\tPath\tSize\tStatus\tImported\tDeals\n")
 		for _, v := range list {
-			fmt.Printf("%s %s %d %s\n", v.Key, v.FilePath, v.Size, v.Status)
+			path := v.FilePath
+			switch {
+			case v.CAR:
+				path = "(car) " + path
+			case path == "":
+				path = "(local)"
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%d\n", v.Key, path, v.Size, v.Status, v.Imported.Format(time.Stamp), len(v.Deals))
+		}
+		return w.Flush()
+	},
+}
+
+var clientDropCmd = &cli.Command{
+	Name:      "drop",
+	Usage:     "Remove imported data and its blocks",
+	ArgsUsage: "[rootCid...]",
+	Action: func(cctx *cli.Context) error {
+		if !cctx.Args().Present() {
+			return xerrors.New("expected at least one root cid")
+		}
+
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := ReqContext(cctx)
+
+		for _, s := range cctx.Args().Slice() {
+			root, err := cid.Parse(s)
+			if err != nil {
+				return xerrors.Errorf("parsing root cid %s: %w", s, err)
+			}
+
+			if err := api.ClientRemoveImport(ctx, root); err != nil {
+				return xerrors.Errorf("removing import %s: %w", root, err)
+			}
 		}
 		return nil
 	},
@@ -186,6 +243,31 @@ var clientRetrieveCmd = &cli.Command{
 			Name:  "address",
 			Usage: "address to use for transactions",
 		},
+		&cli.StringFlag{
+			Name:  "path",
+			Usage: "write out only the file or directory at this path within the data (the whole data is still retrieved)",
+		},
+		&cli.StringFlag{
+			Name:  "range",
+			Usage: "write out only a byte range of a file, as offset:length (length may be omitted to read to the end; the whole data is still retrieved)",
+		},
+		&cli.BoolFlag{
+			Name:  "car",
+			Usage: "write the retrieved data as a car file",
+		},
+		&cli.BoolFlag{
+			Name:  "auto",
+			Usage: "pick the best ranked provider, failing over to the next one on error or stall",
+		},
+		&cli.StringFlag{
+			Name:  "maxPrice",
+			Usage: "maximum FIL to spend on all attempts with --auto",
+		},
+		&cli.DurationFlag{
+			Name:  "stall-timeout",
+			Usage: "with --auto, try the next provider when one makes no progress for this long",
+			Value: time.Minute,
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		if cctx.NArg() != 2 {
@@ -215,6 +297,50 @@ var clientRetrieveCmd = &cli.Command{
 			return err
 		}
 
+		var offset, length uint64
+		if cctx.IsSet("range") {
+			offset, length, err = parseRange(cctx.String("range"))
+			if err != nil {
+				return err
+			}
+		}
+
+		ref := lapi.FileRef{
+			Path:  cctx.Args().Get(1),
+			IsCAR: cctx.Bool("car"),
+		}
+
+		if cctx.Bool("auto") {
+			if !cctx.IsSet("maxPrice") {
+				return xerrors.New("--maxPrice must be set with --auto")
+			}
+			maxPrice, err := types.ParseFIL(cctx.String("maxPrice"))
+			if err != nil {
+				return xerrors.Errorf("parsing maxPrice: %w", err)
+			}
+
+			events, err := api.ClientRetrieveAuto(ctx, lapi.AutoRetrievalParams{
+				Root:   file,
+				Path:   cctx.String("path"),
+				Offset: offset,
+				Length: length,
+
+				Client:       payer,
+				MaxPrice:     types.BigInt(maxPrice),
+				StallTimeout: cctx.Duration("stall-timeout"),
+			}, ref)
+			if err != nil {
+				return xerrors.Errorf("Retrieval Failed: %w", err)
+			}
+
+			if err := showRetrievalProgress(events); err != nil {
+				return xerrors.Errorf("Retrieval Failed: %w", err)
+			}
+
+			fmt.Println("Success")
+			return nil
+		}
+
 		// Check if we already have this data locally
 
 		/*has, err := api.ClientHasLocal(ctx, file)
@@ -239,7 +365,17 @@ var clientRetrieveCmd = &cli.Command{
 			return nil
 		}
 
-		if err := api.ClientRetrieve(ctx, offers[0].Order(payer), cctx.Args().Get(1)); err != nil {
+		order := offers[0].Order(payer)
+		order.Path = cctx.String("path")
+		order.Offset = offset
+		order.Length = length
+
+		events, err := api.ClientRetrieveWithEvents(ctx, order, ref)
+		if err != nil {
+			return xerrors.Errorf("Retrieval Failed: %w", err)
+		}
+
+		if err := showRetrievalProgress(events); err != nil {
 			return xerrors.Errorf("Retrieval Failed: %w", err)
 		}
 
@@ -248,6 +384,76 @@ var clientRetrieveCmd = &cli.Command{
 	},
 }
 
+// showRetrievalProgress displays retrieval events as a progress bar until the
+// event channel is closed. Failed attempts of an automatic retrieval are
+// printed, and the retrieval continues with the next provider.
+func showRetrievalProgress(events <-chan lapi.RetrievalEvent) error {
+	bar := pb.New64(0)
+	bar.ShowPercent = true
+	bar.ShowSpeed = true
+	bar.ShowCounters = true
+	bar.Units = pb.U_BYTES
+
+	bar.Start()
+	defer bar.Finish()
+
+	var miner address.Address
+	var lastErr string
+	for evt := range events {
+		if lastErr != "" {
+			// the retrieval failed over to another provider
+			fmt.Printf("\n%s\n", lastErr)
+			lastErr = ""
+		}
+
+		if evt.Miner != address.Undef && evt.Miner != miner {
+			miner = evt.Miner
+			bar.Total = int64(evt.Size)
+			bar.Prefix(fmt.Sprintf("%s ", miner))
+		}
+
+		bar.Set64(int64(evt.BytesReceived))
+		bar.Postfix(fmt.Sprintf(" %s FIL", types.FIL(evt.FundsSpent)))
+
+		switch {
+		case evt.Err != "" && evt.Miner != address.Undef:
+			lastErr = fmt.Sprintf("%s: %s", evt.Miner, evt.Err)
+		case evt.Err != "":
+			lastErr = evt.Err
+		}
+		if evt.Event == retrievalmarket.ClientEventComplete && evt.Err == "" {
+			return nil
+		}
+	}
+
+	if lastErr != "" {
+		return xerrors.New(lastErr)
+	}
+	return xerrors.New("Retrieval Timed Out")
+}
+
+// parseRange parses a byte range in the offset:length form
+func parseRange(s string) (offset uint64, length uint64, err error) {
+	parts := strings.SplitN(s, ":", 2)
+
+	offset, err = strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, xerrors.Errorf("parsing range offset: %w", err)
+	}
+
+	if len(parts) == 2 && parts[1] != "" {
+		length, err = strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return 0, 0, xerrors.Errorf("parsing range length: %w", err)
+		}
+		if length == 0 {
+			return 0, 0, xerrors.New("range length must be positive")
+		}
+	}
+
+	return offset, length, nil
+}
+
 var clientQueryAskCmd = &cli.Command{
 	Name:  "query-ask",
 	Usage: "find a miners ask",
@@ -343,6 +549,28 @@ var clientQueryAskCmd = &cli.Command{
 var clientListDeals = &cli.Command{
 	Name:  "list-deals",
 	Usage: "List storage market deals",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "state",
+			Usage: "only list deals in these states (e.g. DealSealing)",
+		},
+		&cli.StringFlag{
+			Name:  "provider",
+			Usage: "only list deals with this provider",
+		},
+		&cli.StringFlag{
+			Name:  "after",
+			Usage: "only list deals created after this date (YYYY-MM-DD or RFC3339)",
+		},
+		&cli.StringFlag{
+			Name:  "before",
+			Usage: "only list deals created before this date (YYYY-MM-DD or RFC3339)",
+		},
+		&cli.BoolFlag{
+			Name:  "watch",
+			Usage: "print deal updates as they happen",
+		},
+	},
 	Action: func(cctx *cli.Context) error {
 		api, closer, err := GetFullNodeAPI(cctx)
 		if err != nil {
@@ -351,16 +579,83 @@ var clientListDeals = &cli.Command{
 		defer closer()
 		ctx := ReqContext(cctx)
 
-		deals, err := api.ClientListDeals(ctx)
+		if cctx.Bool("watch") {
+			updates, err := api.ClientDealNotify(ctx)
+			if err != nil {
+				return err
+			}
+
+			for d := range updates {
+				fmt.Printf("%s %s: %s", d.ProposalCid, d.Provider, lapi.DealStates[d.State])
+				if d.SectorID != 0 {
+					fmt.Printf(" (sector %d)", d.SectorID)
+				}
+				if d.Message != "" {
+					fmt.Printf(": %s", d.Message)
+				}
+				fmt.Println()
+			}
+			return nil
+		}
+
+		var filter lapi.DealFilter
+		for _, name := range cctx.StringSlice("state") {
+			st, err := parseDealState(name)
+			if err != nil {
+				return err
+			}
+			filter.States = append(filter.States, st)
+		}
+		if cctx.IsSet("provider") {
+			filter.Provider, err = address.NewFromString(cctx.String("provider"))
+			if err != nil {
+				return xerrors.Errorf("parsing provider: %w", err)
+			}
+		}
+		if cctx.IsSet("after") {
+			filter.CreatedAfter, err = parseDate(cctx.String("after"))
+			if err != nil {
+				return err
+			}
+		}
+		if cctx.IsSet("before") {
+			filter.CreatedBefore, err = parseDate(cctx.String("before"))
+			if err != nil {
+				return err
+			}
+		}
+
+		deals, err := api.ClientListDeals(ctx, filter)
 		if err != nil {
 			return err
 		}
 
 		w := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
-		fmt.Fprintf(w, "DealCid\tProvider\tState\tPieceRef\tSize\tPrice\tDuration\n")
+		fmt.Fprintf(w, "DealCid\tProvider\tState\tPieceRef\tSize\tPrice\tDuration\tSector\tActivation\tExpiration\tMessage\n")
 		for _, d := range deals {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%x\t%d\t%s\t%d\n", d.ProposalCid, d.Provider, lapi.DealStates[d.State], d.PieceRef, d.Size, d.PricePerEpoch, d.Duration)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%x\t%d\t%s\t%d\t%d\t%d\t%d\t%s\n", d.ProposalCid, d.Provider, lapi.DealStates[d.State], d.PieceRef, d.Size, d.PricePerEpoch, d.Duration, d.SectorID, d.ActivationEpoch, d.Expiration, d.Message)
 		}
 		return w.Flush()
 	},
 }
+
+func parseDealState(name string) (lapi.DealState, error) {
+	for st, n := range lapi.DealStates {
+		if strings.EqualFold(n, name) || strings.EqualFold("Deal"+name, n) {
+			return lapi.DealState(st), nil
+		}
+	}
+	return 0, xerrors.Errorf("unknown deal state %q", name)
+}
+
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, xerrors.Errorf("parsing date %q: %w", s, err)
+	}
+	return t, nil
+}
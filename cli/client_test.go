@@ -0,0 +1,34 @@
+package cli
+
+import "testing"
+
+func TestParseRange(t *testing.T) {
+	for _, tc := range []struct {
+		in             string
+		offset, length uint64
+		fail           bool
+	}{
+		{in: "10:20", offset: 10, length: 20},
+		{in: "10:", offset: 10},
+		{in: "10", offset: 10},
+		{in: "10:0", fail: true},
+		{in: ":20", fail: true},
+		{in: "a:b", fail: true},
+	} {
+		offset, length, err := parseRange(tc.in)
+		if tc.fail {
+			if err == nil {
+				t.Errorf("%q: expected an error", tc.in)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: %s", tc.in, err)
+			continue
+		}
+		if offset != tc.offset || length != tc.length {
+			t.Errorf("%q: expected %d:%d, got %d:%d", tc.in, tc.offset, tc.length, offset, length)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/ipfs/go-cid"
 	"golang.org/x/xerrors"
 	"gopkg.in/urfave/cli.v2"
 
@@ -18,6 +19,7 @@ var mpoolCmd = &cli.Command{
 		mpoolPending,
 		mpoolSub,
 		mpoolStat,
+		mpoolReplace,
 	},
 }
 
@@ -154,3 +156,49 @@ var mpoolStat = &cli.Command{
 		return nil
 	},
 }
+
+var mpoolReplace = &cli.Command{
+	Name:      "replace",
+	Usage:     "replace a pending message with a higher gas price",
+	ArgsUsage: "<message-cid>",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "gas-price",
+			Usage: "gas price for the new message, defaults to the minimum price accepted for replacement",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		api, closer, err := GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := ReqContext(cctx)
+
+		if cctx.Args().Len() != 1 {
+			return xerrors.Errorf("'replace' expects one argument, message cid")
+		}
+
+		mcid, err := cid.Parse(cctx.Args().First())
+		if err != nil {
+			return xerrors.Errorf("parsing message cid: %w", err)
+		}
+
+		gasPrice := types.NewInt(0)
+		if cctx.IsSet("gas-price") {
+			gasPrice, err = types.BigFromString(cctx.String("gas-price"))
+			if err != nil {
+				return xerrors.Errorf("parsing gas price: %w", err)
+			}
+		}
+
+		smsg, err := api.MpoolReplace(ctx, mcid, gasPrice)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("replaced %s with %s (gas price %s)\n", mcid, smsg.Cid(), smsg.Message.GasPrice)
+		return nil
+	},
+}
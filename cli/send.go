@@ -17,6 +17,14 @@ var sendCmd = &cli.Command{
 			Name:  "source",
 			Usage: "optionally specify the account to send funds from",
 		},
+		&cli.StringFlag{
+			Name:  "gas-price",
+			Usage: "specify gas price to use in AttoFIL, estimated by the node if not set",
+		},
+		&cli.Uint64Flag{
+			Name:  "gas-limit",
+			Usage: "specify gas limit, estimated by the node if not set",
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		api, closer, err := GetFullNodeAPI(cctx)
@@ -59,11 +67,20 @@ var sendCmd = &cli.Command{
 		}
 
 		msg := &types.Message{
-			From:     fromAddr,
-			To:       toAddr,
-			Value:    types.BigInt(val),
-			GasLimit: types.NewInt(1000),
-			GasPrice: types.NewInt(0),
+			From:  fromAddr,
+			To:    toAddr,
+			Value: types.BigInt(val),
+		}
+
+		if cctx.IsSet("gas-price") {
+			msg.GasPrice, err = types.BigFromString(cctx.String("gas-price"))
+			if err != nil {
+				return err
+			}
+		}
+
+		if cctx.IsSet("gas-limit") {
+			msg.GasLimit = types.NewInt(cctx.Uint64("gas-limit"))
 		}
 
 		_, err = api.MpoolPushMessage(ctx, msg)
@@ -23,6 +23,10 @@ func runSyncer(ctx context.Context, api api.FullNode, st *storage, maxBatch int)
 	if err != nil {
 		panic(err)
 	}
+	// after the api client reconnects, the current head is sent again; the
+	// mpool and block subscriptions are resumed by the client
+	var subOnce sync.Once
+
 	go func() {
 		for notif := range notifs {
 			for _, change := range notif {
@@ -36,8 +40,10 @@ func runSyncer(ctx context.Context, api api.FullNode, st *storage, maxBatch int)
 				}
 
 				if change.Type == store.HCCurrent {
-					go subMpool(ctx, api, st)
-					go subBlocks(ctx, api, st)
+					subOnce.Do(func() {
+						go subMpool(ctx, api, st)
+						go subBlocks(ctx, api, st)
+					})
 				}
 			}
 		}
@@ -247,7 +247,7 @@ func waitForSyncComplete(ctx context.Context, a api.FullNode, r int, t time.Dura
  */
 func getFullNodeAPI(ctx *cli.Context, r int, t time.Duration) (api.FullNode, jsonrpc.ClientCloser, error) {
 	for i := 0; i < r; i++ {
-		api, closer, err := lcli.GetFullNodeAPI(ctx)
+		api, closer, err := lcli.GetFullNodeAPI(ctx, jsonrpc.WithReconnect(true))
 		if err != nil && i == (r-1) {
 			return nil, nil, err
 		}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"math"
 	"os"
-	"sync"
+	"runtime"
 
+	"github.com/docker/go-units"
 	paramfetch "github.com/filecoin-project/go-paramfetch"
 	"github.com/filecoin-project/go-sectorbuilder"
 	"github.com/mitchellh/go-homedir"
@@ -19,15 +21,11 @@ import (
 	lcli "github.com/filecoin-project/lotus/cli"
 	"github.com/filecoin-project/lotus/lib/lotuslog"
 	"github.com/filecoin-project/lotus/node/repo"
+	"github.com/filecoin-project/lotus/storage/sched"
 )
 
 var log = logging.Logger("main")
 
-const (
-	workers   = 1 // TODO: Configurability
-	transfers = 1
-)
-
 func main() {
 	lotuslog.SetupLogLevels()
 
@@ -63,6 +61,20 @@ func main() {
 			&cli.BoolFlag{
 				Name: "no-commit",
 			},
+			&cli.IntFlag{
+				Name:  "cores",
+				Usage: "number of cores the miner can use for tasks on this worker",
+				Value: runtime.NumCPU(),
+			},
+			&cli.StringFlag{
+				Name:  "memory",
+				Usage: "amount of memory the miner can use for tasks on this worker (e.g. 64GiB), defaults to all memory",
+			},
+			&cli.UintFlag{
+				Name:  "transfers",
+				Usage: "number of concurrent sector data transfers",
+				Value: 1,
+			},
 		},
 
 		Commands: local,
@@ -77,7 +89,6 @@ func main() {
 }
 
 type limits struct {
-	workLimit     chan struct{}
 	transferLimit chan struct{}
 }
 
@@ -120,9 +131,15 @@ var runCmd = &cli.Command{
 			log.Warn("Shutting down..")
 		}()
 
+		if cctx.Uint("transfers") == 0 {
+			return xerrors.New("at least one transfer must be allowed")
+		}
+		if cctx.Int("cores") <= 0 {
+			return xerrors.Errorf("invalid number of cores: %d", cctx.Int("cores"))
+		}
+
 		limiter := &limits{
-			workLimit:     make(chan struct{}, workers),
-			transferLimit: make(chan struct{}, transfers),
+			transferLimit: make(chan struct{}, cctx.Uint("transfers")),
 		}
 
 		act, err := nodeApi.ActorAddress(ctx)
@@ -138,32 +155,47 @@ var runCmd = &cli.Command{
 			return xerrors.Errorf("get params: %w", err)
 		}
 
+		if err := os.MkdirAll(r, 0755); err != nil {
+			return err
+		}
+
+		var types []api.WorkerTaskType
+		if !cctx.Bool("no-precommit") {
+			types = append(types, api.TaskPreCommit)
+		}
+		if !cctx.Bool("no-commit") {
+			types = append(types, api.TaskCommit)
+		}
+
+		info, err := sched.LocalInfo([]string{r}, uint64(cctx.Uint("transfers")), types)
+		if err != nil {
+			return xerrors.Errorf("getting worker resources: %w", err)
+		}
+		info.Cores = uint64(cctx.Int("cores"))
+		if cctx.IsSet("memory") {
+			mem, err := units.RAMInBytes(cctx.String("memory"))
+			if err != nil {
+				return xerrors.Errorf("parsing memory: %w", err)
+			}
+			info.Memory = uint64(mem)
+		}
+
+		// the miner runs at most one task per core on the worker
+		threads := info.Cores
+		if threads > math.MaxUint8 {
+			threads = math.MaxUint8
+		}
+
 		sb, err := sectorbuilder.NewStandalone(&sectorbuilder.Config{
 			SectorSize:    ssize,
 			Miner:         act,
-			WorkerThreads: workers,
+			WorkerThreads: uint8(threads),
 			Paths:         sectorbuilder.SimplePath(r),
 		})
 		if err != nil {
 			return err
 		}
 
-		nQueues := workers + transfers
-		var wg sync.WaitGroup
-		wg.Add(nQueues)
-
-		for i := 0; i < nQueues; i++ {
-			go func() {
-				defer wg.Done()
-
-				if err := acceptJobs(ctx, nodeApi, sb, limiter, "http://"+storageAddr, ainfo.AuthHeader(), r, cctx.Bool("no-precommit"), cctx.Bool("no-commit")); err != nil {
-					log.Warnf("%+v", err)
-					return
-				}
-			}()
-		}
-
-		wg.Wait()
-		return nil
+		return acceptJobs(ctx, nodeApi, sb, limiter, "http://"+storageAddr, ainfo.AuthHeader(), r, info)
 	},
 }
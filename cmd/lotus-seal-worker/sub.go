@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/filecoin-project/go-sectorbuilder"
 	"golang.org/x/xerrors"
@@ -10,6 +12,10 @@ import (
 	lapi "github.com/filecoin-project/lotus/api"
 )
 
+// keptCheckInterval is how often the worker checks whether sector data kept
+// for commits is still needed
+const keptCheckInterval = 10 * time.Minute
+
 type worker struct {
 	api           lapi.StorageMiner
 	minerEndpoint string
@@ -18,9 +24,14 @@ type worker struct {
 
 	limiter *limits
 	sb      *sectorbuilder.SectorBuilder
+
+	// sectors precommitted on this worker, whose sealed sector and cache are
+	// kept until they are committed
+	keptLk sync.Mutex
+	kept   map[uint64]struct{}
 }
 
-func acceptJobs(ctx context.Context, api lapi.StorageMiner, sb *sectorbuilder.SectorBuilder, limiter *limits, endpoint string, auth http.Header, repo string, noprecommit, nocommit bool) error {
+func acceptJobs(ctx context.Context, api lapi.StorageMiner, sb *sectorbuilder.SectorBuilder, limiter *limits, endpoint string, auth http.Header, repo string, info lapi.WorkerInfo) error {
 	w := &worker{
 		api:           api,
 		minerEndpoint: endpoint,
@@ -29,46 +40,50 @@ func acceptJobs(ctx context.Context, api lapi.StorageMiner, sb *sectorbuilder.Se
 
 		limiter: limiter,
 		sb:      sb,
+
+		kept: map[uint64]struct{}{},
 	}
 
-	tasks, err := api.WorkerQueue(ctx, sectorbuilder.WorkerCfg{
-		NoPreCommit: noprecommit,
-		NoCommit:    nocommit,
-	})
+	tasks, err := api.WorkerConnect(ctx, info)
 	if err != nil {
 		return err
 	}
 
-loop:
-	for {
-		log.Infof("Waiting for new task")
+	go w.gcLoop(ctx)
 
+	log.Infof("Waiting for tasks")
+
+	for {
 		select {
-		case task := <-tasks:
-			log.Infof("New task: %d, sector %d, action: %d", task.TaskID, task.SectorID, task.Type)
+		case task, ok := <-tasks:
+			if !ok {
+				return xerrors.New("task channel closed, lost connection to the miner?")
+			}
 
-			res := w.processTask(ctx, task)
+			go func() {
+				log.Infof("New task: %d, sector %d, action: %s", task.TaskID, task.SectorID, task.Type)
 
-			log.Infof("Task %d done, err: %+v", task.TaskID, res.GoErr)
+				res := w.processTask(ctx, task)
 
-			if err := api.WorkerDone(ctx, task.TaskID, res); err != nil {
-				log.Error(err)
-			}
+				log.Infof("Task %d done, err: %+v", task.TaskID, res.GoErr)
+
+				if err := api.WorkerDone(ctx, task.TaskID, res); err != nil {
+					log.Error(err)
+				}
+			}()
 		case <-ctx.Done():
-			break loop
+			log.Warn("acceptJobs exit")
+			return nil
 		}
 	}
-
-	log.Warn("acceptJobs exit")
-	return nil
 }
 
-func (w *worker) processTask(ctx context.Context, task sectorbuilder.WorkerTask) sectorbuilder.SealRes {
+func (w *worker) processTask(ctx context.Context, task lapi.WorkerTask) sectorbuilder.SealRes {
 	switch task.Type {
-	case sectorbuilder.WorkerPreCommit:
-	case sectorbuilder.WorkerCommit:
+	case lapi.TaskPreCommit:
+	case lapi.TaskCommit:
 	default:
-		return errRes(xerrors.Errorf("unknown task type %d", task.Type))
+		return errRes(xerrors.Errorf("unknown task type %s", task.Type))
 	}
 
 	if err := w.fetchSector(task.SectorID, task.Type); err != nil {
@@ -80,11 +95,8 @@ func (w *worker) processTask(ctx context.Context, task sectorbuilder.WorkerTask)
 	var res sectorbuilder.SealRes
 
 	switch task.Type {
-	case sectorbuilder.WorkerPreCommit:
-		w.limiter.workLimit <- struct{}{}
-		rspco, err := w.sb.SealPreCommit(ctx, task.SectorID, task.SealTicket, task.Pieces)
-		<-w.limiter.workLimit
-
+	case lapi.TaskPreCommit:
+		rspco, err := w.sb.SealPreCommit(ctx, task.SectorID, task.Seal.SealTicket, task.Seal.Pieces)
 		if err != nil {
 			return errRes(xerrors.Errorf("precomitting: %w", err))
 		}
@@ -101,11 +113,13 @@ func (w *worker) processTask(ctx context.Context, task sectorbuilder.WorkerTask)
 		if err := w.remove("staging", task.SectorID); err != nil {
 			return errRes(xerrors.Errorf("cleaning up staged sector: %w", err))
 		}
-	case sectorbuilder.WorkerCommit:
-		w.limiter.workLimit <- struct{}{}
-		proof, err := w.sb.SealCommit(ctx, task.SectorID, task.SealTicket, task.SealSeed, task.Pieces, task.Rspco)
-		<-w.limiter.workLimit
 
+		// the miner prefers running the commit where the data is
+		w.keptLk.Lock()
+		w.kept[task.SectorID] = struct{}{}
+		w.keptLk.Unlock()
+	case lapi.TaskCommit:
+		proof, err := w.sb.SealCommit(ctx, task.SectorID, task.Seal.SealTicket, task.Seal.SealSeed, task.Seal.Pieces, task.Seal.Rspco)
 		if err != nil {
 			return errRes(xerrors.Errorf("comitting: %w", err))
 		}
@@ -116,7 +130,7 @@ func (w *worker) processTask(ctx context.Context, task sectorbuilder.WorkerTask)
 			return errRes(xerrors.Errorf("pushing precommited data: %w", err))
 		}
 
-		if err := w.remove("sealed", task.SectorID); err != nil {
+		if err := w.removeSealed(task.SectorID); err != nil {
 			return errRes(xerrors.Errorf("cleaning up sealed sector: %w", err))
 		}
 	}
@@ -124,6 +138,49 @@ func (w *worker) processTask(ctx context.Context, task sectorbuilder.WorkerTask)
 	return res
 }
 
+func (w *worker) gcLoop(ctx context.Context) {
+	t := time.NewTicker(keptCheckInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			w.gcKept(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// gcKept removes kept sector data which won't be used by a commit on this
+// worker anymore
+func (w *worker) gcKept(ctx context.Context) {
+	w.keptLk.Lock()
+	sectors := make([]uint64, 0, len(w.kept))
+	for id := range w.kept {
+		sectors = append(sectors, id)
+	}
+	w.keptLk.Unlock()
+
+	for _, id := range sectors {
+		si, err := w.api.SectorsStatus(ctx, id)
+		if err != nil {
+			log.Warnf("getting status of kept sector %d: %+v", id, err)
+			continue
+		}
+
+		switch si.State {
+		case lapi.PreCommitting, lapi.WaitSeed, lapi.Committing, lapi.PreCommitFailed, lapi.SealCommitFailed, lapi.CommitFailed:
+			continue
+		}
+
+		log.Infof("Removing kept data of sector %d (%s)", id, lapi.SectorStates[si.State])
+		if err := w.removeSealed(id); err != nil {
+			log.Errorf("removing kept data of sector %d: %+v", id, err)
+		}
+	}
+}
+
 func errRes(err error) sectorbuilder.SealRes {
 	return sectorbuilder.SealRes{Err: err.Error(), GoErr: err}
 }
@@ -1,22 +1,33 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"mime"
 	"net/http"
 	"os"
+	"time"
 
-	sectorbuilder "github.com/filecoin-project/go-sectorbuilder"
 	"github.com/filecoin-project/go-sectorbuilder/fs"
-	files "github.com/ipfs/go-ipfs-files"
 	"golang.org/x/xerrors"
 	"gopkg.in/cheggaaa/pb.v1"
 	"path/filepath"
 
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/lib/checksum"
 	"github.com/filecoin-project/lotus/lib/tarutil"
 )
 
+const (
+	transferAttempts   = 5
+	transferRetryDelay = 10 * time.Second
+
+	// partSuffix is appended to paths of sector data being fetched, until
+	// it's complete and verified
+	partSuffix = ".part"
+)
+
 func (w *worker) sizeForType(typ string) int64 {
 	size := int64(w.sb.SectorSize())
 	if typ == "cache" {
@@ -25,17 +36,56 @@ func (w *worker) sizeForType(typ string) int64 {
 	return size
 }
 
+// fetch downloads sector data from the miner. Interrupted downloads of files
+// are resumed, and downloaded data is verified against checksums computed
+// by the miner.
 func (w *worker) fetch(typ string, sectorID uint64) error {
 	outname := filepath.Join(w.repo, typ, w.sb.SectorName(sectorID))
+	part := outname + partSuffix
 
 	url := w.minerEndpoint + "/remote/" + typ + "/" + fmt.Sprint(sectorID)
 	log.Infof("Fetch %s %s", typ, url)
 
+	var err error
+	for attempt := 1; attempt <= transferAttempts; attempt++ {
+		if attempt > 1 {
+			log.Warnf("Fetch %s %d failed, retrying (attempt %d/%d): %+v", typ, sectorID, attempt, transferAttempts, err)
+			time.Sleep(transferRetryDelay)
+		}
+
+		if err = w.fetchPart(typ, url, part); err != nil {
+			continue
+		}
+
+		if err = w.verifyFetched(url, part); err != nil {
+			// start over, as we can't tell which part is corrupted
+			if rerr := os.RemoveAll(part); rerr != nil {
+				return xerrors.Errorf("removing corrupted %s: %w", part, rerr)
+			}
+			continue
+		}
+
+		if err := os.RemoveAll(outname); err != nil {
+			return xerrors.Errorf("removing dest: %w", err)
+		}
+		return os.Rename(part, outname)
+	}
+
+	return xerrors.Errorf("fetching %s %d: %w", typ, sectorID, err)
+}
+
+func (w *worker) fetchPart(typ string, url string, part string) error {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return xerrors.Errorf("request: %w", err)
 	}
-	req.Header = w.auth
+	req.Header = w.header()
+
+	var offset int64
+	if stat, err := os.Stat(part); err == nil && stat.Mode().IsRegular() && stat.Size() > 0 {
+		offset = stat.Size()
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -43,14 +93,28 @@ func (w *worker) fetch(typ string, sectorID uint64) error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
+	switch resp.StatusCode {
+	case http.StatusOK:
+		offset = 0
+	case http.StatusPartialContent:
+		log.Infof("Resuming fetch of %s at %d bytes", url, offset)
+	case http.StatusRequestedRangeNotSatisfiable:
+		// we have the whole file already, it only needs to be verified
+		return nil
+	default:
 		return xerrors.Errorf("non-200 code: %d", resp.StatusCode)
 	}
 
-	bar := pb.New64(w.sizeForType(typ))
+	size := w.sizeForType(typ)
+	if resp.ContentLength >= 0 {
+		size = offset + resp.ContentLength
+	}
+
+	bar := pb.New64(size)
 	bar.ShowPercent = true
 	bar.ShowSpeed = true
 	bar.Units = pb.U_BYTES
+	bar.Set64(offset)
 
 	barreader := bar.NewProxyReader(resp.Body)
 
@@ -62,21 +126,41 @@ func (w *worker) fetch(typ string, sectorID uint64) error {
 		return xerrors.Errorf("parse media type: %w", err)
 	}
 
-	if err := os.RemoveAll(outname); err != nil {
-		return xerrors.Errorf("removing dest: %w", err)
+	if offset == 0 {
+		if err := os.RemoveAll(part); err != nil {
+			return xerrors.Errorf("removing dest: %w", err)
+		}
 	}
 
 	switch mediatype {
 	case "application/x-tar":
-		return tarutil.ExtractTar(barreader, outname)
+		return tarutil.ExtractTar(barreader, part)
 	case "application/octet-stream":
-		return files.WriteTo(files.NewReaderFile(barreader), outname)
+		f, err := os.OpenFile(part, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, barreader); err != nil {
+			_ = f.Close()
+			return err
+		}
+		return f.Close()
 	default:
 		return xerrors.Errorf("unknown content type: '%s'", mediatype)
 	}
+}
+
+func (w *worker) verifyFetched(url string, part string) error {
+	var sums checksum.Sums
+	if err := w.getJSON(url+"/checksums", &sums); err != nil {
+		return xerrors.Errorf("getting checksums: %w", err)
+	}
 
+	return checksum.Verify(part, sums)
 }
 
+// push uploads sector data to the miner, along with checksums the miner
+// verifies it against. Interrupted uploads of files are resumed.
 func (w *worker) push(typ string, sectorID uint64) error {
 	w.limiter.transferLimit <- struct{}{}
 	defer func() {
@@ -91,59 +175,137 @@ func (w *worker) push(typ string, sectorID uint64) error {
 	url := w.minerEndpoint + "/remote/" + typ + "/" + fmt.Sprint(sectorID)
 	log.Infof("Push %s %s", typ, url)
 
-	stat, err := os.Stat(string(filename))
+	sums, err := checksum.Files(string(filename))
+	if err != nil {
+		return xerrors.Errorf("computing checksums: %w", err)
+	}
+
+	for attempt := 1; attempt <= transferAttempts; attempt++ {
+		if attempt > 1 {
+			log.Warnf("Push %s %d failed, retrying (attempt %d/%d): %+v", typ, sectorID, attempt, transferAttempts, err)
+			time.Sleep(transferRetryDelay)
+		}
+
+		if err = w.pushOnce(typ, url, string(filename), sums); err == nil {
+			return nil
+		}
+	}
+
+	return xerrors.Errorf("pushing %s %d: %w", typ, sectorID, err)
+}
+
+func (w *worker) pushOnce(typ string, url string, filename string, sums checksum.Sums) error {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+
+	encSums, err := sums.Encode()
 	if err != nil {
 		return err
 	}
 
+	header := w.header()
+	header.Set(checksum.Header, encSums)
+
 	var r io.Reader
+	var offset int64
+	size := w.sizeForType(typ)
 	if stat.IsDir() {
-		r, err = tarutil.TarDirectory(string(filename))
+		tr, err := tarutil.TarDirectory(filename)
+		if err != nil {
+			return xerrors.Errorf("opening push reader: %w", err)
+		}
+		defer tr.Close()
+		r = tr
+
+		header.Set("Content-Type", "application/x-tar")
 	} else {
-		r, err = os.OpenFile(string(filename), os.O_RDONLY, 0644)
-	}
-	if err != nil {
-		return xerrors.Errorf("opening push reader: %w", err)
+		if err := w.getJSON(url+"/partial", &struct{ Size *int64 }{&offset}); err != nil {
+			return xerrors.Errorf("getting pushed size: %w", err)
+		}
+		if offset > stat.Size() {
+			offset = 0
+		}
+		if offset > 0 {
+			log.Infof("Resuming push of %s at %d bytes", url, offset)
+		}
+
+		f, err := os.Open(filename)
+		if err != nil {
+			return xerrors.Errorf("opening push reader: %w", err)
+		}
+		defer f.Close()
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		r = f
+		size = stat.Size()
+
+		header.Set("Content-Type", "application/octet-stream")
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, size-1, size))
 	}
 
-	bar := pb.New64(w.sizeForType(typ))
+	bar := pb.New64(size)
 	bar.ShowPercent = true
 	bar.ShowSpeed = true
 	bar.ShowCounters = true
 	bar.Units = pb.U_BYTES
+	bar.Set64(offset)
 
 	bar.Start()
 	defer bar.Finish()
-	//todo set content size
-
-	header := w.auth
-
-	if stat.IsDir() {
-		header.Set("Content-Type", "application/x-tar")
-	} else {
-		header.Set("Content-Type", "application/octet-stream")
-	}
 
 	req, err := http.NewRequest("PUT", url, bar.NewProxyReader(r))
 	if err != nil {
 		return err
 	}
 	req.Header = header
+	if !stat.IsDir() {
+		req.ContentLength = size - offset
+	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
+
 	if resp.StatusCode != 200 {
-		return xerrors.Errorf("non-200 response: %d", resp.StatusCode)
+		var e struct{ Error string }
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return xerrors.Errorf("non-200 response: %d %s", resp.StatusCode, e.Error)
 	}
 
-	if err := resp.Body.Close(); err != nil {
+	return nil
+}
+
+// header returns a copy of the auth header, which can be modified
+func (w *worker) header() http.Header {
+	if w.auth == nil {
+		return http.Header{}
+	}
+	return w.auth.Clone()
+}
+
+func (w *worker) getJSON(url string, out interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header = w.header()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
-	// TODO: keep files around for later stages of sealing
-	return w.remove(typ, sectorID)
+	if resp.StatusCode != 200 {
+		return xerrors.Errorf("non-200 response: %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
 }
 
 func (w *worker) remove(typ string, sectorID uint64) error {
@@ -151,7 +313,28 @@ func (w *worker) remove(typ string, sectorID uint64) error {
 	return os.RemoveAll(filename)
 }
 
-func (w *worker) fetchSector(sectorID uint64, typ sectorbuilder.WorkerTaskType) error {
+// removeSealed removes the sealed sector and cache of a sector
+func (w *worker) removeSealed(sectorID uint64) error {
+	w.keptLk.Lock()
+	delete(w.kept, sectorID)
+	w.keptLk.Unlock()
+
+	if err := w.remove("sealed", sectorID); err != nil {
+		return err
+	}
+	return w.remove("cache", sectorID)
+}
+
+func (w *worker) fetchSector(sectorID uint64, typ lapi.WorkerTaskType) error {
+	w.keptLk.Lock()
+	_, kept := w.kept[sectorID]
+	w.keptLk.Unlock()
+
+	if typ == lapi.TaskCommit && kept {
+		log.Infof("Sector %d was precommitted here, using local data", sectorID)
+		return nil
+	}
+
 	w.limiter.transferLimit <- struct{}{}
 	defer func() {
 		<-w.limiter.transferLimit
@@ -159,9 +342,9 @@ func (w *worker) fetchSector(sectorID uint64, typ sectorbuilder.WorkerTaskType)
 
 	var err error
 	switch typ {
-	case sectorbuilder.WorkerPreCommit:
+	case lapi.TaskPreCommit:
 		err = w.fetch("staging", sectorID)
-	case sectorbuilder.WorkerCommit:
+	case lapi.TaskCommit:
 		err = w.fetch("sealed", sectorID)
 		if err != nil {
 			return xerrors.Errorf("fetch sealed: %w", err)
@@ -27,6 +27,9 @@ func main() {
 		infoCmd,
 		pledgeSectorCmd,
 		sectorsCmd,
+		workersCmd,
+		storageCmd,
+		provingCmd,
 	}
 	jaeger := tracing.SetupJaegerTracing("lotus")
 	defer func() {
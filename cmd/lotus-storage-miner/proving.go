@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/urfave/cli.v2"
+
+	"github.com/filecoin-project/lotus/build"
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var provingCmd = &cli.Command{
+	Name:  "proving",
+	Usage: "inspect fallback PoSt proving",
+	Subcommands: []*cli.Command{
+		provingCheckCmd,
+	},
+}
+
+var provingCheckCmd = &cli.Command{
+	Name:  "check",
+	Usage: "check that a fallback PoSt over the proving set would succeed, without submitting anything",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "generate",
+			Usage: "generate a full proof to time it, this takes as long as a real PoSt",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := lcli.ReqContext(cctx)
+
+		res, err := nodeApi.ProvingCheck(ctx, cctx.Bool("generate"))
+		if err != nil {
+			return err
+		}
+
+		epochs := func(h uint64) string {
+			if h <= res.Height {
+				return "passed"
+			}
+			return fmt.Sprintf("in %d epochs, ~%s", h-res.Height, time.Duration((h-res.Height)*build.BlockDelay)*time.Second)
+		}
+
+		fmt.Printf("Height: %d\n", res.Height)
+		fmt.Printf("Election period start: %d\n", res.ElectionPeriodStart)
+		fmt.Printf("Challenge: %d (%s)\n", res.ChallengeHeight, epochs(res.ChallengeHeight))
+		fmt.Printf("Proving starts: %d (%s)\n", res.StartHeight, epochs(res.StartHeight))
+		fmt.Printf("Deadline: %d (%s)\n", res.Deadline, epochs(res.Deadline))
+		fmt.Println()
+
+		fmt.Printf("Sectors: %d\n", res.Sectors)
+		fmt.Printf("Declared faults: %d\n", len(res.DeclaredFaults))
+		fmt.Printf("New faults: %d (checked in %s)\n", len(res.Faults), res.ScrubTime.Truncate(time.Millisecond))
+		for _, f := range res.Faults {
+			fmt.Printf("\tSector %d: %s\n", f.SectorID, f.Err)
+		}
+
+		if res.EstimatedProofTime > 0 {
+			fmt.Printf("Estimated proof time: %s\n", res.EstimatedProofTime.Truncate(time.Second))
+		} else {
+			fmt.Println("Estimated proof time: unknown, no proof generated yet")
+		}
+
+		switch {
+		case res.ProofErr != "":
+			fmt.Printf("Proof generation FAILED: %s\n", res.ProofErr)
+		case res.ProofTime > 0:
+			fmt.Printf("Proof generated in %s\n", res.ProofTime.Truncate(time.Millisecond))
+		}
+
+		return nil
+	},
+}
@@ -48,7 +48,7 @@ var runCmd = &cli.Command{
 			os.Setenv("BELLMAN_NO_GPU", "true")
 		}
 
-		nodeApi, ncloser, err := lcli.GetFullNodeAPI(cctx)
+		nodeApi, ncloser, err := lcli.GetFullNodeAPI(cctx, jsonrpc.WithReconnect(true))
 		if err != nil {
 			return err
 		}
@@ -39,6 +39,9 @@ var sectorsCmd = &cli.Command{
 		sectorsListCmd,
 		sectorsRefsCmd,
 		sectorsUpdateCmd,
+		sectorsRecoverCmd,
+		sectorsRemoveCmd,
+		sectorsWatchCmd,
 	},
 }
 
@@ -83,6 +86,10 @@ var sectorsStatusCmd = &cli.Command{
 		fmt.Printf("SeedH:\t\t%d\n", status.Seed.BlockHeight)
 		fmt.Printf("Proof:\t\t%x\n", status.Proof)
 		fmt.Printf("Deals:\t\t%v\n", status.Deals)
+		fmt.Printf("Pieces:\n")
+		for i, p := range status.Pieces {
+			fmt.Printf("\t%d.\tDeal: %d\tSize: %d\tCommP: %x\n", i, p.DealID, p.Size, p.CommP)
+		}
 		fmt.Printf("Retries:\t\t%d\n", status.Retries)
 		if status.LastErr != "" {
 			fmt.Printf("Last Error:\t\t%s\n", status.LastErr)
@@ -244,6 +251,105 @@ var sectorsUpdateCmd = &cli.Command{
 	},
 }
 
+var sectorsRecoverCmd = &cli.Command{
+	Name:      "recover",
+	Usage:     "check that a faulty sector is readable again, and declare it recovered",
+	ArgsUsage: "[sectorId]",
+	Action: func(cctx *cli.Context) error {
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := lcli.ReqContext(cctx)
+		if !cctx.Args().Present() {
+			return xerrors.Errorf("must pass sector ID")
+		}
+
+		id, err := strconv.ParseUint(cctx.Args().First(), 10, 64)
+		if err != nil {
+			return xerrors.Errorf("could not parse sector ID: %w", err)
+		}
+
+		if err := nodeApi.SectorsRecover(ctx, id); err != nil {
+			return err
+		}
+
+		fmt.Printf("Declaring sector %d recovered, check progress with 'sectors status'\n", id)
+		return nil
+	},
+}
+
+var sectorsRemoveCmd = &cli.Command{
+	Name:      "remove",
+	Usage:     "delete the data and the state of a failed sector",
+	ArgsUsage: "[sectorId]",
+	Description: `Only failed sectors which aren't committed on chain can be removed. With
+   --terminate, committed sectors are terminated on chain first. This takes away
+   their power. Sectors with client deals can't be terminated until the deals
+   expire.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "terminate",
+			Usage: "terminate the sector on chain if it's committed",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := lcli.ReqContext(cctx)
+		if !cctx.Args().Present() {
+			return xerrors.Errorf("must pass sector ID")
+		}
+
+		id, err := strconv.ParseUint(cctx.Args().First(), 10, 64)
+		if err != nil {
+			return xerrors.Errorf("could not parse sector ID: %w", err)
+		}
+
+		if cctx.Bool("terminate") {
+			fmt.Printf("Terminating sector %d, waiting for the message to land on chain\n", id)
+		}
+
+		if err := nodeApi.SectorRemove(ctx, id, cctx.Bool("terminate")); err != nil {
+			return err
+		}
+
+		fmt.Printf("Removed sector %d\n", id)
+		return nil
+	},
+}
+
+var sectorsWatchCmd = &cli.Command{
+	Name:  "watch",
+	Usage: "print sector state transitions as they happen",
+	Action: func(cctx *cli.Context) error {
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := lcli.ReqContext(cctx)
+
+		updates, err := nodeApi.SectorsNotify(ctx)
+		if err != nil {
+			return err
+		}
+
+		for u := range updates {
+			fmt.Printf("%s sector %d: %s -> %s (%s)\n", u.Timestamp.Format(time.Stamp), u.SectorID, api.SectorStates[u.From], api.SectorStates[u.To], u.Event)
+			if u.Error != "" {
+				fmt.Printf("\t%s\n", u.Error)
+			}
+		}
+
+		return nil
+	},
+}
+
 func yesno(b bool) string {
 	if b {
 		return "YES"
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/mitchellh/go-homedir"
+	"golang.org/x/xerrors"
+	"gopkg.in/urfave/cli.v2"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var storageCmd = &cli.Command{
+	Name:  "storage",
+	Usage: "manage sector storage paths",
+	Subcommands: []*cli.Command{
+		storageAttachCmd,
+		storageDetachCmd,
+		storageListCmd,
+		storageMoveCmd,
+	},
+}
+
+var storageAttachCmd = &cli.Command{
+	Name:      "attach",
+	Usage:     "attach a storage path",
+	ArgsUsage: "[path]",
+	Description: `Sectors can be moved to an attached path right away. New sectors are
+   sealed into it after the miner is restarted.`,
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "weight",
+			Usage: "weight of the path when picking where new sectors are stored",
+			Value: 1,
+		},
+		&cli.BoolFlag{
+			Name:  "cache",
+			Usage: "use the path for sealing data, not long term storage",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := lcli.ReqContext(cctx)
+
+		path, err := storagePathArg(cctx, 0)
+		if err != nil {
+			return err
+		}
+
+		return nodeApi.StorageAttach(ctx, path, cctx.Int("weight"), cctx.Bool("cache"))
+	},
+}
+
+var storageDetachCmd = &cli.Command{
+	Name:      "detach",
+	Usage:     "detach a storage path holding no sector data",
+	ArgsUsage: "[path]",
+	Description: `Sectors stored in the path have to be moved to other paths first. Until
+   the miner is restarted, new sectors may still be sealed into the path, which
+   keeps it attached.`,
+	Action: func(cctx *cli.Context) error {
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := lcli.ReqContext(cctx)
+
+		path, err := storagePathArg(cctx, 0)
+		if err != nil {
+			return err
+		}
+
+		return nodeApi.StorageDetach(ctx, path)
+	},
+}
+
+var storageListCmd = &cli.Command{
+	Name:  "list",
+	Usage: "list storage paths and their usage",
+	Action: func(cctx *cli.Context) error {
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := lcli.ReqContext(cctx)
+
+		paths, err := nodeApi.StorageList(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range paths {
+			var flags string
+			if p.Cache {
+				flags += ", cache"
+			}
+			if !p.Active {
+				flags += ", active after restart"
+			}
+
+			fmt.Printf("%s (weight %d%s)\n", p.Path, p.Weight, flags)
+			fmt.Printf("\tDisk: %s / %s available\n", types.NewInt(p.Available).SizeStr(), types.NewInt(p.Capacity).SizeStr())
+			fmt.Printf("\tSector data: %s\n", types.NewInt(p.Used).SizeStr())
+			fmt.Printf("\tSealed sectors: %d\n", len(p.Sectors))
+		}
+
+		return nil
+	},
+}
+
+var storageMoveCmd = &cli.Command{
+	Name:      "move",
+	Usage:     "move the sealed data of a proving sector to another storage path",
+	ArgsUsage: "[sectorId path]",
+	Action: func(cctx *cli.Context) error {
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := lcli.ReqContext(cctx)
+
+		if cctx.Args().Len() != 2 {
+			return xerrors.Errorf("expected 2 arguments: sector ID and path")
+		}
+
+		id, err := strconv.ParseUint(cctx.Args().Get(0), 10, 64)
+		if err != nil {
+			return xerrors.Errorf("could not parse sector ID: %w", err)
+		}
+
+		path, err := storagePathArg(cctx, 1)
+		if err != nil {
+			return err
+		}
+
+		return nodeApi.StorageMove(ctx, id, path)
+	},
+}
+
+// storagePathArg returns the absolute path in the given argument, paths are
+// resolved on the miner
+func storagePathArg(cctx *cli.Context, i int) (string, error) {
+	if cctx.Args().Len() <= i {
+		return "", xerrors.Errorf("expected a storage path argument")
+	}
+
+	path, err := homedir.Expand(cctx.Args().Get(i))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(path)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/urfave/cli.v2"
+
+	"github.com/filecoin-project/lotus/chain/types"
+	lcli "github.com/filecoin-project/lotus/cli"
+)
+
+var workersCmd = &cli.Command{
+	Name:  "workers",
+	Usage: "interact with sealing workers",
+	Subcommands: []*cli.Command{
+		workersListCmd,
+	},
+}
+
+var workersListCmd = &cli.Command{
+	Name:  "list",
+	Usage: "list workers, their resources and running tasks",
+	Action: func(cctx *cli.Context) error {
+		nodeApi, closer, err := lcli.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := lcli.ReqContext(cctx)
+
+		workers, err := nodeApi.WorkerList(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, w := range workers {
+			kind := "remote"
+			if w.Local {
+				kind = "local"
+			}
+
+			tasks := make([]string, len(w.Info.TaskTypes))
+			for i, tt := range w.Info.TaskTypes {
+				tasks[i] = string(tt)
+			}
+
+			fmt.Printf("Worker %d, host %s (%s)\n", w.ID, w.Info.Hostname, kind)
+			fmt.Printf("\tTask types: %s\n", strings.Join(tasks, ", "))
+			fmt.Printf("\tCPU: %d / %d core(s) in use\n", w.CoresUsed, w.Info.Cores)
+			if w.Info.Memory > 0 {
+				fmt.Printf("\tRAM: %s / %s in use\n", types.NewInt(w.MemoryUsed).SizeStr(), types.NewInt(w.Info.Memory).SizeStr())
+			}
+			if w.Info.Transfers > 0 {
+				fmt.Printf("\tTransfers: %d / %d\n", w.TransfersUsed, w.Info.Transfers)
+			}
+			for _, p := range w.Info.Paths {
+				fmt.Printf("\tPath %s: %s / %s available\n", p.Path, types.NewInt(p.Available).SizeStr(), types.NewInt(p.Capacity).SizeStr())
+			}
+
+			for _, t := range w.Tasks {
+				var progress string
+				switch {
+				case t.Size > 0:
+					progress = fmt.Sprintf(", %s / %s transferred", types.NewInt(t.Progress).SizeStr(), types.NewInt(t.Size).SizeStr())
+				case t.Progress > 0:
+					progress = fmt.Sprintf(", %s transferred", types.NewInt(t.Progress).SizeStr())
+				}
+
+				fmt.Printf("\tTask %d: %s sector %d, running for %s%s\n", t.TaskID, t.Type, t.SectorID, time.Since(t.Started).Truncate(time.Second), progress)
+			}
+		}
+
+		return nil
+	},
+}
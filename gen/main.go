@@ -9,6 +9,7 @@ import (
 	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/chain/actors"
 	"github.com/filecoin-project/lotus/chain/blocksync"
+	"github.com/filecoin-project/lotus/chain/msgindex"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/lib/statemachine"
 	"github.com/filecoin-project/lotus/paych"
@@ -68,11 +69,19 @@ func main() {
 		os.Exit(1)
 	}
 
+	err = gen.WriteTupleEncodersToFile("./chain/msgindex/cbor_gen.go", "msgindex",
+		msgindex.MsgInfo{},
+	)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
 	err = gen.WriteTupleEncodersToFile("./chain/actors/cbor_gen.go", "actors",
 		actors.InitActorState{},
 		actors.ExecParams{},
 		actors.AccountActorState{},
-		actors.StorageMinerActorState{},
+		actors.FaultDeclaration{},
 		actors.StorageMinerConstructorParams{},
 		actors.SectorPreCommitInfo{},
 		actors.PreCommittedSector{},
@@ -81,6 +90,9 @@ func main() {
 		actors.PaymentVerifyParams{},
 		actors.UpdatePeerIDParams{},
 		actors.DeclareFaultsParams{},
+		actors.DeclareRecoveriesParams{},
+		actors.TerminateSectorsParams{},
+		actors.SectorDeals{},
 		actors.MultiSigActorState{},
 		actors.MultiSigConstructorParams{},
 		actors.MultiSigProposeParams{},
@@ -113,6 +125,7 @@ func main() {
 		actors.ProcessStorageDealsPaymentParams{},
 		actors.OnChainDeal{},
 		actors.ComputeDataCommitmentParams{},
+		actors.GetLastExpirationFromDealIDsParams{},
 		actors.SectorProveCommitInfo{},
 		actors.CheckMinerParams{},
 		actors.CronActorState{},
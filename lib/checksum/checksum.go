@@ -0,0 +1,107 @@
+package checksum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/xerrors"
+)
+
+// Header carries JSON encoded checksums of transferred sector files
+const Header = "X-Checksums"
+
+// Sums maps paths of regular files, relative to the checksummed path, to
+// hex encoded sha256 sums. A file checksummed on its own is keyed by ".".
+type Sums map[string]string
+
+// Files computes checksums of the file at path, or of all regular files in
+// the directory at path
+func Files(path string) (Sums, error) {
+	sums := Sums{}
+
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+
+		sum, err := fileSum(p)
+		if err != nil {
+			return xerrors.Errorf("checksumming %s: %w", p, err)
+		}
+		sums[filepath.ToSlash(rel)] = sum
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sums, nil
+}
+
+func fileSum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Verify checks that the files at path have the expected checksums, and
+// that there are no other files
+func Verify(path string, expected Sums) error {
+	sums, err := Files(path)
+	if err != nil {
+		return err
+	}
+
+	for name, sum := range expected {
+		got, ok := sums[name]
+		if !ok {
+			return xerrors.Errorf("file %s missing", name)
+		}
+		if got != sum {
+			return xerrors.Errorf("checksum mismatch for %s: expected %s, got %s", name, sum, got)
+		}
+	}
+	for name := range sums {
+		if _, ok := expected[name]; !ok {
+			return xerrors.Errorf("unexpected file %s", name)
+		}
+	}
+
+	return nil
+}
+
+func (s Sums) Encode() (string, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func Decode(v string) (Sums, error) {
+	var sums Sums
+	if err := json.Unmarshal([]byte(v), &sums); err != nil {
+		return nil, xerrors.Errorf("decoding checksums: %w", err)
+	}
+	return sums, nil
+}
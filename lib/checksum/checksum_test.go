@@ -0,0 +1,71 @@
+package checksum
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, data string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("a", "aaa")
+	write("b", "bbb")
+
+	sums, err := Files(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sums) != 2 {
+		t.Fatalf("expected 2 sums, got %d", len(sums))
+	}
+
+	enc, err := sums.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := Decode(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(dir, dec); err != nil {
+		t.Fatal(err)
+	}
+
+	single, err := Files(filepath.Join(dir, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if single["."] != sums["a"] {
+		t.Fatalf("file checksum %s doesn't match %s", single["."], sums["a"])
+	}
+
+	write("b", "bbc")
+	if err := Verify(dir, sums); err == nil {
+		t.Fatal("expected checksum mismatch")
+	}
+
+	write("b", "bbb")
+	write("c", "ccc")
+	if err := Verify(dir, sums); err == nil {
+		t.Fatal("expected unexpected file error")
+	}
+
+	if err := os.Remove(filepath.Join(dir, "a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(dir, Sums{"a": sums["a"]}); err == nil {
+		t.Fatal("expected missing file error")
+	}
+}
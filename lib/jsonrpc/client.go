@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 	logging "github.com/ipfs/go-log/v2"
@@ -64,28 +65,55 @@ type ClientCloser func()
 // handler must be pointer to a struct with function fields
 // Returned value closes the client connection
 // TODO: Example
-func NewClient(addr string, namespace string, handler interface{}, requestHeader http.Header) (ClientCloser, error) {
-	return NewMergeClient(addr, namespace, []interface{}{handler}, requestHeader)
+func NewClient(addr string, namespace string, handler interface{}, requestHeader http.Header, opts ...Option) (ClientCloser, error) {
+	return NewMergeClient(addr, namespace, []interface{}{handler}, requestHeader, opts...)
 }
 
 type client struct {
 	namespace string
+	cfg       Config
+
+	addr          string
+	requestHeader http.Header
 
 	requests chan clientRequest
 	exiting  <-chan struct{}
 	idCtr    int64
+
+	// subs are the active channel subscriptions, resumed after reconnecting
+	subs   map[*clientSub]struct{}
+	subsLk sync.Mutex
+}
+
+type clientSub struct {
+	ctx    context.Context
+	req    request
+	chCtor makeChanSink
+
+	closed bool // guarded by client.subsLk
 }
 
 // NewMergeClient is like NewClient, but allows to specify multiple structs
 // to be filled in the same namespace, using one connection
-func NewMergeClient(addr string, namespace string, outs []interface{}, requestHeader http.Header) (ClientCloser, error) {
+func NewMergeClient(addr string, namespace string, outs []interface{}, requestHeader http.Header, opts ...Option) (ClientCloser, error) {
+	cfg := defaultConfig()
+	for _, o := range opts {
+		o(&cfg)
+	}
+
 	conn, _, err := websocket.DefaultDialer.Dial(addr, requestHeader)
 	if err != nil {
 		return nil, err
 	}
 
-	c := client{
+	c := &client{
 		namespace: namespace,
+		cfg:       cfg,
+
+		addr:          addr,
+		requestHeader: requestHeader,
+
+		subs: map[*clientSub]struct{}{},
 	}
 
 	stop := make(chan struct{})
@@ -93,14 +121,7 @@ func NewMergeClient(addr string, namespace string, outs []interface{}, requestHe
 	c.requests = make(chan clientRequest)
 	c.exiting = exiting
 
-	handlers := map[string]rpcHandler{}
-	go (&wsConn{
-		conn:     conn,
-		handler:  handlers,
-		requests: c.requests,
-		stop:     stop,
-		exiting:  exiting,
-	}).handleWsConn(context.TODO())
+	go c.run(conn, stop, exiting)
 
 	for _, handler := range outs {
 		htyp := reflect.TypeOf(handler)
@@ -130,80 +151,251 @@ func NewMergeClient(addr string, namespace string, outs []interface{}, requestHe
 	}, nil
 }
 
-func (c *client) makeOutChan(ctx context.Context, ftyp reflect.Type, valOut int) (func() reflect.Value, makeChanSink) {
+// run handles the websocket connection until the client is closed. When
+// reconnecting is enabled, the connection is redialed when it's lost.
+func (c *client) run(conn *websocket.Conn, stop <-chan struct{}, exiting chan struct{}) {
+	defer close(exiting)
+
+	for {
+		(&wsConn{
+			conn:     conn,
+			handler:  map[string]rpcHandler{},
+			requests: c.requests,
+			stop:     stop,
+			exiting:  make(chan struct{}),
+		}).handleWsConn(context.TODO())
+
+		select {
+		case <-stop:
+			return // client closed
+		default:
+		}
+
+		if !c.cfg.reconnect {
+			// the connection is lost for good, let consumers know their
+			// subscriptions ended
+			c.closeSubs()
+			return
+		}
+
+		conn = c.redial(stop)
+		if conn == nil {
+			return // client closed
+		}
+
+		log.Infow("rpc client reconnected", "addr", c.addr)
+
+		go c.resubscribe()
+		go c.cfg.onReconnect()
+	}
+}
+
+// redial connects to the server with exponential backoff. It returns nil if
+// the client is closed in the meantime.
+func (c *client) redial(stop <-chan struct{}) *websocket.Conn {
+	backoff := c.cfg.reconnectBackoffMin
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(backoff):
+		}
+
+		conn, _, err := websocket.DefaultDialer.Dial(c.addr, c.requestHeader)
+		if err == nil {
+			return conn
+		}
+
+		log.Warnw("rpc client reconnect failed", "addr", c.addr, "error", err, "backoff", backoff)
+
+		backoff *= 2
+		if backoff > c.cfg.reconnectBackoffMax {
+			backoff = c.cfg.reconnectBackoffMax
+		}
+	}
+}
+
+func (c *client) addSub(sub *clientSub) {
+	c.subsLk.Lock()
+	defer c.subsLk.Unlock()
+
+	if sub.closed {
+		return // remote closed the channel before the call returned
+	}
+	c.subs[sub] = struct{}{}
+}
+
+func (c *client) removeSub(sub *clientSub) {
+	c.subsLk.Lock()
+	defer c.subsLk.Unlock()
+
+	sub.closed = true
+	delete(c.subs, sub)
+}
+
+func (c *client) activeSubs() []*clientSub {
+	c.subsLk.Lock()
+	defer c.subsLk.Unlock()
+
+	out := make([]*clientSub, 0, len(c.subs))
+	for sub := range c.subs {
+		out = append(out, sub)
+	}
+	return out
+}
+
+// resubscribe repeats the calls of active channel subscriptions on a new
+// connection. Values are delivered to the channels returned by the original
+// calls.
+func (c *client) resubscribe() {
+	for _, sub := range c.activeSubs() {
+		if sub.ctx.Err() != nil {
+			closeSub(sub)
+			continue
+		}
+
+		req := sub.req
+		id := atomic.AddInt64(&c.idCtr, 1)
+		req.ID = &id
+
+		resp, err := c.sendRequest(sub.ctx, req, sub.chCtor)
+		if err != nil {
+			log.Warnw("resubscribing failed", "method", req.Method, "error", err)
+			closeSub(sub)
+			continue
+		}
+
+		if resp.Error != nil {
+			if resp.Error.Code == eTempWSError {
+				continue // lost the connection again, retried after reconnecting
+			}
+
+			log.Warnw("resubscribing failed", "method", req.Method, "error", resp.Error)
+			closeSub(sub)
+		}
+	}
+}
+
+func (c *client) closeSubs() {
+	for _, sub := range c.activeSubs() {
+		closeSub(sub)
+	}
+}
+
+func closeSub(sub *clientSub) {
+	_, sink := sub.chCtor()
+	sink(nil, false)
+}
+
+func (c *client) makeOutChan(ctx context.Context, ftyp reflect.Type, valOut int, onClose func()) (func() reflect.Value, makeChanSink) {
 	retVal := reflect.Zero(ftyp.Out(valOut))
 
+	// the channel is only created once, subscriptions resumed after
+	// reconnecting keep sending to it
+	var once sync.Once
+	var sink func([]byte, bool)
+
 	chCtor := func() (context.Context, func([]byte, bool)) {
-		// unpack chan type to make sure it's reflect.BothDir
-		ctyp := reflect.ChanOf(reflect.BothDir, ftyp.Out(valOut).Elem())
-		ch := reflect.MakeChan(ctyp, 0) // todo: buffer?
-		chCtx, chCancel := context.WithCancel(ctx)
-		retVal = ch.Convert(ftyp.Out(valOut))
-
-		buf := (&list.List{}).Init()
-		var bufLk sync.Mutex
-
-		return ctx, func(result []byte, ok bool) {
-			if !ok {
-				chCancel()
-				// remote channel closed, close ours too
-				ch.Close()
-				return
-			}
+		once.Do(func() {
+			// unpack chan type to make sure it's reflect.BothDir
+			ctyp := reflect.ChanOf(reflect.BothDir, ftyp.Out(valOut).Elem())
+			ch := reflect.MakeChan(ctyp, 0) // todo: buffer?
+			chCtx, chCancel := context.WithCancel(ctx)
+			retVal = ch.Convert(ftyp.Out(valOut))
+
+			buf := (&list.List{}).Init()
+			var bufLk sync.Mutex
+			// closing is set when the remote channel is closed, the delivery
+			// goroutine closes ours once the buffer is drained
+			var closing bool
+
+			sink = func(result []byte, ok bool) {
+				if !ok {
+					bufLk.Lock()
+					if closing {
+						bufLk.Unlock()
+						return
+					}
+					closing = true
+
+					// remote channel closed, close ours too. If values are
+					// still buffered, the delivery goroutine is running and
+					// closes it after sending them.
+					if buf.Len() == 0 {
+						chCancel()
+						ch.Close()
+					}
+					bufLk.Unlock()
 
-			val := reflect.New(ftyp.Out(valOut).Elem())
-			if err := json.Unmarshal(result, val.Interface()); err != nil {
-				log.Errorf("error unmarshaling chan response: %s", err)
-				return
-			}
+					onClose()
+					return
+				}
 
-			bufLk.Lock()
-			if ctx.Err() != nil {
-				log.Errorf("got rpc message with cancelled context: %s", ctx.Err())
-				bufLk.Unlock()
-				return
-			}
+				val := reflect.New(ftyp.Out(valOut).Elem())
+				if err := json.Unmarshal(result, val.Interface()); err != nil {
+					log.Errorf("error unmarshaling chan response: %s", err)
+					return
+				}
 
-			buf.PushBack(val)
+				bufLk.Lock()
+				if closing {
+					bufLk.Unlock()
+					return
+				}
+				if ctx.Err() != nil {
+					log.Errorf("got rpc message with cancelled context: %s", ctx.Err())
+					bufLk.Unlock()
+					return
+				}
 
-			if buf.Len() > 1 {
-				log.Warnw("rpc output message buffer", "n", buf.Len())
-				bufLk.Unlock()
-				return
-			}
+				buf.PushBack(val)
 
-			go func() {
-				for buf.Len() > 0 {
-					front := buf.Front()
+				if buf.Len() > 1 {
+					log.Warnw("rpc output message buffer", "n", buf.Len())
 					bufLk.Unlock()
+					return
+				}
 
-					cases := []reflect.SelectCase{
-						{
-							Dir:  reflect.SelectRecv,
-							Chan: reflect.ValueOf(chCtx.Done()),
-						},
-						{
-							Dir:  reflect.SelectSend,
-							Chan: ch,
-							Send: front.Value.(reflect.Value).Elem(),
-						},
+				go func() {
+					for buf.Len() > 0 {
+						front := buf.Front()
+						bufLk.Unlock()
+
+						cases := []reflect.SelectCase{
+							{
+								Dir:  reflect.SelectRecv,
+								Chan: reflect.ValueOf(chCtx.Done()),
+							},
+							{
+								Dir:  reflect.SelectSend,
+								Chan: ch,
+								Send: front.Value.(reflect.Value).Elem(),
+							},
+						}
+
+						chosen, _, _ := reflect.Select(cases)
+						bufLk.Lock()
+
+						switch chosen {
+						case 0:
+							buf.Init()
+						case 1:
+							buf.Remove(front)
+						}
 					}
 
-					chosen, _, _ := reflect.Select(cases)
-					bufLk.Lock()
-
-					switch chosen {
-					case 0:
-						buf.Init()
-					case 1:
-						buf.Remove(front)
+					if closing {
+						chCancel()
+						ch.Close()
 					}
-				}
+					bufLk.Unlock()
+				}()
 
-				bufLk.Unlock()
-			}()
+			}
+		})
 
-		}
+		return ctx, sink
 	}
 
 	return func() reflect.Value { return retVal }, chCtor
@@ -217,11 +409,6 @@ func (c *client) sendRequest(ctx context.Context, req request, chCtor makeChanSi
 
 		retCh: chCtor,
 	}
-	select {
-	case c.requests <- creq:
-	case <-c.exiting:
-		return clientResponse{}, fmt.Errorf("websocket routine exiting")
-	}
 
 	var ctxDone <-chan struct{}
 	var resp clientResponse
@@ -230,6 +417,15 @@ func (c *client) sendRequest(ctx context.Context, req request, chCtor makeChanSi
 		ctxDone = ctx.Done()
 	}
 
+	// requests block here while reconnecting
+	select {
+	case c.requests <- creq:
+	case <-ctxDone:
+		return clientResponse{}, ctx.Err()
+	case <-c.exiting:
+		return clientResponse{}, fmt.Errorf("websocket routine exiting")
+	}
+
 	// wait for response, handle context cancellation
 loop:
 	for {
@@ -269,6 +465,10 @@ type rpcFunc struct {
 
 	hasCtx int
 	retCh  bool
+
+	// retry is set for calls which are repeated when the connection is lost
+	// before they return
+	retry bool
 }
 
 func (fn *rpcFunc) processResponse(resp clientResponse, rval reflect.Value) []reflect.Value {
@@ -323,8 +523,13 @@ func (fn *rpcFunc) handleRpcCall(args []reflect.Value) (results []reflect.Value)
 	// if the function returns a channel, we need to provide a sink for the
 	// messages
 	var chCtor makeChanSink
+	var sub *clientSub
 	if fn.retCh {
-		retVal, chCtor = fn.client.makeOutChan(ctx, fn.ftyp, fn.valOut)
+		sub = &clientSub{ctx: ctx}
+		retVal, chCtor = fn.client.makeOutChan(ctx, fn.ftyp, fn.valOut, func() {
+			fn.client.removeSub(sub)
+		})
+		sub.chCtor = chCtor
 	}
 
 	req := request{
@@ -344,15 +549,30 @@ func (fn *rpcFunc) handleRpcCall(args []reflect.Value) (results []reflect.Value)
 		}
 	}
 
-	resp, err := fn.client.sendRequest(ctx, req, chCtor)
-	if err != nil {
-		return fn.processError(fmt.Errorf("sendRequest failed: %w", err))
+	var resp clientResponse
+	for {
+		var err error
+		resp, err = fn.client.sendRequest(ctx, req, chCtor)
+		if err != nil {
+			return fn.processError(fmt.Errorf("sendRequest failed: %w", err))
+		}
+
+		if !fn.retry || resp.Error == nil || resp.Error.Code != eTempWSError {
+			break
+		}
+
+		log.Warnw("connection lost, retrying rpc call", "method", req.Method)
 	}
 
 	if resp.ID != *req.ID {
 		return fn.processError(xerrors.New("request and response id didn't match"))
 	}
 
+	if sub != nil && resp.Error == nil {
+		sub.req = req
+		fn.client.addSub(sub)
+	}
+
 	if fn.valOut != -1 && !fn.retCh {
 		val := reflect.New(fn.ftyp.Out(fn.valOut))
 
@@ -387,6 +607,7 @@ func (c *client) makeRpcFunc(f reflect.StructField) (reflect.Value, error) {
 		fun.hasCtx = 1
 	}
 	fun.retCh = fun.valOut != -1 && ftyp.Out(fun.valOut).Kind() == reflect.Chan
+	fun.retry = c.cfg.reconnect && (fun.retCh || c.cfg.retryable(f))
 
 	return reflect.MakeFunc(ftyp, fun.handleRpcCall), nil
 }
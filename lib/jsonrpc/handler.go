@@ -1,6 +1,7 @@
 package jsonrpc
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/base64"
@@ -104,18 +105,89 @@ func (h handlers) register(namespace string, r interface{}) {
 type rpcErrFunc func(w func(func(io.Writer)), req *request, code int, err error)
 type chanOut func(reflect.Value, int64) error
 
-func (h handlers) handleReader(ctx context.Context, r io.Reader, w io.Writer, rpcError rpcErrFunc) {
+func (h handlers) handleReader(ctx context.Context, r io.Reader, w io.Writer, rpcError rpcErrFunc, chOut chanOut) {
 	wf := func(cb func(io.Writer)) {
 		cb(w)
 	}
 
+	br := bufio.NewReader(r)
+	if isBatch(br) {
+		h.handleBatch(ctx, br, w, rpcError)
+		return
+	}
+
 	var req request
-	if err := json.NewDecoder(r).Decode(&req); err != nil {
+	if err := json.NewDecoder(br).Decode(&req); err != nil {
 		rpcError(wf, &req, rpcParseError, xerrors.Errorf("unmarshaling request: %w", err))
 		return
 	}
 
-	h.handle(ctx, req, wf, rpcError, func(bool) {}, nil)
+	h.handle(ctx, req, wf, rpcError, func(bool) {}, chOut)
+}
+
+// isBatch checks whether the request body starts with a JSON array
+func isBatch(br *bufio.Reader) bool {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return false
+		}
+
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+
+		_ = br.UnreadByte()
+		return b == '['
+	}
+}
+
+// handleBatch handles a JSON-RPC 2.0 batch request. Requests are processed in
+// order, and responses to all non-notification requests are written as a
+// single array. Channel methods aren't supported in batches.
+func (h handlers) handleBatch(ctx context.Context, r io.Reader, w io.Writer, rpcError rpcErrFunc) {
+	wf := func(cb func(io.Writer)) {
+		cb(w)
+	}
+
+	var reqs []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&reqs); err != nil {
+		rpcError(wf, &request{}, rpcParseError, xerrors.Errorf("unmarshaling batch request: %w", err))
+		return
+	}
+
+	if len(reqs) == 0 {
+		rpcError(wf, &request{}, rpcInvalidRequest, xerrors.New("empty batch request"))
+		return
+	}
+
+	resps := make([]json.RawMessage, 0, len(reqs))
+	for _, raw := range reqs {
+		buf := new(bytes.Buffer)
+		bwf := func(cb func(io.Writer)) {
+			cb(buf)
+		}
+
+		var req request
+		if err := json.Unmarshal(raw, &req); err != nil {
+			rpcError(bwf, &req, rpcInvalidRequest, xerrors.Errorf("unmarshaling request: %w", err))
+		} else {
+			h.handle(ctx, req, bwf, rpcError, func(bool) {}, nil)
+		}
+
+		if resp := bytes.TrimSpace(buf.Bytes()); len(resp) > 0 {
+			resps = append(resps, resp)
+		}
+	}
+
+	if len(resps) == 0 {
+		return // only notifications
+	}
+
+	if err := json.NewEncoder(w).Encode(resps); err != nil {
+		log.Errorf("failed to write batch response: %s", err)
+	}
 }
 
 func doCall(methodName string, f reflect.Value, params []reflect.Value) (out []reflect.Value, err error) {
@@ -238,17 +310,32 @@ func (h handlers) handle(ctx context.Context, req request, w func(func(io.Writer
 		// Sending responses here could cause deadlocks on writeLk, or allow
 		// sending channel messages before this rpc call returns
 
-		//noinspection GoNilness // already checked above
-		err = chOut(callResult[handler.valOut], *req.ID)
-		if err == nil {
-			return // channel goroutine handles responding
-		}
+		switch {
+		case resp.Error != nil:
+			// the call failed, there is nothing to stream
+			resp.Result = nil
+		case callResult[handler.valOut].IsNil():
+			log.Warnf("RPC call to '%s' returned a nil channel", req.Method)
+			stats.Record(ctx, metrics.RPCResponseError.M(1))
+			resp.Result = nil
+			resp.Error = &respError{
+				Code:    1,
+				Message: fmt.Sprintf("method '%s' returned a nil channel", req.Method),
+			}
+		default:
+			//noinspection GoNilness // already checked above
+			err = chOut(callResult[handler.valOut], *req.ID)
+			if err == nil {
+				return // channel goroutine handles responding
+			}
 
-		log.Warnf("failed to setup channel in RPC call to '%s': %+v", req.Method, err)
-		stats.Record(ctx, metrics.RPCResponseError.M(1))
-		resp.Error = &respError{
-			Code:    1,
-			Message: err.(error).Error(),
+			log.Warnf("failed to setup channel in RPC call to '%s': %+v", req.Method, err)
+			stats.Record(ctx, metrics.RPCResponseError.M(1))
+			resp.Result = nil
+			resp.Error = &respError{
+				Code:    1,
+				Message: err.(error).Error(),
+			}
 		}
 	}
 
@@ -0,0 +1,136 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+const (
+	streamNDJSON = "application/x-ndjson"
+	streamSSE    = "text/event-stream"
+)
+
+// httpChanID is the channel ID used in streamed responses. Each HTTP request
+// carries a single call, so there is at most one channel per stream.
+const httpChanID = uint64(1)
+
+// streamFormat returns the streaming format requested through the Accept
+// header, or an empty string if the client doesn't accept streamed responses
+func streamFormat(r *http.Request) string {
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, streamSSE):
+		return streamSSE
+	case strings.Contains(accept, streamNDJSON):
+		return streamNDJSON
+	default:
+		return ""
+	}
+}
+
+// httpStream serves channel-returning methods over plain HTTP for clients
+// which can't use websockets. Messages use the same framing as on websocket
+// connections: a response carrying the channel ID, followed by xrpc.ch.val
+// notifications for each value and a final xrpc.ch.close notification.
+// Messages are written either as newline-delimited JSON, or as Server-Sent
+// Events.
+type httpStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	format  string
+
+	done chan struct{}
+}
+
+func newHTTPStream(w http.ResponseWriter, format string) *httpStream {
+	return &httpStream{
+		w:      w,
+		format: format,
+	}
+}
+
+// handleChanOut starts forwarding channel values to the client
+func (s *httpStream) handleChanOut(ch reflect.Value, req int64) error {
+	flusher, ok := s.w.(http.Flusher)
+	if !ok {
+		return xerrors.New("streaming not supported by the http connection")
+	}
+	s.flusher = flusher
+
+	s.w.Header().Set("Content-Type", s.format)
+	s.w.Header().Set("Cache-Control", "no-cache")
+
+	if err := s.send(response{
+		Jsonrpc: "2.0",
+		ID:      req,
+		Result:  httpChanID,
+	}); err != nil {
+		return xerrors.Errorf("sending channel id: %w", err)
+	}
+
+	s.done = make(chan struct{})
+	go s.forward(ch)
+
+	return nil
+}
+
+func (s *httpStream) forward(ch reflect.Value) {
+	defer close(s.done)
+
+	for {
+		val, ok := ch.Recv()
+		if !ok {
+			break
+		}
+
+		// keep draining the channel on write errors, so the sender doesn't
+		// block until it notices the request context was cancelled
+		if err := s.send(request{
+			Jsonrpc: "2.0",
+			ID:      nil, // notification
+			Method:  chValue,
+			Params:  []param{{v: reflect.ValueOf(httpChanID)}, {v: val}},
+		}); err != nil {
+			log.Warnf("failed to stream channel value: %s", err)
+		}
+	}
+
+	if err := s.send(request{
+		Jsonrpc: "2.0",
+		ID:      nil, // notification
+		Method:  chClose,
+		Params:  []param{{v: reflect.ValueOf(httpChanID)}},
+	}); err != nil {
+		log.Warnf("failed to stream channel close: %s", err)
+	}
+}
+
+func (s *httpStream) send(msg interface{}) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if s.format == streamSSE {
+		b = append(append([]byte("data: "), b...), '\n', '\n')
+	} else {
+		b = append(b, '\n')
+	}
+
+	if _, err := s.w.Write(b); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// wait blocks until the streamed channel, if any, is closed
+func (s *httpStream) wait() {
+	if s.done != nil {
+		<-s.done
+	}
+}
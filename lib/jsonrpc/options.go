@@ -0,0 +1,62 @@
+package jsonrpc
+
+import (
+	"reflect"
+	"time"
+)
+
+type Config struct {
+	reconnect           bool
+	reconnectBackoffMin time.Duration
+	reconnectBackoffMax time.Duration
+
+	retryable   func(reflect.StructField) bool
+	onReconnect func()
+}
+
+func defaultConfig() Config {
+	return Config{
+		reconnectBackoffMin: time.Second,
+		reconnectBackoffMax: time.Minute,
+
+		retryable:   func(reflect.StructField) bool { return false },
+		onReconnect: func() {},
+	}
+}
+
+type Option func(c *Config)
+
+// WithReconnect makes the client redial the server when the connection is
+// lost. Channel subscriptions are resumed on the new connection, and calls
+// accepted by the retry filter are retried.
+func WithReconnect(reconnect bool) Option {
+	return func(c *Config) {
+		c.reconnect = reconnect
+	}
+}
+
+// WithReconnectBackoff sets the bounds of the exponential backoff between
+// reconnect attempts
+func WithReconnectBackoff(minDelay, maxDelay time.Duration) Option {
+	return func(c *Config) {
+		c.reconnectBackoffMin = minDelay
+		c.reconnectBackoffMax = maxDelay
+	}
+}
+
+// WithRetryFilter sets the function deciding which methods are safe to call
+// again when the connection is lost before they return. Only idempotent
+// methods should be retried.
+func WithRetryFilter(f func(method reflect.StructField) bool) Option {
+	return func(c *Config) {
+		c.retryable = f
+	}
+}
+
+// WithReconnectNotify sets a function called each time the client reconnects,
+// which allows consumers to resync state they may have missed
+func WithReconnectNotify(f func()) Option {
+	return func(c *Config) {
+		c.onReconnect = f
+	}
+}
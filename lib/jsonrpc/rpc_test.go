@@ -1,9 +1,13 @@
 package jsonrpc
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
@@ -272,6 +276,52 @@ func TestUnmarshalableResult(t *testing.T) {
 	require.EqualError(t, err, "RPC client error: unmarshaling result: nope")
 }
 
+func TestBatch(t *testing.T) {
+	serverHandler := &SimpleServerHandler{}
+
+	rpcServer := NewServer()
+	rpcServer.Register("SimpleServerHandler", serverHandler)
+
+	testServ := httptest.NewServer(rpcServer)
+	defer testServ.Close()
+
+	body := `[
+		{"jsonrpc": "2.0", "id": 1, "method": "SimpleServerHandler.AddGet", "params": [2]},
+		{"jsonrpc": "2.0", "method": "SimpleServerHandler.Add", "params": [3]},
+		{"jsonrpc": "2.0", "id": 3, "method": "SimpleServerHandler.Nope", "params": []}
+	]`
+
+	resp, err := http.Post(testServ.URL, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+
+	var out []struct {
+		ID     int64
+		Result json.RawMessage
+		Error  *respError
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+
+	// no response to the notification
+	require.Len(t, out, 2)
+
+	require.Equal(t, int64(1), out[0].ID)
+	require.Nil(t, out[0].Error)
+	require.Equal(t, "2", string(out[0].Result))
+
+	require.Equal(t, int64(3), out[1].ID)
+	require.NotNil(t, out[1].Error)
+	require.Equal(t, rpcMethodNotFound, out[1].Error.Code)
+
+	require.Equal(t, 5, serverHandler.n)
+
+	// empty batch
+	resp, err = http.Post(testServ.URL, "application/json", strings.NewReader("[]"))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
 type ChanHandler struct {
 	wait chan struct{}
 }
@@ -377,6 +427,118 @@ func TestChan(t *testing.T) {
 	require.Equal(t, false, ok)
 }
 
+func TestHTTPStream(t *testing.T) {
+	for _, format := range []string{streamNDJSON, streamSSE} {
+		t.Run(format, func(t *testing.T) {
+			testHTTPStream(t, format)
+		})
+	}
+}
+
+func testHTTPStream(t *testing.T, format string) {
+	serverHandler := &ChanHandler{
+		wait: make(chan struct{}, 5),
+	}
+
+	rpcServer := NewServer()
+	rpcServer.Register("ChanHandler", serverHandler)
+
+	testServ := httptest.NewServer(rpcServer)
+	defer testServ.Close()
+
+	// values 2 and 4, then the channel is closed remotely at 6
+	for i := 0; i < 3; i++ {
+		serverHandler.wait <- struct{}{}
+	}
+
+	body := `{"jsonrpc": "2.0", "id": 1, "method": "ChanHandler.Sub", "params": [2, 6]}`
+	req, err := http.NewRequest("POST", testServ.URL, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Accept", format)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close() //nolint:errcheck
+
+	require.Equal(t, format, resp.Header.Get("Content-Type"))
+
+	var msgs []frame
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if format == streamSSE {
+			if line == "" {
+				continue
+			}
+			require.True(t, strings.HasPrefix(line, "data: "), line)
+			line = strings.TrimPrefix(line, "data: ")
+		}
+
+		var f frame
+		require.NoError(t, json.Unmarshal([]byte(line), &f))
+		msgs = append(msgs, f)
+	}
+	require.NoError(t, scanner.Err())
+
+	require.Len(t, msgs, 4)
+
+	require.Equal(t, int64(1), *msgs[0].ID)
+	require.Equal(t, "1", string(msgs[0].Result))
+
+	for i, expect := range []string{"2", "4"} {
+		require.Equal(t, chValue, msgs[i+1].Method)
+		require.Len(t, msgs[i+1].Params, 2)
+		require.Equal(t, "1", string(msgs[i+1].Params[0].data))
+		require.Equal(t, expect, string(msgs[i+1].Params[1].data))
+	}
+
+	require.Equal(t, chClose, msgs[3].Method)
+}
+
+type FailingChanHandler struct{}
+
+func (h *FailingChanHandler) Sub(ctx context.Context) (<-chan int, error) {
+	return nil, errors.New("sub failed")
+}
+
+func (h *FailingChanHandler) NilSub(ctx context.Context) (<-chan int, error) {
+	return nil, nil
+}
+
+func TestHTTPStreamError(t *testing.T) {
+	rpcServer := NewServer()
+	rpcServer.Register("FailingChanHandler", &FailingChanHandler{})
+
+	testServ := httptest.NewServer(rpcServer)
+	defer testServ.Close()
+
+	for method, expect := range map[string]string{
+		"Sub":    "sub failed",
+		"NilSub": "method 'FailingChanHandler.NilSub' returned a nil channel",
+	} {
+		body := `{"jsonrpc": "2.0", "id": 1, "method": "FailingChanHandler.` + method + `", "params": []}`
+		req, err := http.NewRequest("POST", testServ.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Accept", streamNDJSON)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		var res struct {
+			ID     int64           `json:"id"`
+			Result json.RawMessage `json:"result"`
+			Error  *respError      `json:"error"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		require.NoError(t, resp.Body.Close())
+
+		require.Equal(t, int64(1), res.ID)
+		require.Nil(t, res.Result)
+		require.NotNil(t, res.Error)
+		require.Equal(t, expect, res.Error.Message)
+	}
+}
+
 func TestControlChanDeadlock(t *testing.T) {
 	for r := 0; r < 20; r++ {
 		testControlChanDeadlock(t)
@@ -423,3 +585,110 @@ func testControlChanDeadlock(t *testing.T) {
 	_, err = client.Sub(ctx, 2, -1)
 	require.NoError(t, err)
 }
+
+type ResubHandler struct {
+	lk   sync.Mutex
+	subs int
+}
+
+// Sub sends the number of subscriptions made so far, and closes the channel
+// when the context is cancelled
+func (h *ResubHandler) Sub(ctx context.Context) (<-chan int, error) {
+	h.lk.Lock()
+	h.subs++
+	n := h.subs
+	h.lk.Unlock()
+
+	out := make(chan int, 1)
+	out <- n
+
+	go func() {
+		<-ctx.Done()
+		close(out)
+	}()
+
+	return out, nil
+}
+
+// trackingListener allows dropping all accepted connections, including
+// hijacked websocket connections
+type trackingListener struct {
+	net.Listener
+
+	lk    sync.Mutex
+	conns []net.Conn
+}
+
+func (l *trackingListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err == nil {
+		l.lk.Lock()
+		l.conns = append(l.conns, c)
+		l.lk.Unlock()
+	}
+	return c, err
+}
+
+func (l *trackingListener) dropAll() {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	for _, c := range l.conns {
+		_ = c.Close()
+	}
+	l.conns = nil
+}
+
+func TestReconnect(t *testing.T) {
+	var client struct {
+		Sub func(context.Context) (<-chan int, error)
+	}
+
+	rpcServer := NewServer()
+	rpcServer.Register("ResubHandler", &ResubHandler{})
+
+	testServ := httptest.NewUnstartedServer(rpcServer)
+	tl := &trackingListener{Listener: testServ.Listener}
+	testServ.Listener = tl
+	testServ.Start()
+	defer testServ.Close()
+
+	reconnected := make(chan struct{}, 1)
+	closer, err := NewClient("ws://"+testServ.Listener.Addr().String(), "ResubHandler", &client, nil,
+		WithReconnect(true),
+		WithReconnectBackoff(10*time.Millisecond, 100*time.Millisecond),
+		WithReconnectNotify(func() {
+			reconnected <- struct{}{}
+		}))
+	require.NoError(t, err)
+	defer closer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := client.Sub(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, <-sub)
+
+	tl.dropAll()
+
+	select {
+	case <-reconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client didn't reconnect")
+	}
+
+	// the subscription is resumed on the same channel
+	select {
+	case n, ok := <-sub:
+		require.True(t, ok)
+		require.Equal(t, 2, n)
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription wasn't resumed")
+	}
+
+	// close (through ctx)
+	cancel()
+	_, ok := <-sub
+	require.False(t, ok)
+}
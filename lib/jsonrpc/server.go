@@ -12,6 +12,7 @@ import (
 
 const (
 	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
 	rpcMethodNotFound = -32601
 	rpcInvalidParams  = -32602
 )
@@ -71,7 +72,18 @@ func (s *RPCServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.methods.handleReader(ctx, r.Body, w, rpcError)
+	var chOut chanOut
+	var stream *httpStream
+	if format := streamFormat(r); format != "" {
+		stream = newHTTPStream(w, format)
+		chOut = stream.handleChanOut
+	}
+
+	s.methods.handleReader(ctx, r.Body, w, rpcError, chOut)
+
+	if stream != nil {
+		stream.wait()
+	}
 }
 
 func rpcError(wf func(func(io.Writer)), req *request, code int, err error) {
@@ -18,6 +18,10 @@ const wsCancel = "xrpc.cancel"
 const chValue = "xrpc.ch.val"
 const chClose = "xrpc.ch.close"
 
+// eTempWSError is the error code of responses to calls which were in flight
+// when the connection was lost
+const eTempWSError = -1111
+
 type frame struct {
 	// common
 	Jsonrpc string            `json:"jsonrpc"`
@@ -438,16 +442,17 @@ func (c *wsConn) handleWsConn(ctx context.Context) {
 				Jsonrpc: "2.0",
 				ID:      id,
 				Error: &respError{
+					Code:    eTempWSError,
 					Message: "handler: websocket connection closed",
 				},
 			}
+		}
 
-			c.handlingLk.Lock()
-			for _, cancel := range c.handling {
-				cancel()
-			}
-			c.handlingLk.Unlock()
+		c.handlingLk.Lock()
+		for _, cancel := range c.handling {
+			cancel()
 		}
+		c.handlingLk.Unlock()
 	}()
 
 	// wait for the first message
@@ -104,6 +104,42 @@ func (s *StateGroup) Stop(ctx context.Context) error {
 	return nil
 }
 
+// Halt stops the state machine identified by `id`. Its state is kept, and
+// sending an event to it starts it again. Steps which are already running
+// aren't interrupted, but can't send events anymore.
+func (s *StateGroup) Halt(ctx context.Context, id interface{}) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	return s.haltLocked(ctx, id)
+}
+
+// End stops the state machine identified by `id`, and removes its state
+func (s *StateGroup) End(ctx context.Context, id interface{}) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if err := s.haltLocked(ctx, id); err != nil {
+		return err
+	}
+
+	return s.sts.Get(id).End()
+}
+
+func (s *StateGroup) haltLocked(ctx context.Context, id interface{}) error {
+	sm, exist := s.sms[statestore.ToKey(id)]
+	if !exist {
+		return nil
+	}
+
+	if err := sm.stop(ctx); err != nil {
+		return xerrors.Errorf("stopping state machine: %w", err)
+	}
+
+	delete(s.sms, statestore.ToKey(id))
+	return nil
+}
+
 // List outputs states of all state machines in this group
 // out: *[]StateT
 func (s *StateGroup) List(out interface{}) error {
@@ -6,6 +6,7 @@ import (
 	"sync/atomic"
 
 	"github.com/filecoin-project/go-statestore"
+	"golang.org/x/xerrors"
 
 	logging "github.com/ipfs/go-log"
 )
@@ -92,7 +93,10 @@ func (fsm *StateMachine) run() {
 				}
 
 				atomic.StoreInt32(&fsm.busy, 0)
-				fsm.stageDone <- struct{}{}
+				select {
+				case fsm.stageDone <- struct{}{}:
+				case <-fsm.closed:
+				}
 			}()
 
 		}
@@ -111,8 +115,12 @@ func (fsm *StateMachine) mutateUser(cb func(user interface{}) error) error {
 }
 
 func (fsm *StateMachine) send(evt Event) error {
-	fsm.eventsIn <- evt // TODO: ctx, at least
-	return nil
+	select {
+	case fsm.eventsIn <- evt: // TODO: ctx, at least
+		return nil
+	case <-fsm.closed:
+		return xerrors.Errorf("state machine %v is stopped", fsm.name)
+	}
 }
 
 func (fsm *StateMachine) stop(ctx context.Context) error {
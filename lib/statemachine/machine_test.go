@@ -102,4 +102,28 @@ func TestPersist(t *testing.T) {
 	}
 }
 
+func TestEnd(t *testing.T) {
+	ds := datastore.NewMapDatastore()
+
+	th := &testHandler{t: t, done: make(chan struct{}), proceed: make(chan struct{})}
+	smm := New(ds, th, TestState{})
+
+	if err := smm.Send(uint64(2), &TestEvent{A: "start"}); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if err := smm.End(context.Background(), uint64(2)); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	has, err := smm.sts.Has(uint64(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Assert(t, !has)
+
+	// the running step finishes without blocking
+	close(th.proceed)
+}
+
 var _ StateHandler = &testHandler{}
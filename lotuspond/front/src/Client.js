@@ -44,7 +44,7 @@ class Client extends React.Component {
 
   getDeals = async () => {
     let miners = await this.props.client.call('Filecoin.StateListMiners', [null])
-    let deals = await this.props.client.call('Filecoin.ClientListDeals', [])
+    let deals = await this.props.client.call('Filecoin.ClientListDeals', [{}])
     miners.sort()
     this.setState({deals, miners})
   }
@@ -55,7 +55,7 @@ class Client extends React.Component {
     let perBlk = this.state.ask.Price * this.state.kbs * 1000 / (1 << 30) * 2
 
     let file = await this.props.pondClient.call('Pond.CreateRandomFile', [this.state.kbs * 1000]) // 1024 won't fit in 1k blocks :(
-    let cid = await this.props.client.call('Filecoin.ClientImport', [file])
+    let cid = await this.props.client.call('Filecoin.ClientImport', [{ Path: file, IsCAR: false }])
     let dealcid = await this.props.client.call('Filecoin.ClientStartDeal', [cid, this.state.miner, `${Math.round(perBlk)}`, Number(this.state.blocks)])
     console.log("deal cid: ", dealcid)
   }
@@ -67,14 +67,13 @@ class Client extends React.Component {
     let order = {
       Root: deal.PieceRef,
       Size: deal.Size,
-      // TODO: support offset
       Total: String(deal.Size * 2),
 
       Client: client,
       Miner: deal.Miner
     }
 
-    await this.props.client.call('Filecoin.ClientRetrieve', [order, '/dev/null'])
+    await this.props.client.call('Filecoin.ClientRetrieve', [order, { Path: '/dev/null', IsCAR: false }])
   }
 
   render() {
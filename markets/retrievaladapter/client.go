@@ -3,6 +3,8 @@ package retrievaladapter
 import (
 	"context"
 
+	"golang.org/x/xerrors"
+
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
 	retrievaltoken "github.com/filecoin-project/go-fil-markets/shared/tokenamount"
@@ -41,6 +43,12 @@ func (rcn *retrievalClientNode) AllocateLane(paymentChannel address.Address) (ui
 // given payment channel so that all the payment vouchers in the lane add up
 // to the given amount (so the payment voucher will be for the difference)
 func (rcn *retrievalClientNode) CreatePaymentVoucher(ctx context.Context, paymentChannel address.Address, amount retrievaltoken.TokenAmount, lane uint64) (*retrievaltypes.SignedVoucher, error) {
+	// the market client can't cancel deals, a retrieval which was given up on
+	// is stopped by not paying for it anymore
+	if err := ctx.Err(); err != nil {
+		return nil, xerrors.Errorf("retrieval cancelled, not creating voucher: %w", err)
+	}
+
 	voucher, err := rcn.payapi.PaychVoucherCreate(ctx, paymentChannel, utils.FromSharedTokenAmount(amount), lane)
 	if err != nil {
 		return nil, err
@@ -23,6 +23,7 @@ import (
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/markets/utils"
 	"github.com/filecoin-project/lotus/node/impl/full"
+	"github.com/filecoin-project/lotus/node/repo/dealmeta"
 )
 
 type ClientNodeAdapter struct {
@@ -34,6 +35,8 @@ type ClientNodeAdapter struct {
 	cs *store.ChainStore
 	fm *market.FundMgr
 	ev *events.Events
+
+	meta *dealmeta.Store
 }
 
 type clientApi struct {
@@ -41,7 +44,7 @@ type clientApi struct {
 	full.StateAPI
 }
 
-func NewClientNodeAdapter(state full.StateAPI, chain full.ChainAPI, mpool full.MpoolAPI, sm *stmgr.StateManager, cs *store.ChainStore, fm *market.FundMgr) storagemarket.StorageClientNode {
+func NewClientNodeAdapter(state full.StateAPI, chain full.ChainAPI, mpool full.MpoolAPI, sm *stmgr.StateManager, cs *store.ChainStore, fm *market.FundMgr, meta *dealmeta.Store) storagemarket.StorageClientNode {
 	return &ClientNodeAdapter{
 		StateAPI: state,
 		ChainAPI: chain,
@@ -51,6 +54,8 @@ func NewClientNodeAdapter(state full.StateAPI, chain full.ChainAPI, mpool full.M
 		cs: cs,
 		fm: fm,
 		ev: events.NewEvents(context.TODO(), &clientApi{chain, state}),
+
+		meta: meta,
 	}
 }
 
@@ -154,6 +159,16 @@ func (n *ClientNodeAdapter) GetBalance(ctx context.Context, addr address.Address
 // ValidatePublishedDeal validates that the provided deal has appeared on chain and references the same ClientDeal
 // returns the Deal id if there is no error
 func (c *ClientNodeAdapter) ValidatePublishedDeal(ctx context.Context, deal storagemarket.ClientDeal) (uint64, error) {
+	dealID, err := c.validatePublishedDeal(ctx, deal)
+	if err != nil {
+		if merr := c.meta.ProposalFailed(deal.ProposalCid, err.Error()); merr != nil {
+			log.Errorf("recording failure of deal %s: %+v", deal.ProposalCid, merr)
+		}
+	}
+	return dealID, err
+}
+
+func (c *ClientNodeAdapter) validatePublishedDeal(ctx context.Context, deal storagemarket.ClientDeal) (uint64, error) {
 	log.Infow("DEAL ACCEPTED!")
 
 	pubmsg, err := c.cs.GetMessage(*deal.PublishMessage)
@@ -219,14 +234,31 @@ func (c *ClientNodeAdapter) ValidatePublishedDeal(ctx context.Context, deal stor
 }
 
 func (c *ClientNodeAdapter) OnDealSectorCommitted(ctx context.Context, provider address.Address, dealId uint64, cb storagemarket.DealSectorCommittedCallback) error {
+	done := cb
+	cb = func(err error) {
+		if err != nil {
+			if merr := c.meta.DealFailed(dealId, err.Error()); merr != nil {
+				log.Errorf("recording failure of deal %d: %+v", dealId, merr)
+			}
+		}
+		done(err)
+	}
+
 	checkFunc := func(ts *types.TipSet) (done bool, more bool, err error) {
-		sd, err := stmgr.GetStorageDeal(ctx, c.StateManager, dealId, ts)
+		sd, err := stmgr.GetStorageDeal(ctx, c.StateAPI.StateManager, dealId, ts)
 		if err != nil {
 			// TODO: This may be fine for some errors
 			return false, false, xerrors.Errorf("failed to look up deal on chain: %w", err)
 		}
 
 		if sd.ActivationEpoch > 0 {
+			sector, err := c.committedSector(ctx, provider, dealId, sd.ActivationEpoch)
+			if err != nil {
+				log.Errorf("finding sector of deal %d: %+v", dealId, err)
+			} else if err := c.meta.SectorCommitted(dealId, sector); err != nil {
+				log.Errorf("recording sector of deal %d: %+v", dealId, err)
+			}
+
 			cb(nil)
 			return true, false, nil
 		}
@@ -246,7 +278,7 @@ func (c *ClientNodeAdapter) OnDealSectorCommitted(ctx context.Context, provider
 			return false, nil
 		}
 
-		sd, err := stmgr.GetStorageDeal(ctx, c.StateManager, dealId, ts)
+		sd, err := stmgr.GetStorageDeal(ctx, c.StateAPI.StateManager, dealId, ts)
 		if err != nil {
 			return false, xerrors.Errorf("failed to look up deal on chain: %w", err)
 		}
@@ -257,6 +289,14 @@ func (c *ClientNodeAdapter) OnDealSectorCommitted(ctx context.Context, provider
 
 		log.Infof("Storage deal %d activated at epoch %d", dealId, sd.ActivationEpoch)
 
+		var params actors.SectorProveCommitInfo
+		if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+			return false, xerrors.Errorf("unmarshaling prove commit params: %w", err)
+		}
+		if err := c.meta.SectorCommitted(dealId, params.SectorID); err != nil {
+			log.Errorf("recording sector of deal %d: %+v", dealId, err)
+		}
+
 		cb(nil)
 
 		return false, nil
@@ -300,6 +340,46 @@ func (c *ClientNodeAdapter) OnDealSectorCommitted(ctx context.Context, provider
 	return nil
 }
 
+// committedSector finds the sector an active deal is stored in from the prove
+// commit message, which is executed in the tipset at the deal activation epoch
+func (c *ClientNodeAdapter) committedSector(ctx context.Context, provider address.Address, dealID uint64, activation uint64) (uint64, error) {
+	ts, err := c.cs.GetTipsetByHeight(ctx, activation, c.cs.GetHeaviestTipSet())
+	if err != nil {
+		return 0, xerrors.Errorf("getting tipset at %d: %w", activation, err)
+	}
+
+	for _, b := range ts.Blocks() {
+		bmsgs, smsgs, err := c.cs.MessagesForBlock(b)
+		if err != nil {
+			return 0, xerrors.Errorf("getting messages of block %s: %w", b.Cid(), err)
+		}
+
+		msgs := bmsgs
+		for _, sm := range smsgs {
+			msgs = append(msgs, &sm.Message)
+		}
+
+		for _, msg := range msgs {
+			if msg.To != provider || msg.Method != actors.MAMethods.ProveCommitSector {
+				continue
+			}
+
+			var params actors.SectorProveCommitInfo
+			if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+				return 0, xerrors.Errorf("unmarshaling prove commit params: %w", err)
+			}
+
+			for _, id := range params.DealIDs {
+				if id == dealID {
+					return params.SectorID, nil
+				}
+			}
+		}
+	}
+
+	return 0, xerrors.Errorf("no prove commit message for deal %d at epoch %d", dealID, activation)
+}
+
 func (n *ClientNodeAdapter) SignProposal(ctx context.Context, signer address.Address, proposal *storagemarket.StorageDealProposal) error {
 	localProposal, err := utils.FromSharedStorageDealProposal(proposal)
 	if err != nil {
@@ -325,7 +405,7 @@ func (n *ClientNodeAdapter) GetDefaultWalletAddress(ctx context.Context) (addres
 func (n *ClientNodeAdapter) ValidateAskSignature(ask *sharedtypes.SignedStorageAsk) error {
 	tss := n.cs.GetHeaviestTipSet().ParentState()
 
-	w, err := stmgr.GetMinerWorkerRaw(context.TODO(), n.StateManager, tss, ask.Ask.Miner)
+	w, err := stmgr.GetMinerWorkerRaw(context.TODO(), n.StateAPI.StateManager, tss, ask.Ask.Miner)
 	if err != nil {
 		return xerrors.Errorf("failed to get worker for miner in ask", err)
 	}
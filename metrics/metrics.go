@@ -34,6 +34,9 @@ var (
 	RPCInvalidMethod         = stats.Int64("rpc/invalid_method", "Total number of invalid RPC methods called", stats.UnitDimensionless)
 	RPCRequestError          = stats.Int64("rpc/request_error", "Total number of request errors handled", stats.UnitDimensionless)
 	RPCResponseError         = stats.Int64("rpc/response_error", "Total number of responses errors handled", stats.UnitDimensionless)
+	MpoolSize                = stats.Int64("mpool/size", "Current number of pending messages in the mpool", stats.UnitDimensionless)
+	MpoolRejected            = stats.Int64("mpool/rejected", "Counter for messages rejected because of mpool limits", stats.UnitDimensionless)
+	MpoolEvicted             = stats.Int64("mpool/evicted", "Counter for messages evicted from a full mpool", stats.UnitDimensionless)
 )
 
 var (
@@ -98,6 +101,19 @@ var (
 		Aggregation: view.Count(),
 		TagKeys:     []tag.Key{RPCMethod},
 	}
+	MpoolSizeView = &view.View{
+		Measure:     MpoolSize,
+		Aggregation: view.LastValue(),
+	}
+	MpoolRejectedView = &view.View{
+		Measure:     MpoolRejected,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{FailureType},
+	}
+	MpoolEvictedView = &view.View{
+		Measure:     MpoolEvicted,
+		Aggregation: view.Count(),
+	}
 )
 
 // DefaultViews is an array of OpenCensus views for metric gathering purposes
@@ -115,4 +131,7 @@ var DefaultViews = []*view.View{
 	RPCInvalidMethodView,
 	RPCRequestErrorView,
 	RPCResponseErrorView,
+	MpoolSizeView,
+	MpoolRejectedView,
+	MpoolEvictedView,
 }
@@ -1,6 +1,7 @@
 package miner
 
 import (
+	"container/heap"
 	"context"
 	"fmt"
 	"sync"
@@ -406,64 +407,184 @@ func (m *Miner) createBlock(base *MiningBase, addr address.Address, ticket *type
 
 type ActorLookup func(context.Context, address.Address, types.TipSetKey) (*types.Actor, error)
 
-func countFrom(msgs []*types.SignedMessage, from address.Address) (out int) {
-	for _, msg := range msgs {
-		if msg.Message.From == from {
-			out++
+// msgChain is a sequence of messages from a single sender, in nonce order,
+// which can be included in a block starting at the current sender nonce
+type msgChain struct {
+	msgs []*types.SignedMessage
+
+	// seq orders chains with equal gas price by the position of their sender
+	// in the pending set, which keeps selection deterministic
+	seq int
+
+	// best prefix of msgs by effective gas price
+	next     int
+	fee, gas types.BigInt
+}
+
+// update finds the prefix of the remaining messages with the highest effective
+// gas price (total fee over total gas), so that a message with a low gas price
+// is pulled in by the higher paying messages that depend on it
+func (mc *msgChain) update() {
+	mc.next = 0
+	mc.fee, mc.gas = types.NewInt(0), types.NewInt(0)
+
+	fee, gas := types.NewInt(0), types.NewInt(0)
+	for i, m := range mc.msgs {
+		fee = types.BigAdd(fee, types.BigMul(m.Message.GasPrice, m.Message.GasLimit))
+		gas = types.BigAdd(gas, m.Message.GasLimit)
+
+		if mc.next == 0 || cmpPrice(fee, gas, mc.fee, mc.gas) >= 0 {
+			mc.next = i + 1
+			mc.fee, mc.gas = fee, gas
 		}
 	}
-	return out
 }
 
-func SelectMessages(ctx context.Context, al ActorLookup, ts *types.TipSet, msgs []*types.SignedMessage) ([]*types.SignedMessage, error) {
-	out := make([]*types.SignedMessage, 0, build.BlockMessageLimit)
-	inclNonces := make(map[address.Address]uint64)
-	inclBalances := make(map[address.Address]types.BigInt)
-	inclCount := make(map[address.Address]int)
+// cmpPrice compares feeA/gasA with feeB/gasB
+func cmpPrice(feeA, gasA, feeB, gasB types.BigInt) int {
+	return types.BigCmp(types.BigMul(feeA, gasB), types.BigMul(feeB, gasA))
+}
 
-	for _, msg := range msgs {
+type chainHeap []*msgChain
+
+func (h chainHeap) Len() int { return len(h) }
+func (h chainHeap) Less(i, j int) bool {
+	if c := cmpPrice(h[i].fee, h[i].gas, h[j].fee, h[j].gas); c != 0 {
+		return c > 0
+	}
+	return h[i].seq < h[j].seq
+}
+func (h chainHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *chainHeap) Push(x interface{}) {
+	*h = append(*h, x.(*msgChain))
+}
 
+func (h *chainHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// buildChains groups messages by sender and returns, for each sender, the
+// messages that can be applied on top of the sender state in ts: consecutive
+// nonces starting at the actor nonce, as long as the actor can pay for them
+func buildChains(ctx context.Context, al ActorLookup, ts *types.TipSet, msgs []*types.SignedMessage) []*msgChain {
+	bySender := make(map[address.Address]map[uint64]*types.SignedMessage)
+	var senders []address.Address
+
+	for _, msg := range msgs {
 		if msg.Message.To == address.Undef {
 			log.Warnf("message in mempool had bad 'To' address")
 			continue
 		}
 
 		from := msg.Message.From
+		set, ok := bySender[from]
+		if !ok {
+			set = make(map[uint64]*types.SignedMessage)
+			bySender[from] = set
+			senders = append(senders, from)
+		}
 
-		if _, ok := inclNonces[from]; !ok {
-			act, err := al(ctx, from, ts.Key())
-			if err != nil {
-				log.Warnf("failed to check message sender balance, skipping message: %+v", err)
-				continue
+		if other, ok := set[msg.Message.Nonce]; ok && !msg.Message.GasPrice.GreaterThan(other.Message.GasPrice) {
+			continue
+		}
+		set[msg.Message.Nonce] = msg
+	}
+
+	chains := make([]*msgChain, 0, len(senders))
+	for i, from := range senders {
+		set := bySender[from]
+
+		act, err := al(ctx, from, ts.Key())
+		if err != nil {
+			log.Warnf("failed to check message sender balance, skipping %d messages: %+v", len(set), err)
+			continue
+		}
+
+		balance := act.Balance
+		mc := &msgChain{seq: i}
+		for nonce := act.Nonce; ; nonce++ {
+			msg, ok := set[nonce]
+			if !ok {
+				break
+			}
+
+			if msg.Message.GasLimit.GreaterThan(types.NewInt(build.BlockGasLimit)) {
+				log.Warnf("message in mempool has GasLimit above block gas limit: %s", msg.Cid())
+				break
+			}
+
+			if balance.LessThan(msg.Message.RequiredFunds()) {
+				log.Warnf("message in mempool does not have enough funds: %s", msg.Cid())
+				break
 			}
+			balance = types.BigSub(balance, msg.Message.RequiredFunds())
 
-			inclNonces[from] = act.Nonce
-			inclBalances[from] = act.Balance
+			mc.msgs = append(mc.msgs, msg)
 		}
 
-		if inclBalances[from].LessThan(msg.Message.RequiredFunds()) {
-			log.Warnf("message in mempool does not have enough funds: %s", msg.Cid())
-			continue
+		if skipped := len(set) - len(mc.msgs); skipped > 0 {
+			log.Debugf("skipping %d messages from %s (actor nonce %d)", skipped, from, act.Nonce)
 		}
 
-		if msg.Message.Nonce > inclNonces[from] {
-			log.Warnf("message in mempool has too high of a nonce (%d > %d, from %s, inclcount %d) %s (%d pending for orig)", msg.Message.Nonce, inclNonces[from], from, inclCount[from], msg.Cid(), countFrom(msgs, from))
+		if len(mc.msgs) == 0 {
 			continue
 		}
 
-		if msg.Message.Nonce < inclNonces[from] {
-			log.Warnf("message in mempool has already used nonce (%d < %d), from %s, to %s, %s (%d pending for)", msg.Message.Nonce, inclNonces[from], msg.Message.From, msg.Message.To, msg.Cid(), countFrom(msgs, from))
-			continue
+		mc.update()
+		chains = append(chains, mc)
+	}
+
+	return chains
+}
+
+// SelectMessages picks the messages to include in a block mined on top of ts.
+// Messages are grouped into per-sender nonce chains, which are packed by
+// effective gas price until either the block gas limit or the block message
+// limit is reached. Messages from a single sender are always included in
+// nonce order, without gaps.
+func SelectMessages(ctx context.Context, al ActorLookup, ts *types.TipSet, msgs []*types.SignedMessage) ([]*types.SignedMessage, error) {
+	chains := buildChains(ctx, al, ts, msgs)
+
+	h := chainHeap(chains)
+	heap.Init(&h)
+
+	out := make([]*types.SignedMessage, 0, build.BlockMessageLimit)
+	gasLeft := types.NewInt(build.BlockGasLimit)
+
+	for h.Len() > 0 && len(out) < build.BlockMessageLimit {
+		mc := h[0]
+
+		var taken int
+		for _, msg := range mc.msgs[:mc.next] {
+			if len(out) >= build.BlockMessageLimit || gasLeft.LessThan(msg.Message.GasLimit) {
+				break
+			}
+
+			gasLeft = types.BigSub(gasLeft, msg.Message.GasLimit)
+			out = append(out, msg)
+			taken++
 		}
 
-		inclNonces[from] = msg.Message.Nonce + 1
-		inclBalances[from] = types.BigSub(inclBalances[from], msg.Message.RequiredFunds())
-		inclCount[from]++
+		if taken < mc.next {
+			// the rest of this chain depends on a message that doesn't fit
+			heap.Pop(&h)
+			continue
+		}
 
-		out = append(out, msg)
-		if len(out) >= build.BlockMessageLimit {
-			break
+		mc.msgs = mc.msgs[taken:]
+		if len(mc.msgs) == 0 {
+			heap.Pop(&h)
+			continue
 		}
+
+		mc.update()
+		heap.Fix(&h, 0)
 	}
+
 	return out, nil
 }
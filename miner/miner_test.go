@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/chain/types"
 )
 
@@ -102,3 +103,121 @@ func wrapMsgs(msgs []types.Message) []*types.SignedMessage {
 	}
 	return out
 }
+
+func mkMsg(from address.Address, nonce uint64, gasPrice, gasLimit uint64) types.Message {
+	return types.Message{
+		From:     from,
+		To:       from,
+		Nonce:    nonce,
+		Value:    types.NewInt(0),
+		GasLimit: types.NewInt(gasLimit),
+		GasPrice: types.NewInt(gasPrice),
+	}
+}
+
+func TestMessageSelectionByGasPrice(t *testing.T) {
+	ctx := context.TODO()
+	a1 := mustIDAddr(1)
+	a2 := mustIDAddr(2)
+	a3 := mustIDAddr(3)
+
+	af := func(ctx context.Context, addr address.Address, tsk types.TipSetKey) (*types.Actor, error) {
+		return &types.Actor{
+			Nonce:   0,
+			Balance: types.NewInt(1000000),
+		}, nil
+	}
+
+	msgs := []types.Message{
+		mkMsg(a1, 0, 1, 100),
+		mkMsg(a1, 1, 1, 100),
+		mkMsg(a2, 0, 5, 100),
+		mkMsg(a3, 0, 1, 100),
+		// a3's second message pays enough to pull the first one in
+		mkMsg(a3, 1, 20, 100),
+	}
+
+	outmsgs, err := SelectMessages(ctx, af, nil, wrapMsgs(msgs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []types.Message{msgs[3], msgs[4], msgs[2], msgs[0], msgs[1]}
+	if len(outmsgs) != len(expect) {
+		t.Fatalf("expected %d messages, got %d", len(expect), len(outmsgs))
+	}
+
+	for i, m := range expect {
+		if outmsgs[i].Message.From != m.From || outmsgs[i].Message.Nonce != m.Nonce {
+			t.Fatalf("message %d: expected %s/%d, got %s/%d", i, m.From, m.Nonce, outmsgs[i].Message.From, outmsgs[i].Message.Nonce)
+		}
+	}
+}
+
+func TestMessageSelectionGasLimit(t *testing.T) {
+	ctx := context.TODO()
+	a1 := mustIDAddr(1)
+	a2 := mustIDAddr(2)
+	a3 := mustIDAddr(3)
+
+	af := func(ctx context.Context, addr address.Address, tsk types.TipSetKey) (*types.Actor, error) {
+		return &types.Actor{
+			Nonce:   0,
+			Balance: types.NewInt(1000000000000),
+		}, nil
+	}
+
+	half := uint64(build.BlockGasLimit / 2)
+
+	msgs := []types.Message{
+		mkMsg(a1, 0, 3, half),
+		// doesn't fit after a1/0, and a1/2 depends on it
+		mkMsg(a1, 1, 3, half+1),
+		mkMsg(a1, 2, 3, 1),
+		mkMsg(a2, 0, 2, half),
+		mkMsg(a3, 0, 1, half),
+	}
+
+	outmsgs, err := SelectMessages(ctx, af, nil, wrapMsgs(msgs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(outmsgs) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(outmsgs))
+	}
+
+	if outmsgs[0].Message.From != a1 || outmsgs[0].Message.Nonce != 0 {
+		t.Fatal("expected a1/0 to be selected first")
+	}
+	if outmsgs[1].Message.From != a2 {
+		t.Fatal("expected a2/0 to fill the rest of the block")
+	}
+}
+
+func BenchmarkSelectMessages(b *testing.B) {
+	ctx := context.TODO()
+
+	var msgs []types.Message
+	for s := uint64(0); s < 200; s++ {
+		from := mustIDAddr(100 + s)
+		for n := uint64(0); n < 20; n++ {
+			msgs = append(msgs, mkMsg(from, n, 1+(s*7+n*13)%50, 1000+(s*n)%5000))
+		}
+	}
+	pending := wrapMsgs(msgs)
+
+	af := func(ctx context.Context, addr address.Address, tsk types.TipSetKey) (*types.Actor, error) {
+		return &types.Actor{
+			Nonce:   0,
+			Balance: types.NewInt(1000000000000),
+		}, nil
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := SelectMessages(ctx, af, nil, pending); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
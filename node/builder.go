@@ -50,11 +50,15 @@ import (
 	"github.com/filecoin-project/lotus/node/modules/lp2p"
 	"github.com/filecoin-project/lotus/node/modules/testing"
 	"github.com/filecoin-project/lotus/node/repo"
+	"github.com/filecoin-project/lotus/node/repo/dealmeta"
+	"github.com/filecoin-project/lotus/node/repo/importmgr"
 	"github.com/filecoin-project/lotus/paych"
 	"github.com/filecoin-project/lotus/peermgr"
 	"github.com/filecoin-project/lotus/storage"
+	"github.com/filecoin-project/lotus/storage/sched"
 	"github.com/filecoin-project/lotus/storage/sealing"
 	"github.com/filecoin-project/lotus/storage/sectorblocks"
+	"github.com/filecoin-project/lotus/storage/stores"
 )
 
 var log = logging.Logger("builder")
@@ -88,6 +92,7 @@ const (
 
 	// filecoin
 	SetGenesisKey
+	SetupMsgIndexKey
 
 	RunHelloKey
 	RunBlockSyncKey
@@ -95,6 +100,7 @@ const (
 
 	HandleIncomingBlocksKey
 	HandleIncomingMessagesKey
+	RunChainPrunerKey
 
 	RunDealClientKey
 	RegisterClientValidatorKey
@@ -216,6 +222,7 @@ func Online() Option {
 			// Filecoin services
 			Override(new(*chain.Syncer), modules.NewSyncer),
 			Override(new(*blocksync.BlockSync), blocksync.NewBlockSyncClient),
+			Override(new(*messagepool.Config), messagepool.DefaultConfig),
 			Override(new(*messagepool.MessagePool), modules.MessagePool),
 
 			Override(new(modules.Genesis), modules.ErrorGenesis),
@@ -235,6 +242,8 @@ func Online() Option {
 
 			Override(new(retrievalmarket.RetrievalClient), modules.RetrievalClient),
 			Override(new(dtypes.ClientDealStore), modules.NewClientDealStore),
+			Override(new(*importmgr.Mgr), modules.ClientImportMgr),
+			Override(new(*dealmeta.Store), modules.ClientDealMeta),
 			Override(new(dtypes.ClientDataTransfer), modules.NewClientDAGServiceDataTransfer),
 			Override(new(*deals.ClientRequestValidator), modules.NewClientRequestValidator),
 			Override(new(storagemarket.StorageClient), modules.StorageClient),
@@ -252,6 +261,7 @@ func Online() Option {
 			Override(new(sectorbuilder.Interface), modules.SectorBuilder),
 			Override(new(*sectorblocks.SectorBlocks), sectorblocks.NewSectorBlocks),
 			Override(new(sealing.TicketFn), modules.SealTicketGen),
+			Override(new(*storage.FPoStScheduler), modules.FPoStScheduler),
 			Override(new(*storage.Miner), modules.StorageMiner),
 
 			Override(new(dtypes.StagingBlockstore), modules.StagingBlockstore),
@@ -332,6 +342,13 @@ func ConfigFullNode(c interface{}) Option {
 
 	return Options(
 		ConfigCommon(&cfg.Common),
+		Override(new(*messagepool.Config), modules.MpoolConfig(cfg.Mpool)),
+		If(cfg.Index.EnableMsgIndex,
+			Override(SetupMsgIndexKey, modules.SetupMsgIndex),
+		),
+		If(cfg.Pruning.Enable,
+			Override(RunChainPrunerKey, modules.RunChainPruner(cfg.Pruning)),
+		),
 		If(cfg.Metrics.HeadNotifs,
 			Override(HeadMetricsKey, metrics.SendHeadNotifs(cfg.Metrics.Nickname)),
 		),
@@ -364,6 +381,25 @@ func ConfigStorageMiner(c interface{}, lr repo.LockedRepo) Option {
 			cfg.SectorBuilder.WorkerCount,
 			cfg.SectorBuilder.DisableLocalPreCommit,
 			cfg.SectorBuilder.DisableLocalCommit)),
+		Override(new(*sched.Scheduler), modules.Scheduler(cfg.SectorBuilder.MaxTransfers)),
+		Override(new(*stores.Manager), modules.StorageManager),
+
+		Override(new(sealing.Config), sealing.Config{
+			WaitDealsDelay:          time.Duration(cfg.Sealing.WaitDealsDelay),
+			StartEpochSealingBuffer: cfg.Sealing.StartEpochSealingBuffer,
+		}),
+		Override(new(storage.ProvingConfig), storage.ProvingConfig{
+			StartConfidence: cfg.Proving.StartConfidence,
+
+			PoStGasLimit:  types.NewInt(cfg.Proving.PoStGasLimit),
+			PoStGasPrice:  types.NewInt(cfg.Proving.PoStGasPrice),
+			FaultGasLimit: types.NewInt(cfg.Proving.FaultGasLimit),
+			FaultGasPrice: types.NewInt(cfg.Proving.FaultGasPrice),
+
+			DeadlineMargin:  cfg.Proving.DeadlineMargin,
+			GasEscalation:   cfg.Proving.GasEscalation,
+			MaxPoStGasPrice: types.NewInt(cfg.Proving.MaxPoStGasPrice),
+		}),
 	)
 }
 
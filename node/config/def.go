@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/filecoin-project/go-sectorbuilder/fs"
+
+	"github.com/filecoin-project/lotus/build"
 )
 
 // Common is common config between full node and miner
@@ -17,6 +19,9 @@ type Common struct {
 type FullNode struct {
 	Common
 	Metrics Metrics
+	Mpool   Mpool
+	Index   Index
+	Pruning Pruning
 }
 
 // // Common
@@ -26,6 +31,8 @@ type StorageMiner struct {
 	Common
 
 	SectorBuilder SectorBuilder
+	Sealing       Sealing
+	Proving       Proving
 }
 
 // API contains configs for API endpoint
@@ -53,15 +60,80 @@ type Metrics struct {
 	PubsubTracing bool
 }
 
+type Mpool struct {
+	// ReplaceByFeeRatio is the minimum GasPrice increase, in percent, needed
+	// for a message to replace a pending message with the same nonce
+	ReplaceByFeeRatio uint64
+
+	// SizeLimit is the maximum number of pending messages in the mpool
+	SizeLimit int
+	// SenderPendingLimit is the maximum number of pending messages accepted
+	// from a single remote sender
+	SenderPendingLimit int
+}
+
+type Index struct {
+	// EnableMsgIndex keeps an index of the tipsets messages were executed in,
+	// making message lookups independent of chain length
+	EnableMsgIndex bool
+}
+
+type Pruning struct {
+	// Enable runs chain blockstore garbage collection every Interval
+	Enable   bool
+	Interval Duration
+	// RecentStateRoots is the number of most recent tipsets whose state
+	// trees are kept
+	RecentStateRoots uint64
+}
+
 // // Storage Miner
 
 type SectorBuilder struct {
-	Path        string // TODO: remove // FORK (-ish)
+	Path string // TODO: remove // FORK (-ish)
+	// Storage paths, paths attached and detached with `storage` commands
+	// are applied on top of these
 	Storage     []fs.PathConfig
 	WorkerCount uint
 
 	DisableLocalPreCommit bool
 	DisableLocalCommit    bool
+
+	// MaxTransfers limits concurrent sector data transfers to and from
+	// remote workers
+	MaxTransfers uint
+}
+
+type Sealing struct {
+	// WaitDealsDelay is how long a sector accepts new deals before it's sealed
+	WaitDealsDelay Duration
+	// StartEpochSealingBuffer is how many epochs before the earliest deal
+	// proposal expiration in a sector sealing is started
+	StartEpochSealingBuffer uint64
+}
+
+type Proving struct {
+	// StartConfidence is the number of epochs to wait after the fallback
+	// PoSt challenge epoch before generating the proof, so that the
+	// challenge is unlikely to be reverted
+	StartConfidence uint64
+
+	// Gas for SubmitFallbackPoSt messages, prices are in attoFIL per unit of
+	// gas. A zero PoStGasPrice uses a price estimated from recent messages.
+	PoStGasLimit uint64
+	PoStGasPrice uint64
+	// Gas for DeclareFaults messages
+	FaultGasLimit uint64
+	FaultGasPrice uint64
+
+	// DeadlineMargin is the number of epochs before the end of the proving
+	// period from which the gas price of a PoSt message that hasn't landed
+	// is raised by GasEscalation percent each epoch, up to MaxPoStGasPrice
+	// (zero for no limit). GasEscalation has to be at least
+	// Mpool.ReplaceByFeeRatio of the full node.
+	DeadlineMargin  uint64
+	GasEscalation   uint64
+	MaxPoStGasPrice uint64
 }
 
 func defCommon() Common {
@@ -88,6 +160,15 @@ func defCommon() Common {
 func DefaultFullNode() *FullNode {
 	return &FullNode{
 		Common: defCommon(),
+		Mpool: Mpool{
+			ReplaceByFeeRatio:  25,
+			SizeLimit:          5000,
+			SenderPendingLimit: 100,
+		},
+		Pruning: Pruning{
+			Interval:         Duration(24 * time.Hour),
+			RecentStateRoots: 2 * build.Finality,
+		},
 	}
 }
 
@@ -96,7 +177,23 @@ func DefaultStorageMiner() *StorageMiner {
 		Common: defCommon(),
 
 		SectorBuilder: SectorBuilder{
-			WorkerCount: 2,
+			WorkerCount:  2,
+			MaxTransfers: 2,
+		},
+		Sealing: Sealing{
+			WaitDealsDelay:          Duration(6 * time.Hour),
+			StartEpochSealingBuffer: 480,
+		},
+		Proving: Proving{
+			StartConfidence: 4,
+
+			PoStGasLimit:  10000000,
+			PoStGasPrice:  1,
+			FaultGasLimit: 10000000,
+			FaultGasPrice: 1,
+
+			DeadlineMargin: 20,
+			GasEscalation:  25,
 		},
 	}
 	cfg.Common.API.ListenAddress = "/ip4/127.0.0.1/tcp/2345/http"
@@ -3,24 +3,33 @@ package client
 import (
 	"bytes"
 	"context"
-	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
 	"os"
+	"sort"
+	"sync"
+	"time"
 
 	"golang.org/x/xerrors"
 
 	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-car"
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-filestore"
 	chunker "github.com/ipfs/go-ipfs-chunker"
 	offline "github.com/ipfs/go-ipfs-exchange-offline"
 	files "github.com/ipfs/go-ipfs-files"
 	ipld "github.com/ipfs/go-ipld-format"
+	logging "github.com/ipfs/go-log/v2"
 	"github.com/ipfs/go-merkledag"
+	"github.com/ipfs/go-path"
+	"github.com/ipfs/go-path/resolver"
 	unixfile "github.com/ipfs/go-unixfs/file"
 	"github.com/ipfs/go-unixfs/importer/balanced"
 	ihelper "github.com/ipfs/go-unixfs/importer/helpers"
+	uio "github.com/ipfs/go-unixfs/io"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"go.uber.org/fx"
 
@@ -36,8 +45,13 @@ import (
 	"github.com/filecoin-project/lotus/node/impl/full"
 	"github.com/filecoin-project/lotus/node/impl/paych"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
+	"github.com/filecoin-project/lotus/node/repo/dealmeta"
+	"github.com/filecoin-project/lotus/node/repo/importmgr"
+	"github.com/filecoin-project/lotus/peermgr"
 )
 
+var log = logging.Logger("client")
+
 type API struct {
 	fx.In
 
@@ -54,6 +68,10 @@ type API struct {
 	LocalDAG   dtypes.ClientDAG
 	Blockstore dtypes.ClientBlockstore
 	Filestore  dtypes.ClientFilestore `optional:"true"`
+	Imports    *importmgr.Mgr
+	DealMeta   *dealmeta.Store
+
+	PeerMgr *peermgr.PeerMgr `optional:"true"`
 }
 
 func (a *API) ClientStartDeal(ctx context.Context, data cid.Cid, addr address.Address, miner address.Address, epochPrice types.BigInt, blocksDuration uint64) (*cid.Cid, error) {
@@ -89,30 +107,42 @@ func (a *API) ClientStartDeal(ctx context.Context, data cid.Cid, addr address.Ad
 		return nil, xerrors.Errorf("failed to start deal: %w", err)
 	}
 
+	if err := a.DealMeta.Created(result.ProposalCid, time.Now()); err != nil {
+		log.Errorf("recording creation of deal %s: %+v", result.ProposalCid, err)
+	}
+	if err := a.Imports.AddDeal(data, result.ProposalCid); err != nil {
+		log.Errorf("recording deal %s for import %s: %+v", result.ProposalCid, data, err)
+	}
+
 	return &result.ProposalCid, nil
 }
 
-func (a *API) ClientListDeals(ctx context.Context) ([]api.DealInfo, error) {
+func (a *API) ClientListDeals(ctx context.Context, filter api.DealFilter) ([]api.DealInfo, error) {
 	deals, err := a.SMDealClient.ListInProgressDeals(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	out := make([]api.DealInfo, len(deals))
-	for k, v := range deals {
-		out[k] = api.DealInfo{
-			ProposalCid: v.ProposalCid,
-			State:       v.State,
-			Provider:    v.Proposal.Provider,
-
-			PieceRef: v.Proposal.PieceRef,
-			Size:     v.Proposal.PieceSize,
+	out := make([]api.DealInfo, 0, len(deals))
+	for _, v := range deals {
+		if !matchDeal(filter, v) {
+			continue
+		}
 
-			PricePerEpoch: utils.FromSharedTokenAmount(v.Proposal.StoragePricePerEpoch),
-			Duration:      v.Proposal.Duration,
+		di, err := a.localDealInfo(v)
+		if err != nil {
+			return nil, err
+		}
 
-			DealID: v.DealID,
+		if !filter.CreatedAfter.IsZero() && !di.CreationTime.After(filter.CreatedAfter) {
+			continue
+		}
+		if !filter.CreatedBefore.IsZero() && !di.CreationTime.Before(filter.CreatedBefore) {
+			continue
 		}
+
+		a.addChainDealInfo(ctx, &di)
+		out = append(out, di)
 	}
 
 	return out, nil
@@ -123,16 +153,205 @@ func (a *API) ClientGetDealInfo(ctx context.Context, d cid.Cid) (*api.DealInfo,
 	if err != nil {
 		return nil, err
 	}
-	return &api.DealInfo{
-		ProposalCid:   v.ProposalCid,
-		State:         v.State,
-		Provider:      v.Proposal.Provider,
-		PieceRef:      v.Proposal.PieceRef,
-		Size:          v.Proposal.PieceSize,
+
+	di, err := a.localDealInfo(v)
+	if err != nil {
+		return nil, err
+	}
+
+	a.addChainDealInfo(ctx, &di)
+	return &di, nil
+}
+
+func (a *API) ClientDealNotify(ctx context.Context) (<-chan api.DealInfo, error) {
+	// deals created before the last listing are either known, or were sent
+	// when they became final
+	lastList := time.Now()
+	changes := a.DealMeta.Subscribe(ctx)
+	heads := a.Chain.SubHeadChanges(ctx)
+
+	deals, err := a.ClientListDeals(ctx, api.DealFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan api.DealInfo, len(deals))
+	// deals which can still change, final deals are dropped once sent
+	known := map[cid.Cid]api.DealInfo{}
+	for _, di := range deals {
+		if !dealFinal(di) {
+			known[di.ProposalCid] = di
+		}
+		out <- di
+	}
+
+	send := func(di api.DealInfo) bool {
+		if dealFinal(di) {
+			delete(known, di.ProposalCid)
+		} else {
+			known[di.ProposalCid] = di
+		}
+
+		select {
+		case out <- di:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(out)
+
+		for {
+			select {
+			case <-changes:
+				// a deal state in the storage market, or deal metadata changed
+				listed := time.Now()
+				deals, err := a.SMDealClient.ListInProgressDeals(ctx)
+				if err != nil {
+					log.Errorf("listing deals for notifications: %+v", err)
+					continue
+				}
+
+				for _, v := range deals {
+					di, err := a.localDealInfo(v)
+					if err != nil {
+						log.Errorf("getting deal info for notifications: %+v", err)
+						continue
+					}
+
+					prev, ok := known[di.ProposalCid]
+					if !ok && di.CreationTime.Before(lastList) {
+						continue
+					}
+
+					if ok {
+						// chain state is updated on head changes
+						di.ActivationEpoch = prev.ActivationEpoch
+						di.Expiration = prev.Expiration
+
+						if !dealChanged(prev, di) {
+							continue
+						}
+					} else {
+						a.addChainDealInfo(ctx, &di)
+					}
+
+					if !send(di) {
+						return
+					}
+				}
+				lastList = listed
+			case _, ok := <-heads:
+				if !ok {
+					return
+				}
+
+				// published deals are activated on chain
+				for _, prev := range known {
+					if prev.DealID == 0 || prev.ActivationEpoch != 0 {
+						continue
+					}
+
+					di := prev
+					a.addChainDealInfo(ctx, &di)
+					if !dealChanged(prev, di) {
+						continue
+					}
+
+					if !send(di) {
+						return
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// localDealInfo builds deal info from the storage market and deal metadata
+func (a *API) localDealInfo(v storagemarket.ClientDeal) (api.DealInfo, error) {
+	di := api.DealInfo{
+		ProposalCid: v.ProposalCid,
+		State:       v.State,
+		Provider:    v.Proposal.Provider,
+
+		PieceRef: v.Proposal.PieceRef,
+		Size:     v.Proposal.PieceSize,
+
 		PricePerEpoch: utils.FromSharedTokenAmount(v.Proposal.StoragePricePerEpoch),
 		Duration:      v.Proposal.Duration,
-		DealID:        v.DealID,
-	}, nil
+
+		DealID: v.DealID,
+	}
+
+	meta, err := a.DealMeta.Get(v.ProposalCid, v.DealID)
+	if err != nil {
+		return api.DealInfo{}, xerrors.Errorf("getting metadata of deal %s: %w", v.ProposalCid, err)
+	}
+	di.CreationTime = meta.Created
+	di.SectorID = meta.SectorID
+	di.Message = meta.Err
+
+	return di, nil
+}
+
+// addChainDealInfo adds the activation of published deals from chain state
+func (a *API) addChainDealInfo(ctx context.Context, di *api.DealInfo) {
+	if di.DealID == 0 {
+		return
+	}
+
+	ocd, err := a.StateMarketStorageDeal(ctx, di.DealID, types.EmptyTSK)
+	if err != nil {
+		log.Warnf("getting on-chain deal %d: %+v", di.DealID, err)
+		return
+	}
+
+	if ocd.ActivationEpoch != 0 {
+		di.ActivationEpoch = ocd.ActivationEpoch
+		di.Expiration = ocd.ActivationEpoch + ocd.Duration
+	}
+}
+
+// matchDeal checks the filter fields known from the storage market
+func matchDeal(filter api.DealFilter, v storagemarket.ClientDeal) bool {
+	if filter.Provider != address.Undef && filter.Provider != v.Proposal.Provider {
+		return false
+	}
+
+	if len(filter.States) == 0 {
+		return true
+	}
+	for _, st := range filter.States {
+		if st == v.State {
+			return true
+		}
+	}
+	return false
+}
+
+// dealChanged compares the deal info which can change over the lifetime of
+// a deal
+func dealChanged(prev, cur api.DealInfo) bool {
+	return prev.State != cur.State ||
+		prev.DealID != cur.DealID ||
+		prev.SectorID != cur.SectorID ||
+		prev.Message != cur.Message ||
+		prev.ActivationEpoch != cur.ActivationEpoch
+}
+
+// dealFinal checks whether the deal info won't change anymore
+func dealFinal(di api.DealInfo) bool {
+	switch di.State {
+	case storagemarket.DealRejected, storagemarket.DealFailed, storagemarket.DealError, storagemarket.DealComplete:
+		return true
+	}
+	return di.SectorID != 0 && di.ActivationEpoch != 0
 }
 
 func (a *API) ClientHasLocal(ctx context.Context, root cid.Cid) (bool, error) {
@@ -174,16 +393,45 @@ func (a *API) ClientFindData(ctx context.Context, root cid.Cid) ([]api.QueryOffe
 	return out, nil
 }
 
-func (a *API) ClientImport(ctx context.Context, path string) (cid.Cid, error) {
-	f, err := os.Open(path)
+func (a *API) ClientImport(ctx context.Context, ref api.FileRef) (cid.Cid, error) {
+	f, err := os.Open(ref.Path)
 	if err != nil {
 		return cid.Undef, err
 	}
+	defer f.Close()
+
 	stat, err := f.Stat()
 	if err != nil {
 		return cid.Undef, err
 	}
 
+	var root cid.Cid
+	if ref.IsCAR {
+		root, err = a.importCAR(f)
+	} else {
+		root, err = a.importFile(ctx, ref.Path, f, stat)
+	}
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	err = a.Imports.Add(importmgr.Import{
+		Root:     root,
+		FilePath: ref.Path,
+		Size:     uint64(stat.Size()),
+		CAR:      ref.IsCAR,
+		Imported: time.Now(),
+	})
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("recording import: %w", err)
+	}
+
+	return root, nil
+}
+
+// importFile adds a file to the filestore, referencing its data instead of
+// copying it
+func (a *API) importFile(ctx context.Context, path string, f *os.File, stat os.FileInfo) (cid.Cid, error) {
 	file, err := files.NewReaderPathFile(path, f, stat)
 	if err != nil {
 		return cid.Undef, err
@@ -215,8 +463,23 @@ func (a *API) ClientImport(ctx context.Context, path string) (cid.Cid, error) {
 	return nd.Cid(), nil
 }
 
+// importCAR copies the blocks of a CAR file into the client blockstore
+func (a *API) importCAR(r io.Reader) (cid.Cid, error) {
+	header, err := car.LoadCar(a.Blockstore, r)
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("loading CAR: %w", err)
+	}
+
+	if len(header.Roots) != 1 {
+		return cid.Undef, xerrors.Errorf("expected a CAR file with a single root, got %d roots", len(header.Roots))
+	}
+
+	return header.Roots[0], nil
+}
+
 func (a *API) ClientImportLocal(ctx context.Context, f io.Reader) (cid.Cid, error) {
-	file := files.NewReaderFile(f)
+	cr := &countReader{r: f}
+	file := files.NewReaderFile(cr)
 
 	bufferedDS := ipld.NewBufferedDAG(ctx, a.LocalDAG)
 
@@ -236,62 +499,265 @@ func (a *API) ClientImportLocal(ctx context.Context, f io.Reader) (cid.Cid, erro
 		return cid.Undef, err
 	}
 
-	return nd.Cid(), bufferedDS.Commit()
+	if err := bufferedDS.Commit(); err != nil {
+		return cid.Undef, err
+	}
+
+	err = a.Imports.Add(importmgr.Import{
+		Root:     nd.Cid(),
+		Size:     cr.n,
+		Imported: time.Now(),
+	})
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("recording import: %w", err)
+	}
+
+	return nd.Cid(), nil
 }
 
 func (a *API) ClientListImports(ctx context.Context) ([]api.Import, error) {
-	if a.Filestore == nil {
-		return nil, errors.New("listing imports is not supported with in-memory dag yet")
-	}
-	next, err := filestore.ListAll(a.Filestore, false)
+	imports, err := a.Imports.List()
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO: make this less very bad by tracking root cids instead of using ListAll
+	out := make([]api.Import, len(imports))
+	for i, imp := range imports {
+		out[i] = api.Import{
+			Status:   importStatus(imp),
+			Key:      imp.Root,
+			FilePath: imp.FilePath,
+			Size:     imp.Size,
 
-	out := make([]api.Import, 0)
-	for {
-		r := next()
-		if r == nil {
-			return out, nil
+			CAR:      imp.CAR,
+			Imported: imp.Imported,
+			Deals:    imp.Deals,
+		}
+	}
+
+	return out, nil
+}
+
+func (a *API) ClientRemoveImport(ctx context.Context, root cid.Cid) error {
+	imp, err := a.Imports.Get(root)
+	if err != nil {
+		return xerrors.Errorf("finding import: %w", err)
+	}
+
+	for _, d := range imp.Deals {
+		deal, err := a.SMDealClient.GetInProgressDeal(ctx, d)
+		if err != nil {
+			return xerrors.Errorf("getting deal %s: %w", d, err)
 		}
-		if r.Offset != 0 {
+
+		if deal.State == api.DealUnknown || deal.State == api.DealAccepted {
+			return xerrors.Errorf("data of deal %s may still be transferred", d)
+		}
+	}
+
+	imports, err := a.Imports.List()
+	if err != nil {
+		return err
+	}
+
+	// blocks shared with other imports are kept
+	keep := cid.NewSet()
+	for _, other := range imports {
+		if other.Root.Equals(root) {
 			continue
 		}
-		out = append(out, api.Import{
-			Status:   r.Status,
-			Key:      r.Key,
-			FilePath: r.FilePath,
-			Size:     r.Size,
-		})
+
+		if err := a.walkLocal(ctx, other.Root, keep.Visit); err != nil {
+			return xerrors.Errorf("listing blocks of import %s: %w", other.Root, err)
+		}
+	}
+
+	remove := cid.NewSet()
+	if err := a.walkLocal(ctx, root, remove.Visit); err != nil {
+		return xerrors.Errorf("listing blocks of import %s: %w", root, err)
+	}
+
+	err = remove.ForEach(func(c cid.Cid) error {
+		if keep.Has(c) {
+			return nil
+		}
+		return a.Blockstore.DeleteBlock(c)
+	})
+	if err != nil {
+		return xerrors.Errorf("removing blocks: %w", err)
+	}
+
+	return a.Imports.Remove(root)
+}
+
+// walkLocal visits the blocks of a DAG in the client blockstore. Raw leaves
+// aren't read, as they may be backed by large files in the filestore.
+func (a *API) walkLocal(ctx context.Context, root cid.Cid, visit func(cid.Cid) bool) error {
+	dag := merkledag.NewDAGService(blockservice.New(a.Blockstore, offline.Exchange(a.Blockstore)))
+	getLinks := func(ctx context.Context, c cid.Cid) ([]*ipld.Link, error) {
+		if c.Type() == cid.Raw {
+			return nil, nil
+		}
+		return merkledag.GetLinksDirect(dag)(ctx, c)
+	}
+
+	return merkledag.Walk(ctx, getLinks, root, visit)
+}
+
+// importStatus checks that the file referenced by an import is unchanged
+func importStatus(imp importmgr.Import) filestore.Status {
+	if imp.CAR || imp.FilePath == "" {
+		return filestore.StatusOk // blocks were copied
+	}
+
+	fi, err := os.Stat(imp.FilePath)
+	switch {
+	case os.IsNotExist(err):
+		return filestore.StatusFileNotFound
+	case err != nil:
+		return filestore.StatusFileError
+	case uint64(fi.Size()) != imp.Size:
+		return filestore.StatusFileChanged
+	}
+	return filestore.StatusOk
+}
+
+type countReader struct {
+	r io.Reader
+	n uint64
+}
+
+func (cr *countReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += uint64(n)
+	return n, err
+}
+
+func (a *API) ClientRetrieve(ctx context.Context, order api.RetrievalOrder, ref api.FileRef) error {
+	events, err := a.ClientRetrieveWithEvents(ctx, order, ref)
+	if err != nil {
+		return err
+	}
+
+	for evt := range events {
+		if evt.Err != "" {
+			return xerrors.Errorf("RetrieveUnixfs: %s", evt.Err)
+		}
+		if evt.Event == retrievalmarket.ClientEventComplete {
+			return nil
+		}
 	}
+
+	return xerrors.New("Retrieval Timed Out")
 }
 
-func (a *API) ClientRetrieve(ctx context.Context, order api.RetrievalOrder, path string) error {
+func (a *API) ClientRetrieveWithEvents(ctx context.Context, order api.RetrievalOrder, ref api.FileRef) (<-chan api.RetrievalEvent, error) {
+	if ref.IsCAR && (order.Offset != 0 || order.Length != 0) {
+		return nil, xerrors.New("a byte range can't be written as a CAR file")
+	}
+
 	if order.MinerPeerID == "" {
 		pid, err := a.StateMinerPeerID(ctx, order.Miner, types.EmptyTSK)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		order.MinerPeerID = pid
 	}
 
-	retrievalResult := make(chan error, 1)
+	// market callbacks must not block the event dispatch, events are queued,
+	// and consecutive progress events are coalesced
+	var lk sync.Mutex
+	var pending []api.RetrievalEvent
+	wake := make(chan struct{}, 1)
 
 	unsubscribe := a.Retrieval.SubscribeToEvents(func(event retrievalmarket.ClientEvent, state retrievalmarket.ClientDealState) {
-		if bytes.Equal(state.PieceCID, order.Root.Bytes()) {
-			switch event {
-			case retrievalmarket.ClientEventError:
-				retrievalResult <- xerrors.New("Retrieval Error")
-			case retrievalmarket.ClientEventComplete:
-				retrievalResult <- nil
-			}
+		if !bytes.Equal(state.PieceCID, order.Root.Bytes()) {
+			return
+		}
+
+		evt := api.RetrievalEvent{
+			Event:         event,
+			Status:        state.Status,
+			DealID:        state.ID,
+			Miner:         order.Miner,
+			Size:          order.Size,
+			BytesReceived: state.TotalReceived,
+			FundsSpent:    utils.FromSharedTokenAmount(state.FundsSpent),
+		}
+		if event == retrievalmarket.ClientEventError {
+			// the market client doesn't pass the cause of a failure to
+			// subscribers, describe it with what the deal state tells
+			evt.Err = fmt.Sprintf("retrieval deal %d with %s failed after receiving %d bytes", state.ID, state.Sender, state.TotalReceived)
+		}
+
+		lk.Lock()
+		if n := len(pending); n > 0 && pending[n-1].DealID == evt.DealID && !finalEvent(pending[n-1]) && !finalEvent(evt) {
+			pending[n-1] = evt
+		} else {
+			pending = append(pending, evt)
+		}
+		lk.Unlock()
+
+		select {
+		case wake <- struct{}{}:
+		default:
 		}
 	})
 
-	a.Retrieval.Retrieve(
+	// events may arrive before Retrieve returns the deal ID
+	dealIDs := make(chan retrievalmarket.DealID, 1)
+
+	out := make(chan api.RetrievalEvent, 16)
+	go func() {
+		defer close(out)
+		defer unsubscribe()
+
+		var dealID retrievalmarket.DealID
+		select {
+		case dealID = <-dealIDs:
+		case <-ctx.Done():
+			return
+		}
+
+		for {
+			lk.Lock()
+			evts := pending
+			pending = nil
+			lk.Unlock()
+
+			for _, evt := range evts {
+				if evt.DealID != dealID {
+					// another retrieval of the same root
+					continue
+				}
+
+				if evt.Event == retrievalmarket.ClientEventComplete {
+					if err := a.writeRetrieved(ctx, order, ref); err != nil {
+						evt.Err = err.Error()
+					}
+				}
+
+				select {
+				case out <- evt:
+				case <-ctx.Done():
+					return
+				}
+
+				if finalEvent(evt) {
+					return
+				}
+			}
+
+			select {
+			case <-wake:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	dealIDs <- a.Retrieval.Retrieve(
 		ctx,
 		order.Root.Bytes(),
 		retrievalmarket.NewParamsV0(types.BigDiv(order.Total, types.NewInt(order.Size)).Int, 0, 0),
@@ -299,26 +765,288 @@ func (a *API) ClientRetrieve(ctx context.Context, order api.RetrievalOrder, path
 		order.MinerPeerID,
 		order.Client,
 		order.Miner)
+
+	return out, nil
+}
+
+func (a *API) ClientRetrieveAuto(ctx context.Context, params api.AutoRetrievalParams, ref api.FileRef) (<-chan api.RetrievalEvent, error) {
+	if ref.IsCAR && (params.Offset != 0 || params.Length != 0) {
+		return nil, xerrors.New("a byte range can't be written as a CAR file")
+	}
+	if params.MaxPrice.Nil() {
+		return nil, xerrors.New("a price cap is required")
+	}
+
+	found, err := a.ClientFindData(ctx, params.Root)
+	if err != nil {
+		return nil, xerrors.Errorf("finding offers: %w", err)
+	}
+
+	offers := rankOffers(found, a.peerLatency)
+	if len(offers) == 0 {
+		return nil, xerrors.Errorf("no retrieval offers for %s", params.Root)
+	}
+
+	retrieve := func(ctx context.Context, order api.RetrievalOrder) (<-chan api.RetrievalEvent, error) {
+		return a.ClientRetrieveWithEvents(ctx, order, ref)
+	}
+
+	out := make(chan api.RetrievalEvent, 16)
+	go func() {
+		defer close(out)
+		autoRetrieve(ctx, offers, params, retrieve, out)
+	}()
+
+	return out, nil
+}
+
+// retrieveFunc starts a retrieval like ClientRetrieveWithEvents
+type retrieveFunc func(ctx context.Context, order api.RetrievalOrder) (<-chan api.RetrievalEvent, error)
+
+// autoRetrieve tries ranked offers until a retrieval completes. A failed
+// attempt counts against the price cap with the funds it spent. An abandoned
+// attempt is cancelled, which stops its payments, but counts with all the
+// funds of its deal, as the market client doesn't report what it paid.
+func autoRetrieve(ctx context.Context, offers []api.QueryOffer, params api.AutoRetrievalParams, retrieve retrieveFunc, out chan<- api.RetrievalEvent) {
+	charged := types.NewInt(0)
+	for _, offer := range offers {
+		if types.BigAdd(charged, offer.MinPrice).GreaterThan(params.MaxPrice) {
+			log.Infof("skipping retrieval offer from %s: price %s exceeds the remaining funds", offer.Miner, types.FIL(offer.MinPrice))
+			continue
+		}
+
+		order := offer.Order(params.Client)
+		order.Path = params.Path
+		order.Offset = params.Offset
+		order.Length = params.Length
+
+		last, abandoned := retrieveFrom(ctx, order, retrieve, params.StallTimeout, out)
+		if last.Event == retrievalmarket.ClientEventComplete && last.Err == "" {
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		if abandoned {
+			charged = types.BigAdd(charged, order.Total)
+		} else {
+			charged = types.BigAdd(charged, last.FundsSpent)
+		}
+		log.Warnf("retrieval of %s from %s failed: %s", params.Root, offer.Miner, last.Err)
+	}
+
 	select {
+	case out <- api.RetrievalEvent{Event: retrievalmarket.ClientEventError, FundsSpent: charged, Err: "no provider left to retrieve from within the price cap"}:
 	case <-ctx.Done():
-		return xerrors.New("Retrieval Timed Out")
-	case err := <-retrievalResult:
-		if err != nil {
-			return xerrors.Errorf("RetrieveUnixfs: %w", err)
+	}
+}
+
+// retrieveFrom runs a retrieval from the provider of the order, forwarding its
+// events to out. It abandons the retrieval when no event arrives for
+// stallTimeout, and returns the last event of the retrieval. The context of
+// the retrieval is cancelled on return, so no more payments are made for it.
+func retrieveFrom(ctx context.Context, order api.RetrievalOrder, retrieve retrieveFunc, stallTimeout time.Duration, out chan<- api.RetrievalEvent) (last api.RetrievalEvent, abandoned bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	last = api.RetrievalEvent{
+		Miner:      order.Miner,
+		Size:       order.Size,
+		FundsSpent: types.NewInt(0),
+	}
+	send := func(evt api.RetrievalEvent) api.RetrievalEvent {
+		select {
+		case out <- evt:
+		case <-ctx.Done():
 		}
+		return evt
 	}
 
-	unsubscribe()
+	events, err := retrieve(ctx, order)
+	if err != nil {
+		last.Event = retrievalmarket.ClientEventError
+		last.Err = err.Error()
+		return send(last), false
+	}
+
+	var stall <-chan time.Time
+	var timer *time.Timer
+	if stallTimeout > 0 {
+		timer = time.NewTimer(stallTimeout)
+		defer timer.Stop()
+		stall = timer.C
+	}
+
+	for {
+		select {
+		case evt, ok := <-events:
+			if !ok {
+				return last, false
+			}
+
+			last = send(evt)
+			if finalEvent(evt) {
+				return last, false
+			}
+
+			if timer != nil {
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(stallTimeout)
+			}
+		case <-stall:
+			last.Event = retrievalmarket.ClientEventError
+			last.Err = fmt.Sprintf("no progress from %s for %s", order.Miner, stallTimeout)
+			return send(last), true
+		}
+	}
+}
+
+// rankOffers orders usable offers from the cheapest, then lowest latency, then
+// smallest one. Offers from peers with unknown latency go after measured ones.
+func rankOffers(offers []api.QueryOffer, latency func(peer.ID) (time.Duration, bool)) []api.QueryOffer {
+	type ranked struct {
+		api.QueryOffer
+		latency time.Duration
+		known   bool
+	}
+
+	var rs []ranked
+	for _, o := range offers {
+		if o.Err != "" {
+			continue
+		}
+
+		l, ok := latency(o.MinerPeerID)
+		rs = append(rs, ranked{QueryOffer: o, latency: l, known: ok})
+	}
+
+	sort.SliceStable(rs, func(i, j int) bool {
+		if c := types.BigCmp(rs[i].MinPrice, rs[j].MinPrice); c != 0 {
+			return c < 0
+		}
+		if rs[i].known != rs[j].known {
+			return rs[i].known
+		}
+		if rs[i].latency != rs[j].latency {
+			return rs[i].latency < rs[j].latency
+		}
+		return rs[i].Size < rs[j].Size
+	})
+
+	out := make([]api.QueryOffer, len(rs))
+	for i, r := range rs {
+		out[i] = r.QueryOffer
+	}
+	return out
+}
+
+func (a *API) peerLatency(p peer.ID) (time.Duration, bool) {
+	if a.PeerMgr == nil {
+		return 0, false
+	}
+	return a.PeerMgr.GetPeerLatency(p)
+}
 
-	nd, err := a.LocalDAG.Get(ctx, order.Root)
+// finalEvent is true for the last event of a retrieval
+func finalEvent(evt api.RetrievalEvent) bool {
+	return evt.Err != "" || evt.Event == retrievalmarket.ClientEventComplete
+}
+
+// writeRetrieved writes the data selected by the order out of the retrieved
+// DAG. The retrieval protocol transfers the whole DAG under the order root.
+func (a *API) writeRetrieved(ctx context.Context, order api.RetrievalOrder, ref api.FileRef) error {
+	nd, err := a.resolveOrderPath(ctx, order)
 	if err != nil {
 		return xerrors.Errorf("ClientRetrieve: %w", err)
 	}
+
+	if ref.IsCAR {
+		return a.writeCAR(ctx, nd.Cid(), ref.Path)
+	}
+
 	file, err := unixfile.NewUnixfsFile(ctx, a.LocalDAG, nd)
 	if err != nil {
 		return xerrors.Errorf("ClientRetrieve: %w", err)
 	}
-	return files.WriteTo(file, path)
+
+	if order.Offset == 0 && order.Length == 0 {
+		return files.WriteTo(file, ref.Path)
+	}
+
+	f, ok := file.(files.File)
+	if !ok {
+		return xerrors.Errorf("ClientRetrieve: %q isn't a file, can't read a byte range", order.Path)
+	}
+	return writeRange(f, order.Offset, order.Length, ref.Path)
+}
+
+// resolveOrderPath finds the node selected by the order path in a unixfs DAG
+func (a *API) resolveOrderPath(ctx context.Context, order api.RetrievalOrder) (ipld.Node, error) {
+	if order.Path == "" {
+		return a.LocalDAG.Get(ctx, order.Root)
+	}
+
+	p, err := path.FromSegments("/ipfs/", order.Root.String(), order.Path)
+	if err != nil {
+		return nil, xerrors.Errorf("parsing path: %w", err)
+	}
+
+	r := &resolver.Resolver{
+		DAG:         a.LocalDAG,
+		ResolveOnce: uio.ResolveUnixfsOnce,
+	}
+
+	nd, err := r.ResolvePath(ctx, p)
+	if err != nil {
+		return nil, xerrors.Errorf("resolving %s: %w", p, err)
+	}
+	return nd, nil
+}
+
+func (a *API) writeCAR(ctx context.Context, root cid.Cid, outPath string) error {
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+
+	if err := car.WriteCar(ctx, a.LocalDAG, []cid.Cid{root}, f); err != nil {
+		_ = f.Close()
+		return xerrors.Errorf("writing CAR: %w", err)
+	}
+
+	return f.Close()
+}
+
+// writeRange writes length bytes of a file starting at offset, or the rest of
+// the file when length is 0
+func writeRange(f files.File, offset, length uint64, outPath string) error {
+	_, err := f.Seek(int64(offset), io.SeekStart)
+	if err == files.ErrNotSupported {
+		_, err = io.CopyN(ioutil.Discard, f, int64(offset))
+	}
+	if err != nil {
+		return xerrors.Errorf("seeking to %d: %w", offset, err)
+	}
+
+	var r io.Reader = f
+	if length != 0 {
+		r = io.LimitReader(f, int64(length))
+	}
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, r); err != nil {
+		_ = out.Close()
+		return xerrors.Errorf("writing range: %w", err)
+	}
+
+	return out.Close()
 }
 
 func (a *API) ClientQueryAsk(ctx context.Context, p peer.ID, miner address.Address) (*types.SignedStorageAsk, error) {
@@ -0,0 +1,303 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/ipfs/go-car"
+	"github.com/ipfs/go-datastore"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	files "github.com/ipfs/go-ipfs-files"
+	"github.com/ipfs/go-merkledag"
+	mdtest "github.com/ipfs/go-merkledag/test"
+	uio "github.com/ipfs/go-unixfs/io"
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func testOffer(t *testing.T, id uint64, price uint64) api.QueryOffer {
+	maddr, err := address.NewIDAddress(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return api.QueryOffer{
+		Size:        1024,
+		MinPrice:    types.NewInt(price),
+		Miner:       maddr,
+		MinerPeerID: peer.ID(maddr.String()),
+	}
+}
+
+func TestRankOffers(t *testing.T) {
+	cheap := testOffer(t, 100, 1)
+	fast := testOffer(t, 101, 2)
+	slow := testOffer(t, 102, 2)
+	unknown := testOffer(t, 103, 2)
+	small := testOffer(t, 104, 2)
+	small.Size = 512
+	failed := testOffer(t, 105, 0)
+	failed.Err = "query failed"
+
+	latencies := map[peer.ID]time.Duration{
+		fast.MinerPeerID:  time.Millisecond,
+		slow.MinerPeerID:  time.Second,
+		small.MinerPeerID: time.Second,
+	}
+	latency := func(p peer.ID) (time.Duration, bool) {
+		l, ok := latencies[p]
+		return l, ok
+	}
+
+	ranked := rankOffers([]api.QueryOffer{unknown, slow, failed, fast, cheap, small}, latency)
+
+	expect := []api.QueryOffer{cheap, fast, small, slow, unknown}
+	if len(ranked) != len(expect) {
+		t.Fatalf("expected %d offers, got %d", len(expect), len(ranked))
+	}
+	for i, o := range expect {
+		if ranked[i].Miner != o.Miner {
+			t.Errorf("offer %d: expected %s, got %s", i, o.Miner, ranked[i].Miner)
+		}
+	}
+}
+
+// fakeRetrieval serves retrievals from a script per miner
+type fakeRetrieval struct {
+	script   map[address.Address][]api.RetrievalEvent
+	attempts []address.Address
+}
+
+func (f *fakeRetrieval) retrieve(ctx context.Context, order api.RetrievalOrder) (<-chan api.RetrievalEvent, error) {
+	f.attempts = append(f.attempts, order.Miner)
+
+	// events aren't closed after the script, so a script without a final
+	// event stalls
+	out := make(chan api.RetrievalEvent, len(f.script[order.Miner]))
+	for _, evt := range f.script[order.Miner] {
+		evt.Miner = order.Miner
+		out <- evt
+	}
+	return out, nil
+}
+
+func runAuto(t *testing.T, f *fakeRetrieval, offers []api.QueryOffer, maxPrice uint64) []api.RetrievalEvent {
+	params := api.AutoRetrievalParams{
+		MaxPrice:     types.NewInt(maxPrice),
+		StallTimeout: 50 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out := make(chan api.RetrievalEvent, 64)
+	autoRetrieve(ctx, offers, params, f.retrieve, out)
+	close(out)
+
+	if ctx.Err() != nil {
+		t.Fatal("automatic retrieval didn't finish")
+	}
+
+	var evts []api.RetrievalEvent
+	for evt := range out {
+		evts = append(evts, evt)
+	}
+	return evts
+}
+
+func TestAutoRetrieveFailover(t *testing.T) {
+	failing, stalling, good := testOffer(t, 100, 1), testOffer(t, 101, 1), testOffer(t, 102, 1)
+
+	f := &fakeRetrieval{script: map[address.Address][]api.RetrievalEvent{
+		failing.Miner: {
+			{Event: retrievalmarket.ClientEventError, FundsSpent: types.NewInt(1), Err: "boom"},
+		},
+		stalling.Miner: {
+			{Event: retrievalmarket.ClientEventProgress, FundsSpent: types.NewInt(0), BytesReceived: 10},
+		},
+		good.Miner: {
+			{Event: retrievalmarket.ClientEventProgress, FundsSpent: types.NewInt(0), BytesReceived: 10},
+			{Event: retrievalmarket.ClientEventComplete, FundsSpent: types.NewInt(1), BytesReceived: 1024},
+		},
+	}}
+
+	evts := runAuto(t, f, []api.QueryOffer{failing, stalling, good}, 10)
+
+	if len(f.attempts) != 3 || f.attempts[0] != failing.Miner || f.attempts[1] != stalling.Miner || f.attempts[2] != good.Miner {
+		t.Fatalf("unexpected attempts: %v", f.attempts)
+	}
+
+	last := evts[len(evts)-1]
+	if last.Event != retrievalmarket.ClientEventComplete || last.Err != "" || last.Miner != good.Miner {
+		t.Fatalf("expected retrieval to complete from %s, got %+v", good.Miner, last)
+	}
+
+	var stalled bool
+	for _, evt := range evts {
+		if evt.Miner == stalling.Miner && evt.Err != "" {
+			stalled = true
+		}
+	}
+	if !stalled {
+		t.Fatal("expected a stall event from the stalling miner")
+	}
+}
+
+func TestAutoRetrievePriceCap(t *testing.T) {
+	stalling, next := testOffer(t, 100, 5), testOffer(t, 101, 6)
+
+	f := &fakeRetrieval{script: map[address.Address][]api.RetrievalEvent{
+		stalling.Miner: {
+			{Event: retrievalmarket.ClientEventFundsExpended, FundsSpent: types.NewInt(1), BytesReceived: 10},
+		},
+		next.Miner: {
+			{Event: retrievalmarket.ClientEventComplete, FundsSpent: types.NewInt(6), BytesReceived: 1024},
+		},
+	}}
+
+	// the stalled deal may spend all of its 5, leaving less than 6
+	evts := runAuto(t, f, []api.QueryOffer{stalling, next}, 10)
+
+	if len(f.attempts) != 1 || f.attempts[0] != stalling.Miner {
+		t.Fatalf("unexpected attempts: %v", f.attempts)
+	}
+
+	last := evts[len(evts)-1]
+	if last.Err == "" || !last.FundsSpent.Equals(types.NewInt(5)) {
+		t.Fatalf("expected failure with 5 charged, got %+v", last)
+	}
+}
+
+func TestWriteRange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lotus-client-range-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	for _, tc := range []struct {
+		offset, length uint64
+		expect         string
+	}{
+		{offset: 2, length: 3, expect: "234"},
+		{offset: 2, expect: "23456789"},
+		{offset: 8, length: 10, expect: "89"},
+	} {
+		out := filepath.Join(dir, "out")
+		if err := writeRange(files.NewBytesFile([]byte("0123456789")), tc.offset, tc.length, out); err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tc.expect {
+			t.Errorf("range %d:%d: expected %q, got %q", tc.offset, tc.length, tc.expect, b)
+		}
+	}
+}
+
+func TestWriteRetrievedPath(t *testing.T) {
+	ctx := context.Background()
+	dag := mdtest.Mock()
+
+	file := merkledag.NewRawNode([]byte("0123456789"))
+	if err := dag.Add(ctx, file); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := uio.NewDirectory(dag)
+	if err := sub.AddChild(ctx, "file.txt", file); err != nil {
+		t.Fatal(err)
+	}
+	subnd, err := sub.GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dag.Add(ctx, subnd); err != nil {
+		t.Fatal(err)
+	}
+
+	root := uio.NewDirectory(dag)
+	if err := root.AddChild(ctx, "sub", subnd); err != nil {
+		t.Fatal(err)
+	}
+	rootnd, err := root.GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dag.Add(ctx, rootnd); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &API{LocalDAG: dag}
+
+	nd, err := a.resolveOrderPath(ctx, api.RetrievalOrder{Root: rootnd.Cid(), Path: "sub/file.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nd.Cid() != file.Cid() {
+		t.Fatalf("resolved %s, expected %s", nd.Cid(), file.Cid())
+	}
+
+	if _, err := a.resolveOrderPath(ctx, api.RetrievalOrder{Root: rootnd.Cid(), Path: "sub/missing"}); err == nil {
+		t.Fatal("expected an error resolving a missing path")
+	}
+
+	dir, err := ioutil.TempDir("", "lotus-client-retrieved-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	order := api.RetrievalOrder{Root: rootnd.Cid(), Path: "sub/file.txt", Offset: 4, Length: 2}
+	out := filepath.Join(dir, "range")
+	if err := a.writeRetrieved(ctx, order, api.FileRef{Path: out}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "45" {
+		t.Fatalf("expected range \"45\", got %q", b)
+	}
+
+	// a directory can't be read as a range
+	order = api.RetrievalOrder{Root: rootnd.Cid(), Path: "sub", Length: 2}
+	if err := a.writeRetrieved(ctx, order, api.FileRef{Path: filepath.Join(dir, "dir")}); err == nil {
+		t.Fatal("expected an error reading a range of a directory")
+	}
+
+	order = api.RetrievalOrder{Root: rootnd.Cid(), Path: "sub"}
+	carPath := filepath.Join(dir, "sub.car")
+	if err := a.writeRetrieved(ctx, order, api.FileRef{Path: carPath, IsCAR: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	carBytes, err := ioutil.ReadFile(carPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs := blockstore.NewBlockstore(datastore.NewMapDatastore())
+	header, err := car.LoadCar(bs, bytes.NewReader(carBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(header.Roots) != 1 || header.Roots[0] != subnd.Cid() {
+		t.Fatalf("unexpected CAR roots: %v", header.Roots)
+	}
+	if has, err := bs.Has(file.Cid()); err != nil || !has {
+		t.Fatalf("expected the file in the CAR (err: %v)", err)
+	}
+}
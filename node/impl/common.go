@@ -2,6 +2,7 @@ package impl
 
 import (
 	"context"
+	"time"
 
 	"github.com/filecoin-project/lotus/node/modules/lp2p"
 
@@ -17,6 +18,7 @@ import (
 	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/apistruct"
 	"github.com/filecoin-project/lotus/build"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 )
@@ -31,6 +33,9 @@ type CommonAPI struct {
 
 type jwtPayload struct {
 	Allow []string
+
+	// Expiration is the unix time after which the token is rejected, if set
+	Expiration int64 `json:"exp,omitempty"`
 }
 
 func (a *CommonAPI) AuthVerify(ctx context.Context, token string) ([]api.Permission, error) {
@@ -39,12 +44,26 @@ func (a *CommonAPI) AuthVerify(ctx context.Context, token string) ([]api.Permiss
 		return nil, xerrors.Errorf("JWT Verification failed: %w", err)
 	}
 
+	if payload.Expiration != 0 && time.Now().Unix() > payload.Expiration {
+		return nil, xerrors.Errorf("token expired at %s", time.Unix(payload.Expiration, 0))
+	}
+
 	return payload.Allow, nil
 }
 
-func (a *CommonAPI) AuthNew(ctx context.Context, perms []api.Permission) ([]byte, error) {
+func (a *CommonAPI) AuthNew(ctx context.Context, perms []api.Permission, ttl time.Duration) ([]byte, error) {
+	for _, perm := range perms {
+		if err := apistruct.ValidPerm(perm); err != nil {
+			return nil, err
+		}
+	}
+
 	p := jwtPayload{
-		Allow: perms, // TODO: consider checking validity
+		Allow: perms,
+	}
+
+	if ttl != 0 {
+		p.Expiration = time.Now().Add(ttl).Unix()
 	}
 
 	return jwt.Sign(&p, (*jwt.HMACSHA)(a.APISecret))
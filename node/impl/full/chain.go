@@ -26,6 +26,8 @@ import (
 	"github.com/filecoin-project/go-address"
 
 	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/messagepool"
+	"github.com/filecoin-project/lotus/chain/stmgr"
 	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
 )
@@ -35,7 +37,9 @@ type ChainAPI struct {
 
 	WalletAPI
 
-	Chain *store.ChainStore
+	Chain        *store.ChainStore
+	StateManager *stmgr.StateManager
+	Mpool        *messagepool.MessagePool
 }
 
 func (a *ChainAPI) ChainNotify(ctx context.Context) (<-chan []*store.HeadChange, error) {
@@ -332,7 +336,7 @@ func (a *ChainAPI) ChainGetMessage(ctx context.Context, mc cid.Cid) (*types.Mess
 	return cm.VMMessage(), nil
 }
 
-func (a *ChainAPI) ChainExport(ctx context.Context, tsk types.TipSetKey) (<-chan []byte, error) {
+func (a *ChainAPI) ChainExport(ctx context.Context, nroots uint64, oldmsgskip bool, tsk types.TipSetKey) (<-chan []byte, error) {
 	ts, err := a.Chain.GetTipSetFromKey(tsk)
 	if err != nil {
 		return nil, xerrors.Errorf("loading tipset %s: %w", tsk, err)
@@ -341,7 +345,7 @@ func (a *ChainAPI) ChainExport(ctx context.Context, tsk types.TipSetKey) (<-chan
 	out := make(chan []byte)
 	go func() {
 		defer w.Close()
-		if err := a.Chain.Export(ctx, ts, w); err != nil {
+		if err := a.Chain.Export(ctx, ts, nroots, oldmsgskip, w); err != nil {
 			log.Errorf("chain export call failed: %s", err)
 			return
 		}
@@ -366,3 +370,16 @@ func (a *ChainAPI) ChainExport(ctx context.Context, tsk types.TipSetKey) (<-chan
 
 	return out, nil
 }
+
+func (a *ChainAPI) ChainBackfillMsgIndex(ctx context.Context) (int, error) {
+	mi := a.StateManager.MsgIndex()
+	if mi == nil {
+		return 0, xerrors.Errorf("message index is not enabled (set Index.EnableMsgIndex in the config)")
+	}
+
+	return mi.Backfill(ctx, a.Chain.GetHeaviestTipSet())
+}
+
+func (a *ChainAPI) ChainPrune(ctx context.Context, keepStateRoots uint64) (*store.PruneResult, error) {
+	return a.StateManager.PruneChain(ctx, keepStateRoots, a.Mpool.PendingCids)
+}
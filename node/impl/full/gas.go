@@ -0,0 +1,83 @@
+package full
+
+import (
+	"context"
+	"sort"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/stmgr"
+	"github.com/filecoin-project/lotus/chain/store"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+const (
+	// gasLimitOverestimation is the safety margin, in percent, added on top
+	// of the gas used by a message when estimating its GasLimit
+	gasLimitOverestimation = 25
+
+	// gasPriceLookback is the number of tipsets looked at when suggesting
+	// a GasPrice
+	gasPriceLookback = 20
+)
+
+// estimateGasLimit executes the message on top of the state computed for ts
+// and returns the gas it used, plus a safety margin. The message is executed
+// with its own nonce, after priorMsgs, which hold the pending messages of the
+// sender with lower nonces.
+func estimateGasLimit(ctx context.Context, sm *stmgr.StateManager, msgIn *types.Message, priorMsgs []*types.Message, ts *types.TipSet) (types.BigInt, error) {
+	msg := *msgIn
+	msg.GasLimit = types.NewInt(build.BlockGasLimit)
+	msg.GasPrice = types.NewInt(0)
+
+	res, err := sm.CallWithGas(ctx, &msg, priorMsgs, ts)
+	if err != nil {
+		return types.EmptyInt, xerrors.Errorf("executing message: %w", err)
+	}
+
+	if res.MsgRct.ExitCode != 0 {
+		return types.EmptyInt, xerrors.Errorf("message execution failed (exit %d): %s", res.MsgRct.ExitCode, res.Error)
+	}
+
+	used := res.MsgRct.GasUsed
+	margin := types.BigDiv(types.BigMul(used, types.NewInt(gasLimitOverestimation)), types.NewInt(100))
+
+	return types.BigAdd(used, margin), nil
+}
+
+// estimateGasPrice returns the median GasPrice of messages included in the
+// last gasPriceLookback tipsets. Messages which paid nothing for gas are left
+// out, they would drag the median towards zero.
+func estimateGasPrice(cs *store.ChainStore, ts *types.TipSet) (types.BigInt, error) {
+	var prices []types.BigInt
+
+	for i := 0; i < gasPriceLookback && ts.Height() > 0; i++ {
+		msgs, err := cs.MessagesForTipset(ts)
+		if err != nil {
+			return types.EmptyInt, xerrors.Errorf("loading messages for tipset %s: %w", ts.Cids(), err)
+		}
+
+		for _, m := range msgs {
+			if m.VMMessage().GasPrice.IsZero() {
+				continue
+			}
+			prices = append(prices, m.VMMessage().GasPrice)
+		}
+
+		ts, err = cs.LoadTipSet(ts.Parents())
+		if err != nil {
+			return types.EmptyInt, xerrors.Errorf("loading parent tipset: %w", err)
+		}
+	}
+
+	if len(prices) == 0 {
+		return types.NewInt(0), nil
+	}
+
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i].LessThan(prices[j])
+	})
+
+	return prices[len(prices)/2], nil
+}
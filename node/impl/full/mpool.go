@@ -2,6 +2,7 @@ package full
 
 import (
 	"context"
+	"errors"
 
 	"github.com/ipfs/go-cid"
 	"go.uber.org/fx"
@@ -10,6 +11,7 @@ import (
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/chain/messagepool"
+	"github.com/filecoin-project/lotus/chain/stmgr"
 	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
 )
@@ -19,7 +21,8 @@ type MpoolAPI struct {
 
 	WalletAPI
 
-	Chain *store.ChainStore
+	Chain        *store.ChainStore
+	StateManager *stmgr.StateManager
 
 	Mpool *messagepool.MessagePool
 }
@@ -86,31 +89,140 @@ func (a *MpoolAPI) MpoolPush(ctx context.Context, smsg *types.SignedMessage) (ci
 	return a.Mpool.Push(smsg)
 }
 
+// errNonceChanged is returned from the PushWithNonce callback when the gas was
+// estimated for a nonce which was taken by another message in the meantime
+var errNonceChanged = errors.New("nonce changed while estimating gas")
+
 func (a *MpoolAPI) MpoolPushMessage(ctx context.Context, msg *types.Message) (*types.SignedMessage, error) {
 	if msg.Nonce != 0 {
 		return nil, xerrors.Errorf("MpoolPushMessage expects message nonce to be 0, was %d", msg.Nonce)
 	}
 
-	return a.Mpool.PushWithNonce(msg.From, func(nonce uint64) (*types.SignedMessage, error) {
-		msg.Nonce = nonce
+	orig := *msg
+	for {
+		*msg = orig
 
-		b, err := a.WalletBalance(ctx, msg.From)
+		// gas is estimated for the nonce the message is going to get, on top
+		// of the pending messages of the sender
+		nonce, err := a.Mpool.GetNonce(msg.From)
 		if err != nil {
-			return nil, xerrors.Errorf("mpool push: getting origin balance: %w", err)
+			return nil, xerrors.Errorf("mpool push: getting nonce: %w", err)
 		}
+		msg.Nonce = nonce
+
+		if err := a.fillGas(ctx, msg); err != nil {
+			return nil, xerrors.Errorf("mpool push: %w", err)
+		}
+
+		smsg, err := a.Mpool.PushWithNonce(msg.From, func(nonce uint64) (*types.SignedMessage, error) {
+			if nonce != msg.Nonce {
+				return nil, errNonceChanged
+			}
+
+			b, err := a.WalletBalance(ctx, msg.From)
+			if err != nil {
+				return nil, xerrors.Errorf("mpool push: getting origin balance: %w", err)
+			}
+
+			if b.LessThan(msg.Value) {
+				return nil, xerrors.Errorf("mpool push: not enough funds: %s < %s", b, msg.Value)
+			}
 
-		if b.LessThan(msg.Value) {
-			return nil, xerrors.Errorf("mpool push: not enough funds: %s < %s", b, msg.Value)
+			return a.WalletSignMessage(ctx, msg.From, msg)
+		})
+		if err == errNonceChanged {
+			continue
 		}
 
-		return a.WalletSignMessage(ctx, msg.From, msg)
-	})
+		return smsg, err
+	}
 }
 
 func (a *MpoolAPI) MpoolGetNonce(ctx context.Context, addr address.Address) (uint64, error) {
 	return a.Mpool.GetNonce(addr)
 }
 
+func (a *MpoolAPI) MpoolReplace(ctx context.Context, mcid cid.Cid, gasPrice types.BigInt) (*types.SignedMessage, error) {
+	pending, ok := a.Mpool.PendingByCid(mcid)
+	if !ok {
+		return nil, xerrors.Errorf("message %s not found in mpool", mcid)
+	}
+
+	minPrice := a.Mpool.ReplaceByFeePrice(pending.Message.GasPrice)
+	if gasPrice.Nil() || gasPrice.IsZero() {
+		gasPrice = minPrice
+	}
+
+	if gasPrice.LessThan(minPrice) {
+		return nil, xerrors.Errorf("replacing message %s: GasPrice must be at least %s, was %s: %w", mcid, minPrice, gasPrice, messagepool.ErrRBFTooLowPremium)
+	}
+
+	msg := pending.Message
+	msg.GasPrice = gasPrice
+
+	smsg, err := a.WalletSignMessage(ctx, msg.From, &msg)
+	if err != nil {
+		return nil, xerrors.Errorf("signing replacement message: %w", err)
+	}
+
+	if _, err := a.Mpool.Push(smsg); err != nil {
+		return nil, xerrors.Errorf("pushing replacement message: %w", err)
+	}
+
+	return smsg, nil
+}
+
+func (a *MpoolAPI) MpoolEstimateGas(ctx context.Context, msgIn *types.Message) (*types.Message, error) {
+	msg := *msgIn
+
+	nonce, err := a.Mpool.GetNonce(msg.From)
+	if err != nil {
+		return nil, xerrors.Errorf("getting nonce: %w", err)
+	}
+	msg.Nonce = nonce
+
+	if err := a.fillGas(ctx, &msg); err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
+
+func (a *MpoolAPI) MpoolEstimateGasPrice(ctx context.Context) (types.BigInt, error) {
+	return estimateGasPrice(a.Chain, a.Chain.GetHeaviestTipSet())
+}
+
+// fillGas sets GasLimit and GasPrice of the message, if they are unset. The
+// gas limit is estimated for the nonce set in the message.
+func (a *MpoolAPI) fillGas(ctx context.Context, msg *types.Message) error {
+	pending, ts := a.Mpool.PendingFor(msg.From)
+
+	if msg.GasLimit.Nil() {
+		var prior []*types.Message
+		for _, m := range pending {
+			if m.Message.Nonce < msg.Nonce {
+				prior = append(prior, &m.Message)
+			}
+		}
+
+		gl, err := estimateGasLimit(ctx, a.StateManager, msg, prior, ts)
+		if err != nil {
+			return xerrors.Errorf("estimating gas limit: %w", err)
+		}
+		msg.GasLimit = gl
+	}
+
+	if msg.GasPrice.Nil() {
+		gp, err := estimateGasPrice(a.Chain, ts)
+		if err != nil {
+			return xerrors.Errorf("estimating gas price: %w", err)
+		}
+		msg.GasPrice = gp
+	}
+
+	return nil
+}
+
 func (a *MpoolAPI) MpoolSub(ctx context.Context) (<-chan api.MpoolUpdate, error) {
 	return a.Mpool.Updates(ctx)
 }
@@ -158,6 +158,14 @@ func (a *StateAPI) StateCall(ctx context.Context, msg *types.Message, tsk types.
 	return a.StateManager.Call(ctx, msg, ts)
 }
 
+func (a *StateAPI) StateEstimateGas(ctx context.Context, msg *types.Message, tsk types.TipSetKey) (types.BigInt, error) {
+	ts, err := a.Chain.GetTipSetFromKey(tsk)
+	if err != nil {
+		return types.EmptyInt, xerrors.Errorf("loading tipset %s: %w", tsk, err)
+	}
+	return estimateGasLimit(ctx, a.StateManager, msg, nil, ts)
+}
+
 func (a *StateAPI) StateReplay(ctx context.Context, tsk types.TipSetKey, mc cid.Cid) (*api.InvocResult, error) {
 	ts, err := a.Chain.GetTipSetFromKey(tsk)
 	if err != nil {
@@ -476,6 +484,16 @@ func (a *StateAPI) StateListMessages(ctx context.Context, match *types.Message,
 		return true
 	}
 
+	if mi := a.StateManager.MsgIndex(); mi != nil && ts.Equals(a.Chain.GetHeaviestTipSet()) {
+		complete, err := mi.Complete()
+		if err != nil {
+			return nil, xerrors.Errorf("checking message index: %w", err)
+		}
+		if complete {
+			return a.listIndexedMessages(ts, match, matchFunc, toheight)
+		}
+	}
+
 	var out []cid.Cid
 	for ts.Height() >= toheight {
 		msgs, err := a.Chain.MessagesForTipset(ts)
@@ -504,6 +522,59 @@ func (a *StateAPI) StateListMessages(ctx context.Context, match *types.Message,
 	return out, nil
 }
 
+// listIndexedMessages lists messages matching the filter using the message
+// index. Messages included in the head tipset aren't executed yet, so they
+// aren't indexed and are collected directly
+func (a *StateAPI) listIndexedMessages(ts *types.TipSet, match *types.Message, matchFunc func(*types.Message) bool, toheight uint64) ([]cid.Cid, error) {
+	var out []cid.Cid
+	if ts.Height() >= toheight {
+		msgs, err := a.Chain.MessagesForTipset(ts)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to get messages for tipset (%s): %w", ts.Key(), err)
+		}
+
+		for _, msg := range msgs {
+			if matchFunc(msg.VMMessage()) {
+				out = append(out, msg.Cid())
+			}
+		}
+	}
+
+	addr := match.From
+	if addr == address.Undef {
+		addr = match.To
+	}
+
+	mi := a.StateManager.MsgIndex()
+	cids, err := mi.MessagesFor(addr)
+	if err != nil {
+		return nil, xerrors.Errorf("listing indexed messages: %w", err)
+	}
+
+	for _, c := range cids {
+		info, err := mi.GetMsgInfo(c)
+		if err != nil {
+			return nil, xerrors.Errorf("getting indexed message info: %w", err)
+		}
+
+		if info.Height < toheight {
+			// MessagesFor returns messages ordered by height
+			break
+		}
+
+		msg, err := a.Chain.GetCMessage(c)
+		if err != nil {
+			return nil, xerrors.Errorf("loading message %s: %w", c, err)
+		}
+
+		if matchFunc(msg.VMMessage()) {
+			out = append(out, c)
+		}
+	}
+
+	return out, nil
+}
+
 func (a *StateAPI) StateCompute(ctx context.Context, height uint64, msgs []*types.Message, tsk types.TipSetKey) (cid.Cid, error) {
 	ts, err := a.Chain.GetTipSetFromKey(tsk)
 	if err != nil {
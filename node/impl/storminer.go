@@ -3,6 +3,7 @@ package impl
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/filecoin-project/lotus/chain/types"
 	"io"
 	"mime"
@@ -11,17 +12,20 @@ import (
 	"strconv"
 
 	"github.com/gorilla/mux"
-	files "github.com/ipfs/go-ipfs-files"
+	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-sectorbuilder"
 	"github.com/filecoin-project/go-sectorbuilder/fs"
 	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/api/apistruct"
+	"github.com/filecoin-project/lotus/lib/checksum"
 	"github.com/filecoin-project/lotus/lib/tarutil"
 	"github.com/filecoin-project/lotus/miner"
 	"github.com/filecoin-project/lotus/storage"
+	"github.com/filecoin-project/lotus/storage/sched"
 	"github.com/filecoin-project/lotus/storage/sectorblocks"
+	"github.com/filecoin-project/lotus/storage/stores"
 )
 
 type StorageMinerAPI struct {
@@ -30,16 +34,19 @@ type StorageMinerAPI struct {
 	SectorBuilderConfig *sectorbuilder.Config
 	SectorBuilder       sectorbuilder.Interface
 	SectorBlocks        *sectorblocks.SectorBlocks
+	Scheduler           *sched.Scheduler
+	StorageManager      *stores.Manager
 
 	Miner      *storage.Miner
+	FPoSt      *storage.FPoStScheduler
 	BlockMiner *miner.Miner
 	Full       api.FullNode
 }
 
 func (sm *StorageMinerAPI) ServeRemote(w http.ResponseWriter, r *http.Request) {
-	if !apistruct.HasPerm(r.Context(), apistruct.PermAdmin) {
+	if !apistruct.HasPerm(r.Context(), apistruct.PermWorker) {
 		w.WriteHeader(401)
-		json.NewEncoder(w).Encode(struct{ Error string }{"unauthorized: missing write permission"})
+		json.NewEncoder(w).Encode(struct{ Error string }{"unauthorized: missing worker permission"})
 		return
 	}
 
@@ -47,13 +54,18 @@ func (sm *StorageMinerAPI) ServeRemote(w http.ResponseWriter, r *http.Request) {
 
 	mux.HandleFunc("/remote/{type}/{id}", sm.remoteGetSector).Methods("GET")
 	mux.HandleFunc("/remote/{type}/{id}", sm.remotePutSector).Methods("PUT")
+	mux.HandleFunc("/remote/{type}/{id}/checksums", sm.remoteSectorChecksums).Methods("GET")
+	mux.HandleFunc("/remote/{type}/{id}/partial", sm.remotePartialSize).Methods("GET")
 
 	log.Infof("SERVEGETREMOTE %s", r.URL)
 
 	mux.ServeHTTP(w, r)
 }
 
-func (sm *StorageMinerAPI) remoteGetSector(w http.ResponseWriter, r *http.Request) {
+// remoteTransfer runs a transfer of sector data as a fetch task. Errors
+// returned by the transfer are sent to the worker, so transfers return
+// errors only before writing a response.
+func (sm *StorageMinerAPI) remoteTransfer(w http.ResponseWriter, r *http.Request, transfer func(ctx context.Context, typ fs.DataType, id uint64) error) {
 	vars := mux.Vars(r)
 
 	id, err := strconv.ParseUint(vars["id"], 10, 64)
@@ -63,100 +75,257 @@ func (sm *StorageMinerAPI) remoteGetSector(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	path, err := sm.SectorBuilder.SectorPath(fs.DataType(vars["type"]), id)
+	err = sm.Scheduler.Schedule(r.Context(), api.TaskFetch, id, func(ctx context.Context) error {
+		return transfer(ctx, fs.DataType(vars["type"]), id)
+	})
 	if err != nil {
-		log.Error(err)
+		log.Errorf("%s %s: %+v", r.Method, r.URL, err)
 		w.WriteHeader(500)
-		return
+		json.NewEncoder(w).Encode(struct{ Error string }{err.Error()})
+	}
+}
+
+func (sm *StorageMinerAPI) remoteGetSector(w http.ResponseWriter, r *http.Request) {
+	sm.remoteTransfer(w, r, func(ctx context.Context, typ fs.DataType, id uint64) error {
+		path, err := sm.SectorBuilder.SectorPath(typ, id)
+		if err != nil {
+			return err
+		}
+
+		stat, err := os.Stat(string(path))
+		if err != nil {
+			return err
+		}
+
+		if stat.IsDir() {
+			rd, err := tarutil.TarDirectory(string(path))
+			if err != nil {
+				return err
+			}
+			defer rd.Close()
+
+			w.Header().Set("Content-Type", "application/x-tar")
+			w.WriteHeader(200)
+
+			pw := &progressWriter{ResponseWriter: w, report: func(n uint64) {
+				sm.Scheduler.ReportProgress(ctx, n, 0)
+			}}
+			if _, err := io.Copy(pw, rd); err != nil {
+				log.Errorf("sending %s sector %d: %+v", typ, id, err)
+			}
+			return nil
+		}
+
+		f, err := os.Open(string(path))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		pw := &progressWriter{ResponseWriter: w, report: func(n uint64) {
+			sm.Scheduler.ReportProgress(ctx, n, uint64(stat.Size()))
+		}}
+		// resumed transfers request the remaining range of the file
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &pw.n); err != nil {
+			pw.n = 0
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeContent(pw, r, "", stat.ModTime(), f)
+		return nil
+	})
+}
+
+func (sm *StorageMinerAPI) remoteSectorChecksums(w http.ResponseWriter, r *http.Request) {
+	sm.remoteTransfer(w, r, func(ctx context.Context, typ fs.DataType, id uint64) error {
+		path, err := sm.SectorBuilder.SectorPath(typ, id)
+		if err != nil {
+			return err
+		}
+
+		sums, err := checksum.Files(string(path))
+		if err != nil {
+			return err
+		}
+
+		return json.NewEncoder(w).Encode(sums)
+	})
+}
+
+// remotePutPath returns the path a sector pushed by a worker is stored at
+func (sm *StorageMinerAPI) remotePutPath(typ fs.DataType, id uint64) (fs.SectorPath, error) {
+	// This is going to get better with worker-to-worker transfers
+
+	path, err := sm.SectorBuilder.SectorPath(typ, id)
+	if err == fs.ErrNotFound {
+		return sm.SectorBuilder.AllocSectorPath(typ, id, true)
 	}
+	return path, err
+}
+
+// remotePartialSize returns the size of a partially pushed file, which the
+// worker resumes the push from
+func (sm *StorageMinerAPI) remotePartialSize(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
 
-	stat, err := os.Stat(string(path))
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
 	if err != nil {
-		log.Error(err)
+		log.Error("parsing sector id: ", err)
 		w.WriteHeader(500)
 		return
 	}
 
-	var rd io.Reader
-	if stat.IsDir() {
-		rd, err = tarutil.TarDirectory(string(path))
-		w.Header().Set("Content-Type", "application/x-tar")
-	} else {
-		rd, err = os.OpenFile(string(path), os.O_RDONLY, 0644)
-		w.Header().Set("Content-Type", "application/octet-stream")
-	}
+	path, err := sm.remotePutPath(fs.DataType(vars["type"]), id)
 	if err != nil {
 		log.Error(err)
 		w.WriteHeader(500)
 		return
 	}
 
-	w.WriteHeader(200)
-	if _, err := io.Copy(w, rd); err != nil {
+	var size int64
+	stat, err := os.Stat(string(path) + partSuffix)
+	switch {
+	case err == nil && stat.Mode().IsRegular():
+		size = stat.Size()
+	case err != nil && !os.IsNotExist(err):
 		log.Error(err)
+		w.WriteHeader(500)
 		return
 	}
+
+	json.NewEncoder(w).Encode(struct{ Size int64 }{size})
 }
 
 func (sm *StorageMinerAPI) remotePutSector(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
+	sm.remoteTransfer(w, r, func(ctx context.Context, typ fs.DataType, id uint64) error {
+		sums, err := checksum.Decode(r.Header.Get(checksum.Header))
+		if err != nil {
+			return err
+		}
 
-	id, err := strconv.ParseUint(vars["id"], 10, 64)
-	if err != nil {
-		log.Error("parsing sector id: ", err)
-		w.WriteHeader(500)
-		return
-	}
+		path, err := sm.remotePutPath(typ, id)
+		if err != nil {
+			return err
+		}
+		part := string(path) + partSuffix
 
-	// This is going to get better with worker-to-worker transfers
+		mediatype, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			return err
+		}
 
-	path, err := sm.SectorBuilder.SectorPath(fs.DataType(vars["type"]), id)
-	if err != nil {
-		if err != fs.ErrNotFound {
-			log.Error(err)
-			w.WriteHeader(500)
-			return
+		switch mediatype {
+		case "application/x-tar":
+			if err := os.RemoveAll(part); err != nil {
+				return err
+			}
+
+			body := &progressReader{Reader: r.Body, report: func(n uint64) {
+				sm.Scheduler.ReportProgress(ctx, n, 0)
+			}}
+			if err := tarutil.ExtractTar(body, part); err != nil {
+				return xerrors.Errorf("extracting %s: %w", part, err)
+			}
+		default:
+			if err := writePart(ctx, sm.Scheduler, part, r); err != nil {
+				return err
+			}
 		}
 
-		path, err = sm.SectorBuilder.AllocSectorPath(fs.DataType(vars["type"]), id, true)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(500)
-			return
+		if err := checksum.Verify(part, sums); err != nil {
+			if rerr := os.RemoveAll(part); rerr != nil {
+				log.Errorf("removing corrupted %s: %+v", part, rerr)
+			}
+			return xerrors.Errorf("verifying %s sector %d: %w", typ, id, err)
+		}
+
+		if err := os.RemoveAll(string(path)); err != nil {
+			return err
+		}
+		if err := os.Rename(part, string(path)); err != nil {
+			return err
+		}
+
+		w.WriteHeader(200)
+
+		log.Infof("received %s sector (%d): %d bytes", typ, id, r.ContentLength)
+		return nil
+	})
+}
+
+// partSuffix is appended to paths of sector data being received, until it's
+// complete and verified
+const partSuffix = ".part"
+
+// writePart writes a pushed file to a partial file, at the offset from the
+// Content-Range header, which lets workers resume interrupted pushes
+func writePart(ctx context.Context, s *sched.Scheduler, part string, r *http.Request) error {
+	start, total := int64(0), r.ContentLength
+	if cr := r.Header.Get("Content-Range"); cr != "" {
+		var end int64
+		if _, err := fmt.Sscanf(cr, "bytes %d-%d/%d", &start, &end, &total); err != nil {
+			return xerrors.Errorf("parsing Content-Range '%s': %w", cr, err)
 		}
 	}
 
-	mediatype, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	f, err := os.OpenFile(part, os.O_WRONLY|os.O_CREATE, 0644)
 	if err != nil {
-		log.Error(err)
-		w.WriteHeader(500)
-		return
+		return err
 	}
+	defer f.Close()
 
-	if err := os.RemoveAll(string(path)); err != nil {
-		log.Error(err)
-		w.WriteHeader(500)
-		return
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if start > stat.Size() {
+		return xerrors.Errorf("push resumed at %d, but only %d bytes were received", start, stat.Size())
+	}
+	if err := f.Truncate(start); err != nil {
+		return err
+	}
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		return err
 	}
 
-	switch mediatype {
-	case "application/x-tar":
-		if err := tarutil.ExtractTar(r.Body, string(path)); err != nil {
-			log.Error(err)
-			w.WriteHeader(500)
-			return
-		}
-	default:
-		if err := files.WriteTo(files.NewReaderFile(r.Body), string(path)); err != nil {
-			log.Error(err)
-			w.WriteHeader(500)
-			return
-		}
+	body := &progressReader{Reader: r.Body, n: uint64(start), report: func(n uint64) {
+		s.ReportProgress(ctx, n, uint64(total))
+	}}
+	n, err := io.Copy(f, body)
+	if err != nil {
+		return xerrors.Errorf("writing %s: %w", part, err)
+	}
+	if total >= 0 && start+n != total {
+		return xerrors.Errorf("incomplete push: received %d of %d bytes", start+n, total)
 	}
 
-	w.WriteHeader(200)
+	return f.Close()
+}
+
+type progressReader struct {
+	io.Reader
+	n      uint64
+	report func(uint64)
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.Reader.Read(p)
+	pr.n += uint64(n)
+	pr.report(pr.n)
+	return n, err
+}
 
-	log.Infof("received %s sector (%s): %d bytes", vars["type"], vars["sname"], r.ContentLength)
+type progressWriter struct {
+	http.ResponseWriter
+	n      uint64
+	report func(uint64)
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.ResponseWriter.Write(p)
+	pw.n += uint64(n)
+	pw.report(pw.n)
+	return n, err
 }
 
 func (sm *StorageMinerAPI) WorkerStats(context.Context) (sectorbuilder.WorkerStats, error) {
@@ -183,8 +352,14 @@ func (sm *StorageMinerAPI) SectorsStatus(ctx context.Context, sid uint64) (api.S
 	}
 
 	deals := make([]uint64, len(info.Pieces))
+	pieces := make([]api.SectorPiece, len(info.Pieces))
 	for i, piece := range info.Pieces {
 		deals[i] = piece.DealID
+		pieces[i] = api.SectorPiece{
+			DealID: piece.DealID,
+			Size:   piece.Size,
+			CommP:  piece.CommP,
+		}
 	}
 
 	log := make([]api.SectorLog, len(info.Log))
@@ -204,6 +379,7 @@ func (sm *StorageMinerAPI) SectorsStatus(ctx context.Context, sid uint64) (api.S
 		CommR:    info.CommR,
 		Proof:    info.Proof,
 		Deals:    deals,
+		Pieces:   pieces,
 		Ticket:   info.Ticket.SB(),
 		Seed:     info.Seed.SB(),
 		Retries:  info.Nonce,
@@ -247,12 +423,113 @@ func (sm *StorageMinerAPI) SectorsUpdate(ctx context.Context, id uint64, state a
 	return sm.Miner.ForceSectorState(ctx, id, state)
 }
 
+func (sm *StorageMinerAPI) SectorsRecover(ctx context.Context, id uint64) error {
+	return sm.Miner.RecoverSector(ctx, id)
+}
+
+func (sm *StorageMinerAPI) SectorsNotify(ctx context.Context) (<-chan api.SectorUpdate, error) {
+	return sm.Miner.SectorUpdates(ctx)
+}
+
+func (sm *StorageMinerAPI) SectorRemove(ctx context.Context, id uint64, terminate bool) error {
+	if terminate {
+		return sm.Miner.TerminateSector(ctx, id, sm.StorageManager.Remove)
+	}
+	return sm.Miner.RemoveSector(ctx, id, sm.StorageManager.Remove)
+}
+
 func (sm *StorageMinerAPI) WorkerQueue(ctx context.Context, cfg sectorbuilder.WorkerCfg) (<-chan sectorbuilder.WorkerTask, error) {
-	return sm.SectorBuilder.AddWorker(ctx, cfg)
+	info := api.WorkerInfo{
+		Hostname: "legacy",
+		Cores:    1, // limits the worker to a task at a time
+	}
+	if !cfg.NoPreCommit {
+		info.TaskTypes = append(info.TaskTypes, api.TaskPreCommit)
+	}
+	if !cfg.NoCommit {
+		info.TaskTypes = append(info.TaskTypes, api.TaskCommit)
+	}
+
+	tasks, err := sm.Scheduler.AddWorker(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan sectorbuilder.WorkerTask)
+	go func() {
+		defer close(out)
+
+		for {
+			select {
+			case task, ok := <-tasks:
+				if !ok {
+					return
+				}
+
+				st := task.Seal
+				st.TaskID = task.TaskID // the worker reports results with it
+
+				select {
+				case out <- st:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (sm *StorageMinerAPI) WorkerConnect(ctx context.Context, info api.WorkerInfo) (<-chan api.WorkerTask, error) {
+	return sm.Scheduler.AddWorker(ctx, info)
 }
 
 func (sm *StorageMinerAPI) WorkerDone(ctx context.Context, task uint64, res sectorbuilder.SealRes) error {
-	return sm.SectorBuilder.TaskDone(ctx, task, res)
+	return sm.Scheduler.TaskDone(ctx, task, res)
+}
+
+func (sm *StorageMinerAPI) WorkerList(context.Context) ([]api.WorkerState, error) {
+	return sm.Scheduler.List(), nil
+}
+
+func (sm *StorageMinerAPI) StorageAttach(ctx context.Context, path string, weight int, cache bool) error {
+	return sm.StorageManager.Attach(fs.PathConfig{
+		Path:   path,
+		Weight: weight,
+		Cache:  cache,
+	})
+}
+
+func (sm *StorageMinerAPI) StorageDetach(ctx context.Context, path string) error {
+	return sm.StorageManager.Detach(path)
+}
+
+func (sm *StorageMinerAPI) StorageList(context.Context) ([]api.StoragePathInfo, error) {
+	return sm.StorageManager.List()
+}
+
+func (sm *StorageMinerAPI) StorageMove(ctx context.Context, sector uint64, path string) error {
+	info, err := sm.Miner.GetSectorInfo(sector)
+	if err != nil {
+		return err
+	}
+	if info.State != api.Proving {
+		return xerrors.Errorf("sector %d is in state %s, only proving sectors can be moved", sector, api.SectorStates[info.State])
+	}
+
+	// moves are transfers, limited and listed with the others
+	return sm.Scheduler.Schedule(ctx, api.TaskFetch, sector, func(ctx context.Context) error {
+		return sm.StorageManager.Move(ctx, sector, path, func(done, size uint64) {
+			sm.Scheduler.ReportProgress(ctx, done, size)
+		})
+	})
+}
+
+func (sm *StorageMinerAPI) ProvingCheck(ctx context.Context, generate bool) (*api.ProvingCheck, error) {
+	return sm.FPoSt.Check(ctx, generate)
 }
 
 var _ api.StorageMiner = &StorageMinerAPI{}
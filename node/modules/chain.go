@@ -3,6 +3,7 @@ package modules
 import (
 	"bytes"
 	"context"
+	"time"
 
 	"github.com/ipfs/go-bitswap"
 	"github.com/ipfs/go-bitswap/network"
@@ -19,9 +20,11 @@ import (
 	"github.com/filecoin-project/lotus/chain"
 	"github.com/filecoin-project/lotus/chain/blocksync"
 	"github.com/filecoin-project/lotus/chain/messagepool"
+	"github.com/filecoin-project/lotus/chain/msgindex"
 	"github.com/filecoin-project/lotus/chain/stmgr"
 	"github.com/filecoin-project/lotus/chain/store"
 	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/node/config"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 	"github.com/filecoin-project/lotus/node/modules/helpers"
 	"github.com/filecoin-project/lotus/node/repo"
@@ -41,9 +44,19 @@ func ChainExchange(mctx helpers.MetricsCtx, lc fx.Lifecycle, host host.Host, rt
 	return exch
 }
 
-func MessagePool(lc fx.Lifecycle, sm *stmgr.StateManager, ps *pubsub.PubSub, ds dtypes.MetadataDS) (*messagepool.MessagePool, error) {
+func MpoolConfig(cfg config.Mpool) func() *messagepool.Config {
+	return func() *messagepool.Config {
+		return &messagepool.Config{
+			ReplaceByFeeRatio:  cfg.ReplaceByFeeRatio,
+			SizeLimit:          cfg.SizeLimit,
+			SenderPendingLimit: cfg.SenderPendingLimit,
+		}
+	}
+}
+
+func MessagePool(lc fx.Lifecycle, sm *stmgr.StateManager, ps *pubsub.PubSub, ds dtypes.MetadataDS, cfg *messagepool.Config) (*messagepool.MessagePool, error) {
 	mpp := messagepool.NewProvider(sm, ps)
-	mp, err := messagepool.New(mpp, ds)
+	mp, err := messagepool.New(mpp, ds, cfg)
 	if err != nil {
 		return nil, xerrors.Errorf("constructing mpool: %w", err)
 	}
@@ -55,6 +68,32 @@ func MessagePool(lc fx.Lifecycle, sm *stmgr.StateManager, ps *pubsub.PubSub, ds
 	return mp, nil
 }
 
+func SetupMsgIndex(cs *store.ChainStore, sm *stmgr.StateManager, ds dtypes.MetadataDS) {
+	sm.SetMsgIndex(msgindex.New(cs, ds))
+}
+
+func RunChainPruner(cfg config.Pruning) func(helpers.MetricsCtx, fx.Lifecycle, *stmgr.StateManager, *messagepool.MessagePool) {
+	return func(mctx helpers.MetricsCtx, lc fx.Lifecycle, sm *stmgr.StateManager, mp *messagepool.MessagePool) {
+		ctx := helpers.LifecycleCtx(mctx, lc)
+
+		go func() {
+			tick := time.NewTicker(time.Duration(cfg.Interval))
+			defer tick.Stop()
+
+			for {
+				select {
+				case <-tick.C:
+					if _, err := sm.PruneChain(ctx, cfg.RecentStateRoots, mp.PendingCids); err != nil {
+						log.Errorf("pruning chain blockstore: %s", err)
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+}
+
 func ChainBlockstore(r repo.LockedRepo) (dtypes.ChainBlockstore, error) {
 	blocks, err := r.Datastore("/blocks")
 	if err != nil {
@@ -65,12 +104,14 @@ func ChainBlockstore(r repo.LockedRepo) (dtypes.ChainBlockstore, error) {
 	return blockstore.NewIdStore(bs), nil
 }
 
-func ChainGCBlockstore(bs dtypes.ChainBlockstore, gcl dtypes.ChainGCLocker) dtypes.ChainGCBlockstore {
-	return blockstore.NewGCBlockstore(bs, gcl)
+// ChainGCBlockstore uses the chain store blockstore, so that blocks fetched
+// while the chain is pruned aren't deleted
+func ChainGCBlockstore(cs *store.ChainStore, gcl dtypes.ChainGCLocker) dtypes.ChainGCBlockstore {
+	return blockstore.NewGCBlockstore(cs.Blockstore(), gcl)
 }
 
-func ChainBlockservice(bs dtypes.ChainBlockstore, rem dtypes.ChainExchange) dtypes.ChainBlockService {
-	return blockservice.New(bs, rem)
+func ChainBlockservice(cs *store.ChainStore, rem dtypes.ChainExchange) dtypes.ChainBlockService {
+	return blockservice.New(cs.Blockstore(), rem)
 }
 
 func ChainStore(lc fx.Lifecycle, bs dtypes.ChainBlockstore, ds dtypes.MetadataDS, syscalls *types.VMSyscalls) *store.ChainStore {
@@ -34,6 +34,8 @@ import (
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 	"github.com/filecoin-project/lotus/node/modules/helpers"
 	"github.com/filecoin-project/lotus/node/repo"
+	"github.com/filecoin-project/lotus/node/repo/dealmeta"
+	"github.com/filecoin-project/lotus/node/repo/importmgr"
 	"github.com/filecoin-project/lotus/paych"
 )
 
@@ -71,9 +73,21 @@ func NewClientDAGServiceDataTransfer(h host.Host, gs dtypes.ClientGraphsync) dty
 	return graphsyncimpl.NewGraphSyncDataTransfer(h, gs)
 }
 
-// NewClientDealStore creates a statestore for the client to store its deals
-func NewClientDealStore(ds dtypes.MetadataDS) dtypes.ClientDealStore {
-	return statestore.New(namespace.Wrap(ds, datastore.NewKey("/deals/client")))
+// NewClientDealStore creates a statestore for the client to store its deals.
+// Deal metadata subscribers are notified of changes to it.
+func NewClientDealStore(ds dtypes.MetadataDS, meta *dealmeta.Store) dtypes.ClientDealStore {
+	return statestore.New(meta.Watch(namespace.Wrap(ds, datastore.NewKey("/deals/client"))))
+}
+
+// ClientImportMgr keeps track of the files imported by the client
+func ClientImportMgr(ds dtypes.MetadataDS) *importmgr.Mgr {
+	return importmgr.New(namespace.Wrap(ds, datastore.NewKey("/client/imports")))
+}
+
+// ClientDealMeta keeps what the client learns about its deals outside of the
+// storage market
+func ClientDealMeta(ds dtypes.MetadataDS) *dealmeta.Store {
+	return dealmeta.New(namespace.Wrap(ds, datastore.NewKey("/client/dealmeta")))
 }
 
 // ClientDAG is a DAGService for the ClientBlockstore
@@ -43,8 +43,10 @@ import (
 	"github.com/filecoin-project/lotus/node/modules/helpers"
 	"github.com/filecoin-project/lotus/node/repo"
 	"github.com/filecoin-project/lotus/storage"
+	"github.com/filecoin-project/lotus/storage/sched"
 	"github.com/filecoin-project/lotus/storage/sealing"
 	"github.com/filecoin-project/lotus/storage/sectorblocks"
+	"github.com/filecoin-project/lotus/storage/stores"
 )
 
 func minerAddrFromDS(ds dtypes.MetadataDS) (address.Address, error) {
@@ -83,6 +85,14 @@ func SectorBuilderConfig(storage []fs.PathConfig, threads uint, noprecommit, noc
 			}
 		}
 
+		paths, err := stores.Paths(ds, storage)
+		if err != nil {
+			return nil, xerrors.Errorf("getting storage paths: %w", err)
+		}
+		if err := stores.PruneLinks(paths); err != nil {
+			return nil, xerrors.Errorf("removing links to moved sectors: %w", err)
+		}
+
 		if threads > math.MaxUint8 {
 			return nil, xerrors.Errorf("too many sectorbuilder threads specified: %d, max allowed: %d", threads, math.MaxUint8)
 		}
@@ -95,14 +105,14 @@ func SectorBuilderConfig(storage []fs.PathConfig, threads uint, noprecommit, noc
 			NoPreCommit:   noprecommit,
 			NoCommit:      nocommit,
 
-			Paths: storage,
+			Paths: paths,
 		}
 
 		return sb, nil
 	}
 }
 
-func StorageMiner(mctx helpers.MetricsCtx, lc fx.Lifecycle, api api.FullNode, h host.Host, ds dtypes.MetadataDS, sb sectorbuilder.Interface, tktFn sealing.TicketFn) (*storage.Miner, error) {
+func FPoStScheduler(mctx helpers.MetricsCtx, lc fx.Lifecycle, api api.FullNode, ds dtypes.MetadataDS, sb sectorbuilder.Interface, pcfg storage.ProvingConfig) (*storage.FPoStScheduler, error) {
 	maddr, err := minerAddrFromDS(ds)
 	if err != nil {
 		return nil, err
@@ -115,16 +125,40 @@ func StorageMiner(mctx helpers.MetricsCtx, lc fx.Lifecycle, api api.FullNode, h
 		return nil, err
 	}
 
-	fps := storage.NewFPoStScheduler(api, sb, maddr, worker)
+	fps := storage.NewFPoStScheduler(api, sb, pcfg, maddr, worker)
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go fps.Run(ctx)
+			return nil
+		},
+	})
+
+	return fps, nil
+}
+
+// StorageMiner takes the FPoStScheduler so that PoSts are always run along
+// with the miner
+func StorageMiner(mctx helpers.MetricsCtx, lc fx.Lifecycle, api api.FullNode, h host.Host, ds dtypes.MetadataDS, sb sectorbuilder.Interface, tktFn sealing.TicketFn, scfg sealing.Config, _ *storage.FPoStScheduler) (*storage.Miner, error) {
+	maddr, err := minerAddrFromDS(ds)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := helpers.LifecycleCtx(mctx, lc)
 
-	sm, err := storage.NewMiner(api, maddr, worker, h, ds, sb, tktFn)
+	worker, err := api.StateMinerWorker(ctx, maddr, types.EmptyTSK)
+	if err != nil {
+		return nil, err
+	}
+
+	sm, err := storage.NewMiner(api, maddr, worker, h, ds, sb, tktFn, scfg)
 	if err != nil {
 		return nil, err
 	}
 
 	lc.Append(fx.Hook{
 		OnStart: func(context.Context) error {
-			go fps.Run(ctx)
 			return sm.Run(ctx)
 		},
 		OnStop: sm.Stop,
@@ -253,6 +287,26 @@ func SectorBuilder(cfg *sectorbuilder.Config, ds dtypes.MetadataDS) (*sectorbuil
 	return sb, nil
 }
 
+func Scheduler(transfers uint) func(mctx helpers.MetricsCtx, lc fx.Lifecycle, sb sectorbuilder.Interface, sbc *sectorbuilder.Config) (*sched.Scheduler, error) {
+	return func(mctx helpers.MetricsCtx, lc fx.Lifecycle, sb sectorbuilder.Interface, sbc *sectorbuilder.Config) (*sched.Scheduler, error) {
+		paths := make([]string, len(sbc.Paths))
+		for i, p := range sbc.Paths {
+			paths[i] = p.Path
+		}
+
+		local, err := sched.LocalInfo(paths, uint64(transfers), []api.WorkerTaskType{api.TaskUnseal, api.TaskFetch})
+		if err != nil {
+			return nil, xerrors.Errorf("getting local worker resources: %w", err)
+		}
+
+		return sched.New(helpers.LifecycleCtx(mctx, lc), sb, sbc.SectorSize, local), nil
+	}
+}
+
+func StorageManager(ds dtypes.MetadataDS, sb sectorbuilder.Interface, sbc *sectorbuilder.Config) *stores.Manager {
+	return stores.New(ds, sb, sbc.Paths)
+}
+
 func SealTicketGen(api api.FullNode) sealing.TicketFn {
 	return func(ctx context.Context) (*sectorbuilder.SealTicket, error) {
 		ts, err := api.ChainHead(ctx)
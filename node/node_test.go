@@ -38,6 +38,7 @@ import (
 	modtest "github.com/filecoin-project/lotus/node/modules/testing"
 	"github.com/filecoin-project/lotus/node/repo"
 	"github.com/filecoin-project/lotus/storage/sbmock"
+	"github.com/filecoin-project/lotus/storage/sealing"
 )
 
 func init() {
@@ -108,6 +109,9 @@ func testStorageNode(ctx context.Context, t *testing.T, waddr address.Address, a
 		node.Override(new(api.FullNode), tnd),
 		node.Override(new(*miner.Miner), miner.NewTestMiner(mineBlock, act)),
 
+		// don't wait for more deals in tests
+		node.Override(new(sealing.Config), sealing.Config{WaitDealsDelay: time.Second}),
+
 		opts,
 	)
 	if err != nil {
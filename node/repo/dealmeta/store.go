@@ -0,0 +1,196 @@
+package dealmeta
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"golang.org/x/xerrors"
+)
+
+// Meta is what the client knows about a storage deal besides its state in the
+// storage market
+type Meta struct {
+	Created  time.Time
+	SectorID uint64 // 0 until a sector with the deal is seen committed
+	Err      string // why the deal failed
+}
+
+// Store keeps deal metadata by proposal CID, and by deal ID once the deal is
+// published
+type Store struct {
+	ds datastore.Batching
+	lk sync.Mutex
+
+	subLk sync.Mutex
+	subs  map[chan struct{}]struct{}
+}
+
+func New(ds datastore.Batching) *Store {
+	return &Store{
+		ds:   ds,
+		subs: map[chan struct{}]struct{}{},
+	}
+}
+
+// Subscribe returns a channel which receives a value after deal metadata, or
+// deal states stored through a datastore returned by Watch, change. Changes
+// are coalesced, subscribers re-read the deals they are interested in.
+func (s *Store) Subscribe(ctx context.Context) <-chan struct{} {
+	ch := make(chan struct{}, 1)
+
+	s.subLk.Lock()
+	s.subs[ch] = struct{}{}
+	s.subLk.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		s.subLk.Lock()
+		delete(s.subs, ch)
+		s.subLk.Unlock()
+	}()
+
+	return ch
+}
+
+func (s *Store) notify() {
+	s.subLk.Lock()
+	defer s.subLk.Unlock()
+
+	for ch := range s.subs {
+		select {
+		case ch <- struct{}{}:
+		default: // a change is already pending
+		}
+	}
+}
+
+// Watch wraps the datastore the storage market client keeps deal states in,
+// notifying subscribers when they are written
+func (s *Store) Watch(ds datastore.Datastore) datastore.Datastore {
+	return &watchedDS{Datastore: ds, notify: s.notify}
+}
+
+type watchedDS struct {
+	datastore.Datastore
+	notify func()
+}
+
+func (w *watchedDS) Put(k datastore.Key, v []byte) error {
+	if err := w.Datastore.Put(k, v); err != nil {
+		return err
+	}
+	w.notify()
+	return nil
+}
+
+func (w *watchedDS) Delete(k datastore.Key) error {
+	if err := w.Datastore.Delete(k); err != nil {
+		return err
+	}
+	w.notify()
+	return nil
+}
+
+func (s *Store) Created(proposal cid.Cid, t time.Time) error {
+	return s.update(proposalKey(proposal), func(m *Meta) {
+		m.Created = t
+	})
+}
+
+// ProposalFailed records why a deal failed before it got a deal ID
+func (s *Store) ProposalFailed(proposal cid.Cid, msg string) error {
+	return s.update(proposalKey(proposal), func(m *Meta) {
+		m.Err = msg
+	})
+}
+
+func (s *Store) DealFailed(dealID uint64, msg string) error {
+	return s.update(dealKey(dealID), func(m *Meta) {
+		m.Err = msg
+	})
+}
+
+func (s *Store) SectorCommitted(dealID uint64, sectorID uint64) error {
+	return s.update(dealKey(dealID), func(m *Meta) {
+		m.SectorID = sectorID
+	})
+}
+
+// Get returns what is known about a deal, dealID is 0 for unpublished deals
+func (s *Store) Get(proposal cid.Cid, dealID uint64) (Meta, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	out, err := s.getLocked(proposalKey(proposal))
+	if err != nil || dealID == 0 {
+		return out, err
+	}
+
+	dm, err := s.getLocked(dealKey(dealID))
+	if err != nil {
+		return out, err
+	}
+
+	if dm.SectorID != 0 {
+		out.SectorID = dm.SectorID
+	}
+	if dm.Err != "" {
+		out.Err = dm.Err
+	}
+	return out, nil
+}
+
+func (s *Store) update(k datastore.Key, mutate func(*Meta)) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	m, err := s.getLocked(k)
+	if err != nil {
+		return err
+	}
+
+	mutate(&m)
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := s.ds.Put(k, b); err != nil {
+		return err
+	}
+
+	s.notify()
+	return nil
+}
+
+// getLocked returns empty metadata for unknown deals
+func (s *Store) getLocked(k datastore.Key) (Meta, error) {
+	var out Meta
+
+	b, err := s.ds.Get(k)
+	if xerrors.Is(err, datastore.ErrNotFound) {
+		return out, nil
+	}
+	if err != nil {
+		return out, xerrors.Errorf("getting deal metadata %s: %w", k, err)
+	}
+
+	if err := json.Unmarshal(b, &out); err != nil {
+		return out, xerrors.Errorf("decoding deal metadata %s: %w", k, err)
+	}
+	return out, nil
+}
+
+func proposalKey(proposal cid.Cid) datastore.Key {
+	return datastore.NewKey("/proposal").ChildString(proposal.String())
+}
+
+func dealKey(dealID uint64) datastore.Key {
+	return datastore.NewKey("/deal").ChildString(strconv.FormatUint(dealID, 10))
+}
@@ -0,0 +1,114 @@
+package dealmeta
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/multiformats/go-multihash"
+)
+
+func testCid(t *testing.T, s string) cid.Cid {
+	h, err := multihash.Sum([]byte(s), multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cid.NewCidV1(cid.Raw, h)
+}
+
+func TestDealMeta(t *testing.T) {
+	s := New(datastore.NewMapDatastore())
+
+	a, b := testCid(t, "a"), testCid(t, "b")
+
+	m, err := s.Get(a, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != (Meta{}) {
+		t.Fatalf("expected empty metadata for unknown deal, got %+v", m)
+	}
+
+	created := time.Unix(1579000000, 0)
+	if err := s.Created(a, created); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SectorCommitted(5, 12); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err = s.Get(a, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Created.Equal(created) || m.SectorID != 12 || m.Err != "" {
+		t.Fatalf("unexpected metadata: %+v", m)
+	}
+
+	if err := s.Created(b, created); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ProposalFailed(b, "not published"); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err = s.Get(b, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Created.Equal(created) || m.Err != "not published" {
+		t.Fatalf("unexpected metadata: %+v", m)
+	}
+
+	if err := s.DealFailed(6, "not activated"); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err = s.Get(b, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Err != "not activated" {
+		t.Fatalf("expected deal failure to take precedence, got %+v", m)
+	}
+}
+
+func TestDealMetaSubscribe(t *testing.T) {
+	s := New(datastore.NewMapDatastore())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := s.Subscribe(ctx)
+	states := s.Watch(datastore.NewMapDatastore())
+
+	expectChange := func(what string) {
+		select {
+		case <-changes:
+		case <-time.After(time.Second):
+			t.Fatalf("no change notified for %s", what)
+		}
+	}
+
+	if err := s.Created(testCid(t, "a"), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SectorCommitted(5, 12); err != nil {
+		t.Fatal(err)
+	}
+	// changes are coalesced
+	expectChange("metadata")
+
+	select {
+	case <-changes:
+		t.Fatal("unexpected second notification")
+	default:
+	}
+
+	if err := states.Put(datastore.NewKey("deal"), []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+	expectChange("deal state")
+}
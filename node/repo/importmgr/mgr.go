@@ -0,0 +1,133 @@
+package importmgr
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"golang.org/x/xerrors"
+)
+
+// Import records data imported by the client
+type Import struct {
+	Root     cid.Cid
+	FilePath string // empty for data uploaded through the API
+	Size     uint64
+	CAR      bool // blocks were loaded from a CAR file, not referenced in FilePath
+	Imported time.Time
+
+	// Deals are proposal CIDs of storage deals made with the data
+	Deals []cid.Cid
+}
+
+// Mgr tracks imported data by its root CID
+type Mgr struct {
+	ds datastore.Batching
+	lk sync.Mutex
+}
+
+func New(ds datastore.Batching) *Mgr {
+	return &Mgr{ds: ds}
+}
+
+// Add records an import. Deals recorded for the root before are kept.
+func (m *Mgr) Add(imp Import) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	old, err := m.getLocked(imp.Root)
+	switch {
+	case err == nil:
+		imp.Deals = append(old.Deals, imp.Deals...)
+	case xerrors.Is(err, datastore.ErrNotFound):
+	default:
+		return err
+	}
+
+	return m.putLocked(imp)
+}
+
+// AddDeal records a deal made with imported data. Deals for data which wasn't
+// imported aren't recorded.
+func (m *Mgr) AddDeal(root cid.Cid, proposal cid.Cid) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	imp, err := m.getLocked(root)
+	if xerrors.Is(err, datastore.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	imp.Deals = append(imp.Deals, proposal)
+	return m.putLocked(imp)
+}
+
+func (m *Mgr) Get(root cid.Cid) (Import, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	return m.getLocked(root)
+}
+
+// List returns all imports, oldest first
+func (m *Mgr) List() ([]Import, error) {
+	res, err := m.ds.Query(query.Query{})
+	if err != nil {
+		return nil, xerrors.Errorf("querying imports: %w", err)
+	}
+	defer res.Close()
+
+	var out []Import
+	for r := range res.Next() {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+
+		var imp Import
+		if err := json.Unmarshal(r.Value, &imp); err != nil {
+			return nil, xerrors.Errorf("decoding import %s: %w", r.Key, err)
+		}
+		out = append(out, imp)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Imported.Before(out[j].Imported)
+	})
+	return out, nil
+}
+
+func (m *Mgr) Remove(root cid.Cid) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	return m.ds.Delete(datastore.NewKey(root.String()))
+}
+
+func (m *Mgr) getLocked(root cid.Cid) (Import, error) {
+	var out Import
+
+	b, err := m.ds.Get(datastore.NewKey(root.String()))
+	if err != nil {
+		return out, xerrors.Errorf("getting import %s: %w", root, err)
+	}
+
+	if err := json.Unmarshal(b, &out); err != nil {
+		return out, xerrors.Errorf("decoding import %s: %w", root, err)
+	}
+	return out, nil
+}
+
+func (m *Mgr) putLocked(imp Import) error {
+	b, err := json.Marshal(imp)
+	if err != nil {
+		return err
+	}
+	return m.ds.Put(datastore.NewKey(imp.Root.String()), b)
+}
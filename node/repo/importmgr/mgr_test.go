@@ -0,0 +1,64 @@
+package importmgr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/multiformats/go-multihash"
+)
+
+func testCid(t *testing.T, s string) cid.Cid {
+	h, err := multihash.Sum([]byte(s), multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cid.NewCidV1(cid.Raw, h)
+}
+
+func TestImports(t *testing.T) {
+	m := New(datastore.NewMapDatastore())
+
+	a, b := testCid(t, "a"), testCid(t, "b")
+	deal := testCid(t, "deal")
+
+	now := time.Now()
+	if err := m.Add(Import{Root: b, FilePath: "/b", Size: 2, Imported: now.Add(time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add(Import{Root: a, FilePath: "/a", Size: 1, Imported: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.AddDeal(a, deal); err != nil {
+		t.Fatal(err)
+	}
+	// not imported, ignored
+	if err := m.AddDeal(testCid(t, "c"), deal); err != nil {
+		t.Fatal(err)
+	}
+
+	// importing again keeps deals
+	if err := m.Add(Import{Root: a, FilePath: "/a2", Size: 1, Imported: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := m.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Root != a || list[1].Root != b {
+		t.Fatalf("unexpected imports: %+v", list)
+	}
+	if list[0].FilePath != "/a2" || len(list[0].Deals) != 1 || list[0].Deals[0] != deal {
+		t.Fatalf("unexpected import: %+v", list[0])
+	}
+
+	if err := m.Remove(a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Get(a); err == nil {
+		t.Fatal("expected removed import to be gone")
+	}
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
+)
+
+// Check runs the checks of a fallback PoSt over the current proving set
+// without submitting anything. With generate set, a full proof is generated
+// to time it.
+func (s *FPoStScheduler) Check(ctx context.Context, generate bool) (*api.ProvingCheck, error) {
+	ts, err := s.api.ChainHead(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	eps, err := s.api.StateMinerElectionPeriodStart(ctx, s.actor, ts.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("getting ElectionPeriodStart: %w", err)
+	}
+
+	ssi, err := s.sortedSectorInfo(ctx, ts)
+	if err != nil {
+		return nil, xerrors.Errorf("getting sorted sector info: %w", err)
+	}
+
+	declared, err := s.api.StateMinerFaults(ctx, s.actor, ts.Key())
+	if err != nil {
+		return nil, xerrors.Errorf("checking on-chain faults: %w", err)
+	}
+
+	out := &api.ProvingCheck{
+		Height:              ts.Height(),
+		ElectionPeriodStart: eps,
+		ChallengeHeight:     eps + build.FallbackPoStDelay,
+		StartHeight:         eps + build.FallbackPoStDelay + s.cfg.StartConfidence,
+		Deadline:            eps + build.SlashablePowerDelay,
+
+		Sectors:        uint64(len(ssi.Values())),
+		DeclaredFaults: declared,
+	}
+
+	scrubStart := time.Now()
+	faults := s.sb.Scrub(ssi)
+	out.ScrubTime = time.Since(scrubStart)
+
+	faultIDs := append([]uint64{}, declared...)
+	for _, fault := range faults {
+		out.Faults = append(out.Faults, api.ProvingFault{
+			SectorID: fault.SectorID,
+			Err:      fault.Err.Error(),
+		})
+		faultIDs = append(faultIDs, fault.SectorID)
+	}
+
+	s.proofTimeLk.Lock()
+	last, lastSectors := s.lastProofTime, s.lastProofSectors
+	s.proofTimeLk.Unlock()
+
+	if lastSectors > 0 {
+		out.EstimatedProofTime = last * time.Duration(out.Sectors) / time.Duration(lastSectors)
+	}
+
+	if !generate {
+		return out, nil
+	}
+
+	rand, err := s.api.ChainGetRandomness(ctx, ts.Key(), int64(ts.Height()))
+	if err != nil {
+		return nil, xerrors.Errorf("getting chain randomness: %w", err)
+	}
+
+	var seed [32]byte
+	copy(seed[:], rand)
+
+	proofStart := time.Now()
+	if _, _, err := s.sb.GenerateFallbackPoSt(ssi, seed, dedupe(faultIDs)); err != nil {
+		out.ProofErr = err.Error()
+		return out, nil
+	}
+	out.ProofTime = time.Since(proofStart)
+	s.recordProofTime(out.ProofTime, out.Sectors)
+
+	return out, nil
+}
+
+// recordProofTime keeps the time it took to prove the given number of
+// sectors, to estimate the time of following proofs
+func (s *FPoStScheduler) recordProofTime(elapsed time.Duration, sectors uint64) {
+	s.proofTimeLk.Lock()
+	defer s.proofTimeLk.Unlock()
+
+	s.lastProofTime = elapsed
+	s.lastProofSectors = sectors
+}
+
+func dedupe(ids []uint64) []uint64 {
+	seen := map[uint64]bool{}
+	out := make([]uint64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+type pendingPost struct {
+	eps uint64
+	msg *types.SignedMessage
+
+	raisedAt uint64 // height at which the gas price was last raised
+
+	// earlier messages for the same nonce, replaced with higher gas prices
+	replaced []cid.Cid
+
+	// stops waiting for the message, when it's replaced
+	cancel context.CancelFunc
+}
+
+// trackPostLocked waits for a submitted PoSt message to land, keeping it as
+// the pending message until it does. Must be called with pendingLk held.
+func (s *FPoStScheduler) trackPostLocked(p *pendingPost) {
+	if s.pending != nil {
+		s.pending.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	p.cancel = cancel
+	s.pending = p
+
+	go func() {
+		defer cancel()
+
+		rec, err := s.api.StateWaitMsg(ctx, p.msg.Cid())
+		if ctx.Err() != nil {
+			return // replaced
+		}
+
+		s.pendingLk.Lock()
+		if s.pending == p {
+			s.pending = nil
+		}
+		s.pendingLk.Unlock()
+
+		if err != nil {
+			log.Error(err)
+			return
+		}
+
+		if rec.Receipt.ExitCode == 0 {
+			return
+		}
+
+		log.Errorf("Submitting fallback post %s failed: exit %d", p.msg.Cid(), rec.Receipt.ExitCode)
+	}()
+}
+
+// escalatePost raises the gas price of the pending PoSt message if it hasn't
+// landed within the deadline margin of the end of the proving period. The
+// price is raised once per epoch, up to the configured maximum, unless one of
+// the messages replaced before landed.
+func (s *FPoStScheduler) escalatePost(ctx context.Context, ts *types.TipSet) error {
+	s.pendingLk.Lock()
+	defer s.pendingLk.Unlock()
+
+	p := s.pending
+	if p == nil {
+		return nil
+	}
+
+	deadline := p.eps + build.SlashablePowerDelay
+	if ts.Height() <= deadline && (ts.Height()+s.cfg.DeadlineMargin < deadline || ts.Height() <= p.raisedAt) {
+		return nil
+	}
+
+	// only the last message is waited for, but a replaced one may land
+	landed, err := s.landedLocked(ctx, p, ts)
+	if err != nil {
+		return xerrors.Errorf("checking whether PoSt message landed: %w", err)
+	}
+	if landed {
+		return nil
+	}
+
+	if ts.Height() > deadline {
+		p.cancel()
+		s.pending = nil
+		return xerrors.Errorf("PoSt message %s for election period %d didn't land before the deadline at %d", p.msg.Cid(), p.eps, deadline)
+	}
+
+	cur := p.msg.Message.GasPrice
+	price := types.BigDiv(types.BigMul(cur, types.NewInt(100+s.cfg.GasEscalation)), types.NewInt(100))
+	if !price.GreaterThan(cur) {
+		price = types.BigAdd(cur, types.NewInt(1))
+	}
+	if max := s.cfg.MaxPoStGasPrice; !max.Nil() && max.Sign() > 0 && price.GreaterThan(max) {
+		price = max
+	}
+	if !price.GreaterThan(cur) {
+		return nil // already paying the maximum
+	}
+
+	log.Warnw("PoSt message didn't land close to the deadline, raising gas price",
+		"msg", p.msg.Cid(),
+		"height", ts.Height(),
+		"deadline", deadline,
+		"price", price)
+
+	sm, err := s.api.MpoolReplace(ctx, p.msg.Cid(), price)
+	if err != nil {
+		return xerrors.Errorf("replacing message %s: %w", p.msg.Cid(), err)
+	}
+
+	s.trackPostLocked(&pendingPost{
+		eps:      p.eps,
+		msg:      sm,
+		raisedAt: ts.Height(),
+		replaced: append(p.replaced, p.msg.Cid()),
+	})
+	return nil
+}
+
+// landedLocked checks whether a message for the nonce of the pending PoSt was
+// included in the chain. Must be called with pendingLk held.
+func (s *FPoStScheduler) landedLocked(ctx context.Context, p *pendingPost, ts *types.TipSet) (bool, error) {
+	act, err := s.api.StateGetActor(ctx, p.msg.Message.From, ts.Key())
+	if err != nil {
+		return false, xerrors.Errorf("getting worker actor: %w", err)
+	}
+	if act.Nonce <= p.msg.Message.Nonce {
+		return false, nil
+	}
+
+	rec, err := s.api.StateGetReceipt(ctx, p.msg.Cid(), ts.Key())
+	if err != nil {
+		return false, xerrors.Errorf("getting receipt of %s: %w", p.msg.Cid(), err)
+	}
+	if rec != nil {
+		return true, nil // handled by trackPostLocked
+	}
+
+	for _, c := range p.replaced {
+		rec, err := s.api.StateGetReceipt(ctx, c, ts.Key())
+		if err != nil {
+			return false, xerrors.Errorf("getting receipt of %s: %w", c, err)
+		}
+		if rec == nil {
+			continue
+		}
+
+		p.cancel()
+		s.pending = nil
+
+		if rec.ExitCode != 0 {
+			log.Errorf("Submitting fallback post %s failed: exit %d", c, rec.ExitCode)
+		} else {
+			log.Infow("replaced PoSt message landed", "msg", c, "pending", p.msg.Cid())
+		}
+		return true, nil
+	}
+
+	p.cancel()
+	s.pending = nil
+	return false, xerrors.Errorf("nonce %d of PoSt message %s was used by another message", p.msg.Message.Nonce, p.msg.Cid())
+}
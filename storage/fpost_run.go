@@ -41,7 +41,7 @@ func (s *FPoStScheduler) doPost(ctx context.Context, eps uint64, ts *types.TipSe
 			return
 		}
 
-		if err := s.submitPost(ctx, proof); err != nil {
+		if err := s.submitPost(ctx, eps, proof); err != nil {
 			log.Errorf("submitPost failed: %+v", err)
 			s.failPost(eps)
 			return
@@ -64,8 +64,8 @@ func (s *FPoStScheduler) declareFaults(ctx context.Context, fc uint64, params *a
 		Method:   actors.MAMethods.DeclareFaults,
 		Params:   enc,
 		Value:    types.NewInt(0),
-		GasLimit: types.NewInt(10000000), // i dont know help
-		GasPrice: types.NewInt(1),
+		GasLimit: s.cfg.FaultGasLimit,
+		GasPrice: s.cfg.FaultGasPrice,
 	}
 
 	sm, err := s.api.MpoolPushMessage(ctx, msg)
@@ -175,6 +175,7 @@ func (s *FPoStScheduler) runPost(ctx context.Context, eps uint64, ts *types.TipS
 	}
 
 	elapsed := time.Since(tsStart)
+	s.recordProofTime(elapsed, uint64(len(ssi.Values())))
 	log.Infow("submitting PoSt", "pLen", len(proof), "elapsed", elapsed)
 
 	candidates := make([]types.EPostTicket, len(scandidates))
@@ -217,7 +218,7 @@ func (s *FPoStScheduler) sortedSectorInfo(ctx context.Context, ts *types.TipSet)
 	return sectorbuilder.NewSortedPublicSectorInfo(sbsi), nil
 }
 
-func (s *FPoStScheduler) submitPost(ctx context.Context, proof *actors.SubmitFallbackPoStParams) error {
+func (s *FPoStScheduler) submitPost(ctx context.Context, eps uint64, proof *actors.SubmitFallbackPoStParams) error {
 	ctx, span := trace.StartSpan(ctx, "storage.commitPost")
 	defer span.End()
 
@@ -226,17 +227,25 @@ func (s *FPoStScheduler) submitPost(ctx context.Context, proof *actors.SubmitFal
 		return xerrors.Errorf("could not serialize submit post parameters: %w", aerr)
 	}
 
+	price := s.cfg.PoStGasPrice
+	if price.Nil() || price.Sign() == 0 {
+		var err error
+		price, err = s.api.MpoolEstimateGasPrice(ctx)
+		if err != nil {
+			return xerrors.Errorf("estimating gas price: %w", err)
+		}
+	}
+
 	msg := &types.Message{
 		To:       s.actor,
 		From:     s.worker,
 		Method:   actors.MAMethods.SubmitFallbackPoSt,
 		Params:   enc,
-		Value:    types.NewInt(1000),     // currently hard-coded late fee in actor, returned if not late
-		GasLimit: types.NewInt(10000000), // i dont know help
-		GasPrice: types.NewInt(1),
+		Value:    types.NewInt(1000), // currently hard-coded late fee in actor, returned if not late
+		GasLimit: s.cfg.PoStGasLimit,
+		GasPrice: price,
 	}
 
-	// TODO: consider maybe caring about the output
 	sm, err := s.api.MpoolPushMessage(ctx, msg)
 	if err != nil {
 		return xerrors.Errorf("pushing message to mpool: %w", err)
@@ -244,19 +253,9 @@ func (s *FPoStScheduler) submitPost(ctx context.Context, proof *actors.SubmitFal
 
 	log.Infof("Submitted fallback post: %s", sm.Cid())
 
-	go func() {
-		rec, err := s.api.StateWaitMsg(context.TODO(), sm.Cid())
-		if err != nil {
-			log.Error(err)
-			return
-		}
-
-		if rec.Receipt.ExitCode == 0 {
-			return
-		}
-
-		log.Errorf("Submitting fallback post %s failed: exit %d", sm.Cid(), rec.Receipt.ExitCode)
-	}()
+	s.pendingLk.Lock()
+	s.trackPostLocked(&pendingPost{eps: eps, msg: sm})
+	s.pendingLk.Unlock()
 
 	return nil
 }
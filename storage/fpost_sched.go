@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"sync"
+	"time"
 
 	"go.opencensus.io/trace"
 	"golang.org/x/xerrors"
@@ -17,11 +18,34 @@ import (
 
 const Inactive = 0
 
-const StartConfidence = 4 // TODO: config
+// ProvingConfig configures when fallback PoSts are generated, and the gas
+// paid for PoSt and fault messages
+type ProvingConfig struct {
+	// StartConfidence is the number of epochs to wait after the challenge
+	// epoch before generating a PoSt, so that the challenge is unlikely to
+	// be reverted
+	StartConfidence uint64
+
+	PoStGasLimit  types.BigInt
+	PoStGasPrice  types.BigInt // zero uses a price estimated from recent messages
+	FaultGasLimit types.BigInt
+	FaultGasPrice types.BigInt
+
+	// DeadlineMargin is the number of epochs before the end of the proving
+	// period from which the gas price of a PoSt message that hasn't landed
+	// is raised
+	DeadlineMargin uint64
+	// GasEscalation is the percentage the gas price is raised by each epoch,
+	// it has to be at least the replace-by-fee ratio of the mpool
+	GasEscalation uint64
+	// MaxPoStGasPrice caps raised gas prices, zero means no limit
+	MaxPoStGasPrice types.BigInt
+}
 
 type FPoStScheduler struct {
 	api storageMinerApi
 	sb  sectorbuilder.Interface
+	cfg ProvingConfig
 
 	actor  address.Address
 	worker address.Address
@@ -34,10 +58,19 @@ type FPoStScheduler struct {
 
 	failed uint64 // eps
 	failLk sync.Mutex
+
+	// submitted PoSt message which hasn't landed yet
+	pending   *pendingPost
+	pendingLk sync.Mutex
+
+	// duration of the last generated proof, and the number of sectors in it
+	lastProofTime    time.Duration
+	lastProofSectors uint64
+	proofTimeLk      sync.Mutex
 }
 
-func NewFPoStScheduler(api storageMinerApi, sb sectorbuilder.Interface, actor address.Address, worker address.Address) *FPoStScheduler {
-	return &FPoStScheduler{api: api, sb: sb, actor: actor, worker: worker}
+func NewFPoStScheduler(api storageMinerApi, sb sectorbuilder.Interface, cfg ProvingConfig, actor address.Address, worker address.Address) *FPoStScheduler {
+	return &FPoStScheduler{api: api, sb: sb, cfg: cfg, actor: actor, worker: worker}
 }
 
 func (s *FPoStScheduler) Run(ctx context.Context) {
@@ -78,6 +111,9 @@ func (s *FPoStScheduler) Run(ctx context.Context) {
 					log.Errorf("change.Val was nil")
 				}
 				switch change.Type {
+				case store.HCCurrent:
+					// sent again when the node api client reconnects
+					lowest, highest = change.Val, change.Val
 				case store.HCRevert:
 					lowest = change.Val
 				case store.HCApply:
@@ -121,6 +157,11 @@ func (s *FPoStScheduler) update(ctx context.Context, new *types.TipSet) error {
 	if new == nil {
 		return xerrors.Errorf("no new tipset in FPoStScheduler.update")
 	}
+
+	if err := s.escalatePost(ctx, new); err != nil {
+		log.Errorf("raising gas price of pending PoSt: %+v", err)
+	}
+
 	newEPS, start, err := s.shouldFallbackPost(ctx, new)
 	if err != nil {
 		return err
@@ -168,7 +209,7 @@ func (s *FPoStScheduler) shouldFallbackPost(ctx context.Context, ts *types.TipSe
 	}
 
 	if ts.Height() >= eps+build.FallbackPoStDelay {
-		return eps, ts.Height() >= eps+build.FallbackPoStDelay+StartConfidence, nil
+		return eps, ts.Height() >= eps+build.FallbackPoStDelay+s.cfg.StartConfidence, nil
 	}
 	return 0, false, nil
 }
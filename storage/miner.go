@@ -31,6 +31,7 @@ type Miner struct {
 	sb    sectorbuilder.Interface
 	ds    datastore.Batching
 	tktFn sealing.TicketFn
+	scfg  sealing.Config
 
 	maddr  address.Address
 	worker address.Address
@@ -53,6 +54,8 @@ type storageMinerApi interface {
 	StateMinerFaults(context.Context, address.Address, types.TipSetKey) ([]uint64, error)
 
 	MpoolPushMessage(context.Context, *types.Message) (*types.SignedMessage, error)
+	MpoolReplace(context.Context, cid.Cid, types.BigInt) (*types.SignedMessage, error)
+	MpoolEstimateGasPrice(context.Context) (types.BigInt, error)
 
 	ChainHead(context.Context) (*types.TipSet, error)
 	ChainNotify(context.Context) (<-chan []*store.HeadChange, error)
@@ -66,13 +69,14 @@ type storageMinerApi interface {
 	WalletHas(context.Context, address.Address) (bool, error)
 }
 
-func NewMiner(api storageMinerApi, maddr, worker address.Address, h host.Host, ds datastore.Batching, sb sectorbuilder.Interface, tktFn sealing.TicketFn) (*Miner, error) {
+func NewMiner(api storageMinerApi, maddr, worker address.Address, h host.Host, ds datastore.Batching, sb sectorbuilder.Interface, tktFn sealing.TicketFn, scfg sealing.Config) (*Miner, error) {
 	m := &Miner{
 		api:   api,
 		h:     h,
 		sb:    sb,
 		ds:    ds,
 		tktFn: tktFn,
+		scfg:  scfg,
 
 		maddr:  maddr,
 		worker: worker,
@@ -87,7 +91,7 @@ func (m *Miner) Run(ctx context.Context) error {
 	}
 
 	evts := events.NewEvents(ctx, m.api)
-	m.sealing = sealing.New(m.api, evts, m.maddr, m.worker, m.ds, m.sb, m.tktFn)
+	m.sealing = sealing.New(m.api, evts, m.maddr, m.worker, m.ds, m.sb, m.tktFn, m.scfg)
 
 	go m.sealing.Run(ctx)
 
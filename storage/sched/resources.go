@@ -0,0 +1,189 @@
+package sched
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+type resources struct {
+	cores     uint64
+	memory    uint64
+	disk      uint64
+	transfers uint64
+}
+
+// taskResources returns rough estimates of what a task on a sector of the
+// given size needs
+func taskResources(tt api.WorkerTaskType, ssize uint64) resources {
+	switch tt {
+	case api.TaskPreCommit:
+		// staged and sealed sector, and a cache about 10 times the sector size
+		return resources{cores: 1, memory: 2 * ssize, disk: 12 * ssize}
+	case api.TaskCommit:
+		return resources{cores: 4, memory: ssize, disk: 11 * ssize}
+	case api.TaskUnseal:
+		return resources{cores: 1, memory: ssize, disk: ssize}
+	case api.TaskFetch:
+		return resources{transfers: 1}
+	default:
+		return resources{}
+	}
+}
+
+func (r resources) add(o resources) resources {
+	return resources{
+		cores:     r.cores + o.cores,
+		memory:    r.memory + o.memory,
+		disk:      r.disk + o.disk,
+		transfers: r.transfers + o.transfers,
+	}
+}
+
+func (r resources) sub(o resources) resources {
+	return resources{
+		cores:     r.cores - o.cores,
+		memory:    r.memory - o.memory,
+		disk:      r.disk - o.disk,
+		transfers: r.transfers - o.transfers,
+	}
+}
+
+// totals returns the resources declared by a worker. Zero values are not
+// tracked.
+func totals(info api.WorkerInfo) resources {
+	r := resources{
+		cores:     info.Cores,
+		memory:    info.Memory,
+		transfers: info.Transfers,
+	}
+	for _, p := range info.Paths {
+		r.disk += p.Available
+	}
+	return r
+}
+
+// clamp caps requirements at what the worker has, so that any task a worker
+// accepts can run when the worker is idle
+func (r resources) clamp(total resources) resources {
+	min := func(a, b uint64) uint64 {
+		if a < b {
+			return a
+		}
+		return b
+	}
+
+	return resources{
+		cores:     min(r.cores, total.cores),
+		memory:    min(r.memory, total.memory),
+		disk:      min(r.disk, total.disk),
+		transfers: min(r.transfers, total.transfers),
+	}
+}
+
+func (r resources) fits(used, total resources) bool {
+	fit := func(need, used, total uint64) bool {
+		return need == 0 || used+need <= total
+	}
+
+	return fit(r.cores, used.cores, total.cores) &&
+		fit(r.memory, used.memory, total.memory) &&
+		fit(r.disk, used.disk, total.disk) &&
+		fit(r.transfers, used.transfers, total.transfers)
+}
+
+// count returns how many tasks with the requirements fit in total
+func (r resources) count(total resources) uint64 {
+	n := uint64(0)
+	div := func(need, total uint64) {
+		if need == 0 {
+			return
+		}
+		if c := total / need; n == 0 || c < n {
+			n = c
+		}
+	}
+
+	div(r.cores, total.cores)
+	div(r.memory, total.memory)
+	div(r.disk, total.disk)
+	div(r.transfers, total.transfers)
+
+	if n == 0 {
+		return 1
+	}
+	return n
+}
+
+// LocalInfo returns the resources of this machine, with disk space of the
+// given storage paths
+func LocalInfo(paths []string, transfers uint64, types []api.WorkerTaskType) (api.WorkerInfo, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return api.WorkerInfo{}, xerrors.Errorf("getting hostname: %w", err)
+	}
+
+	mem, err := memTotal()
+	if err != nil {
+		return api.WorkerInfo{}, xerrors.Errorf("getting memory size: %w", err)
+	}
+
+	info := api.WorkerInfo{
+		Hostname:  hostname,
+		Cores:     uint64(runtime.NumCPU()),
+		Memory:    mem,
+		Transfers: transfers,
+		TaskTypes: types,
+	}
+
+	for _, p := range paths {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(p, &st); err != nil {
+			return api.WorkerInfo{}, xerrors.Errorf("statfs %s: %w", p, err)
+		}
+
+		info.Paths = append(info.Paths, api.WorkerPath{
+			Path:      p,
+			Capacity:  uint64(st.Blocks) * uint64(st.Bsize),
+			Available: uint64(st.Bavail) * uint64(st.Bsize),
+		})
+	}
+
+	return info, nil
+}
+
+// memTotal returns the amount of physical memory, or 0 where it can't be
+// read from /proc/meminfo
+func memTotal() (uint64, error) {
+	f, err := os.Open("/proc/meminfo")
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		fields := strings.Fields(scan.Text())
+		if len(fields) != 3 || fields[0] != "MemTotal:" || fields[2] != "kB" {
+			continue
+		}
+
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, xerrors.Errorf("parsing MemTotal: %w", err)
+		}
+		return kb * 1024, nil
+	}
+
+	return 0, scan.Err()
+}
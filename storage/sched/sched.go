@@ -0,0 +1,476 @@
+package sched
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	sectorbuilder "github.com/filecoin-project/go-sectorbuilder"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+var log = logging.Logger("sched")
+
+// sealer hands out sealing tasks to remote workers
+type sealer interface {
+	AddWorker(context.Context, sectorbuilder.WorkerCfg) (<-chan sectorbuilder.WorkerTask, error)
+	TaskDone(context.Context, uint64, sectorbuilder.SealRes) error
+}
+
+type task struct {
+	id       uint64
+	typ      api.WorkerTaskType
+	sectorID uint64
+
+	seal  *sectorbuilder.WorkerTask // set for tasks from the sectorbuilder
+	ready chan struct{}             // closed when a local task is assigned
+
+	worker  uint64
+	need    resources
+	started time.Time
+
+	progress uint64
+	size     uint64
+}
+
+type taskKey struct{}
+
+type workerHandle struct {
+	id    uint64
+	info  api.WorkerInfo
+	local bool
+
+	total resources
+	used  resources
+	tasks map[uint64]*task
+
+	ctx context.Context
+	out chan api.WorkerTask
+}
+
+func (w *workerHandle) accepts(tt api.WorkerTaskType) bool {
+	for _, t := range w.info.TaskTypes {
+		if t == tt {
+			return true
+		}
+	}
+	return false
+}
+
+// Scheduler assigns sealing tasks from the sectorbuilder, and unseal and
+// fetch tasks run by the miner itself, to workers with the resources to run
+// them. Tasks on a sector go to the worker which holds its data when it can
+// take them.
+type Scheduler struct {
+	sb    sealer
+	ssize uint64
+	ctx   context.Context
+
+	lk         sync.Mutex
+	workers    map[uint64]*workerHandle
+	nextWorker uint64
+	nextTask   uint64
+
+	queue   []*task
+	holders map[uint64]uint64 // sector ID -> worker keeping sector data
+
+	// Sealing task slots registered with the sectorbuilder, by task type.
+	// The sectorbuilder drops tasks given to slots whose context is
+	// cancelled, so slots stay open for the lifetime of the scheduler, and
+	// tasks they receive wait in the queue until a worker can run them.
+	slots map[api.WorkerTaskType]uint64
+}
+
+// New creates a scheduler, with a local worker running tasks in the miner
+// process
+func New(ctx context.Context, sb sealer, ssize uint64, local api.WorkerInfo) *Scheduler {
+	s := &Scheduler{
+		sb:    sb,
+		ssize: ssize,
+		ctx:   ctx,
+
+		workers: map[uint64]*workerHandle{},
+		holders: map[uint64]uint64{},
+		slots:   map[api.WorkerTaskType]uint64{},
+	}
+
+	s.addWorkerLocked(ctx, local, true)
+
+	return s
+}
+
+func (s *Scheduler) addWorkerLocked(ctx context.Context, info api.WorkerInfo, local bool) *workerHandle {
+	s.nextWorker++
+	w := &workerHandle{
+		id:    s.nextWorker,
+		info:  info,
+		local: local,
+
+		total: totals(info),
+		tasks: map[uint64]*task{},
+
+		ctx: ctx,
+	}
+	if !local {
+		w.out = make(chan api.WorkerTask)
+	}
+
+	s.workers[w.id] = w
+	return w
+}
+
+// AddWorker registers a remote worker, which stays connected until ctx is
+// cancelled. Tasks assigned to the worker are sent on the returned channel.
+func (s *Scheduler) AddWorker(ctx context.Context, info api.WorkerInfo) (<-chan api.WorkerTask, error) {
+	for _, tt := range info.TaskTypes {
+		if tt != api.TaskPreCommit && tt != api.TaskCommit {
+			return nil, xerrors.Errorf("remote workers can't run %s tasks", tt)
+		}
+	}
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	w := s.addWorkerLocked(ctx, info, false)
+	log.Infow("worker connected", "worker", w.id, "host", info.Hostname, "cores", info.Cores, "memory", info.Memory, "tasks", info.TaskTypes)
+
+	if err := s.ensureSlotsLocked(); err != nil {
+		delete(s.workers, w.id)
+		return nil, err
+	}
+	s.scheduleLocked()
+
+	go func() {
+		<-ctx.Done()
+		s.removeWorker(w.id)
+	}()
+
+	return w.out, nil
+}
+
+// removeWorker puts tasks of a disconnected worker back in the queue
+func (s *Scheduler) removeWorker(id uint64) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	w, ok := s.workers[id]
+	if !ok {
+		return
+	}
+	delete(s.workers, id)
+
+	requeue := make([]*task, 0, len(w.tasks))
+	for _, t := range w.tasks {
+		t.worker = 0
+		t.need = resources{}
+		requeue = append(requeue, t)
+	}
+	sort.Slice(requeue, func(i, j int) bool {
+		return requeue[i].id < requeue[j].id
+	})
+
+	// requeued tasks get new IDs, so that results the disconnected worker
+	// may still report are ignored
+	for _, t := range requeue {
+		s.nextTask++
+		t.id = s.nextTask
+	}
+	s.queue = append(requeue, s.queue...)
+
+	for sector, holder := range s.holders {
+		if holder == id {
+			delete(s.holders, sector)
+		}
+	}
+
+	log.Warnw("worker disconnected", "worker", id, "host", w.info.Hostname, "requeued", len(requeue))
+
+	s.scheduleLocked()
+}
+
+// ensureSlotsLocked registers sectorbuilder slots for as many sealing tasks
+// as connected workers can run at once
+func (s *Scheduler) ensureSlotsLocked() error {
+	for _, tt := range []api.WorkerTaskType{api.TaskPreCommit, api.TaskCommit} {
+		var want uint64
+		for _, w := range s.workers {
+			if !w.accepts(tt) {
+				continue
+			}
+			need := taskResources(tt, s.ssize).clamp(w.total)
+			want += need.count(w.total)
+		}
+
+		for s.slots[tt] < want {
+			tasks, err := s.sb.AddWorker(s.ctx, sectorbuilder.WorkerCfg{
+				NoPreCommit: tt != api.TaskPreCommit,
+				NoCommit:    tt != api.TaskCommit,
+			})
+			if err != nil {
+				return xerrors.Errorf("adding %s slot: %w", tt, err)
+			}
+			s.slots[tt]++
+
+			go s.runSlot(tasks)
+		}
+	}
+
+	return nil
+}
+
+func (s *Scheduler) runSlot(tasks <-chan sectorbuilder.WorkerTask) {
+	for {
+		select {
+		case st, ok := <-tasks:
+			if !ok {
+				return
+			}
+
+			tt := api.TaskPreCommit
+			if st.Type == sectorbuilder.WorkerCommit {
+				tt = api.TaskCommit
+			}
+
+			s.lk.Lock()
+			s.nextTask++
+			s.queue = append(s.queue, &task{
+				id:       s.nextTask,
+				typ:      tt,
+				sectorID: st.SectorID,
+				seal:     &st,
+			})
+			s.scheduleLocked()
+			s.lk.Unlock()
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// Schedule waits for the local worker to have resources for a task, and
+// runs it
+func (s *Scheduler) Schedule(ctx context.Context, tt api.WorkerTaskType, sectorID uint64, work func(context.Context) error) error {
+	s.lk.Lock()
+	s.nextTask++
+	t := &task{
+		id:       s.nextTask,
+		typ:      tt,
+		sectorID: sectorID,
+		ready:    make(chan struct{}),
+	}
+	s.queue = append(s.queue, t)
+	s.scheduleLocked()
+	s.lk.Unlock()
+
+	select {
+	case <-t.ready:
+	case <-ctx.Done():
+		s.lk.Lock()
+		queued := s.dequeueLocked(t.id)
+		if !queued {
+			s.releaseLocked(t)
+		}
+		s.lk.Unlock()
+		return ctx.Err()
+	}
+
+	defer func() {
+		s.lk.Lock()
+		s.releaseLocked(t)
+		s.lk.Unlock()
+	}()
+
+	return work(context.WithValue(ctx, taskKey{}, t))
+}
+
+// ReportProgress sets the progress of a task, from within the work function
+// passed to Schedule
+func (s *Scheduler) ReportProgress(ctx context.Context, progress, size uint64) {
+	t, ok := ctx.Value(taskKey{}).(*task)
+	if !ok {
+		return
+	}
+
+	s.lk.Lock()
+	t.progress = progress
+	t.size = size
+	s.lk.Unlock()
+}
+
+// TaskDone reports the result of a sealing task to the sectorbuilder. Results
+// of tasks which were requeued since are ignored.
+func (s *Scheduler) TaskDone(ctx context.Context, id uint64, res sectorbuilder.SealRes) error {
+	s.lk.Lock()
+
+	w, t := s.findLocked(id)
+	if t == nil || t.seal == nil {
+		s.lk.Unlock()
+		return xerrors.Errorf("sealing task %d not found, it may have been reassigned", id)
+	}
+
+	if res.Err == "" {
+		switch t.typ {
+		case api.TaskPreCommit:
+			s.holders[t.sectorID] = w.id
+		case api.TaskCommit:
+			delete(s.holders, t.sectorID)
+		}
+	}
+	s.releaseLocked(t)
+
+	s.lk.Unlock()
+
+	return s.sb.TaskDone(ctx, t.seal.TaskID, res)
+}
+
+// findLocked returns an assigned task, and the worker running it
+func (s *Scheduler) findLocked(id uint64) (*workerHandle, *task) {
+	for _, w := range s.workers {
+		if t, ok := w.tasks[id]; ok {
+			return w, t
+		}
+	}
+	return nil, nil
+}
+
+func (s *Scheduler) dequeueLocked(id uint64) bool {
+	for i, t := range s.queue {
+		if t.id == id {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scheduler) releaseLocked(t *task) {
+	w, ok := s.workers[t.worker]
+	if !ok {
+		return
+	}
+	if _, ok := w.tasks[t.id]; !ok {
+		return
+	}
+
+	delete(w.tasks, t.id)
+	w.used = w.used.sub(t.need)
+
+	s.scheduleLocked()
+}
+
+// scheduleLocked assigns queued tasks to workers, in queue order
+func (s *Scheduler) scheduleLocked() {
+	var waiting []*task
+	for _, t := range s.queue {
+		w, need := s.pickLocked(t)
+		if w == nil {
+			waiting = append(waiting, t)
+			continue
+		}
+
+		t.worker = w.id
+		t.need = need
+		t.started = time.Now()
+		w.used = w.used.add(need)
+		w.tasks[t.id] = t
+
+		if t.seal != nil {
+			go s.send(w, api.WorkerTask{
+				TaskID:   t.id,
+				Type:     t.typ,
+				SectorID: t.sectorID,
+				Seal:     *t.seal,
+			})
+		} else {
+			close(t.ready)
+		}
+	}
+	s.queue = waiting
+}
+
+// pickLocked returns a worker with resources to run the task, preferring the
+// worker holding the sector data, then the least busy one
+func (s *Scheduler) pickLocked(t *task) (*workerHandle, resources) {
+	fits := func(w *workerHandle) (resources, bool) {
+		if (t.seal != nil) == w.local || !w.accepts(t.typ) {
+			return resources{}, false
+		}
+		need := taskResources(t.typ, s.ssize).clamp(w.total)
+		return need, need.fits(w.used, w.total)
+	}
+
+	if holder, ok := s.workers[s.holders[t.sectorID]]; ok {
+		if need, ok := fits(holder); ok {
+			return holder, need
+		}
+	}
+
+	var best *workerHandle
+	var bestNeed resources
+	for _, w := range s.workers {
+		need, ok := fits(w)
+		if !ok {
+			continue
+		}
+		if best == nil || len(w.tasks) < len(best.tasks) || (len(w.tasks) == len(best.tasks) && w.id < best.id) {
+			best = w
+			bestNeed = need
+		}
+	}
+
+	return best, bestNeed
+}
+
+func (s *Scheduler) send(w *workerHandle, task api.WorkerTask) {
+	select {
+	case w.out <- task:
+	case <-w.ctx.Done():
+		// the task is requeued when the worker is removed
+	}
+}
+
+// List returns the state of connected workers
+func (s *Scheduler) List() []api.WorkerState {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	out := make([]api.WorkerState, 0, len(s.workers))
+	for _, w := range s.workers {
+		ws := api.WorkerState{
+			ID:    w.id,
+			Local: w.local,
+			Info:  w.info,
+
+			CoresUsed:     w.used.cores,
+			MemoryUsed:    w.used.memory,
+			DiskUsed:      w.used.disk,
+			TransfersUsed: w.used.transfers,
+		}
+
+		for _, t := range w.tasks {
+			ws.Tasks = append(ws.Tasks, api.WorkerTaskInfo{
+				TaskID:   t.id,
+				Type:     t.typ,
+				SectorID: t.sectorID,
+				Started:  t.started,
+
+				Progress: t.progress,
+				Size:     t.size,
+			})
+		}
+		sort.Slice(ws.Tasks, func(i, j int) bool {
+			return ws.Tasks[i].TaskID < ws.Tasks[j].TaskID
+		})
+
+		out = append(out, ws)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
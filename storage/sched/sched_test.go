@@ -0,0 +1,206 @@
+package sched
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	sectorbuilder "github.com/filecoin-project/go-sectorbuilder"
+
+	"github.com/filecoin-project/lotus/api"
+)
+
+type testSealer struct {
+	lk    sync.Mutex
+	slots []chan sectorbuilder.WorkerTask
+	done  map[uint64]sectorbuilder.SealRes
+}
+
+func (ts *testSealer) AddWorker(ctx context.Context, cfg sectorbuilder.WorkerCfg) (<-chan sectorbuilder.WorkerTask, error) {
+	ts.lk.Lock()
+	defer ts.lk.Unlock()
+
+	ch := make(chan sectorbuilder.WorkerTask, 1)
+	ts.slots = append(ts.slots, ch)
+	return ch, nil
+}
+
+func (ts *testSealer) TaskDone(ctx context.Context, id uint64, res sectorbuilder.SealRes) error {
+	ts.lk.Lock()
+	defer ts.lk.Unlock()
+
+	ts.done[id] = res
+	return nil
+}
+
+func (ts *testSealer) push(t *testing.T, slot int, task sectorbuilder.WorkerTask) {
+	ts.lk.Lock()
+	defer ts.lk.Unlock()
+
+	if slot >= len(ts.slots) {
+		t.Fatalf("slot %d not registered, have %d", slot, len(ts.slots))
+	}
+	ts.slots[slot] <- task
+}
+
+func recvTask(t *testing.T, tasks <-chan api.WorkerTask) api.WorkerTask {
+	t.Helper()
+
+	select {
+	case task := <-tasks:
+		return task
+	case <-time.After(time.Second):
+		t.Fatal("no task assigned")
+		return api.WorkerTask{}
+	}
+}
+
+func noTask(t *testing.T, tasks <-chan api.WorkerTask) {
+	t.Helper()
+
+	select {
+	case task := <-tasks:
+		t.Fatalf("unexpected task %d assigned", task.TaskID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSchedulerRequeue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ts := &testSealer{done: map[uint64]sectorbuilder.SealRes{}}
+	s := New(ctx, ts, 1024, api.WorkerInfo{TaskTypes: []api.WorkerTaskType{api.TaskUnseal, api.TaskFetch}})
+
+	wctx, disconnect := context.WithCancel(ctx)
+	w1, err := s.AddWorker(wctx, api.WorkerInfo{Cores: 1, TaskTypes: []api.WorkerTaskType{api.TaskPreCommit}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts.slots) != 1 {
+		t.Fatalf("expected 1 slot, got %d", len(ts.slots))
+	}
+
+	ts.push(t, 0, sectorbuilder.WorkerTask{Type: sectorbuilder.WorkerPreCommit, TaskID: 10, SectorID: 1})
+	task := recvTask(t, w1)
+	if task.SectorID != 1 || task.Type != api.TaskPreCommit || task.Seal.TaskID != 10 {
+		t.Fatalf("unexpected task: %+v", task)
+	}
+
+	disconnect()
+
+	// the task waits for a worker with resources
+	w2, err := s.AddWorker(ctx, api.WorkerInfo{Cores: 2, TaskTypes: []api.WorkerTaskType{api.TaskPreCommit}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requeued := recvTask(t, w2)
+	if requeued.Seal.TaskID != 10 || requeued.TaskID == task.TaskID {
+		t.Fatalf("expected task %d to be requeued with a new ID, got %+v", task.TaskID, requeued)
+	}
+
+	// the disconnected worker may still finish the task
+	if err := s.TaskDone(ctx, task.TaskID, sectorbuilder.SealRes{}); err == nil {
+		t.Fatal("expected the stale result to be rejected")
+	}
+	if len(ts.done) != 0 {
+		t.Fatal("stale result passed to the sectorbuilder")
+	}
+
+	if err := s.TaskDone(ctx, requeued.TaskID, sectorbuilder.SealRes{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ts.done[10]; !ok {
+		t.Fatal("result not passed to the sectorbuilder")
+	}
+
+	workers := s.List()
+	if len(workers) != 2 || !workers[0].Local || len(workers[1].Tasks) != 0 {
+		t.Fatalf("unexpected workers: %+v", workers)
+	}
+}
+
+func TestSchedulerLocality(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ts := &testSealer{done: map[uint64]sectorbuilder.SealRes{}}
+	s := New(ctx, ts, 1024, api.WorkerInfo{})
+
+	types := []api.WorkerTaskType{api.TaskPreCommit, api.TaskCommit}
+	w1, err := s.AddWorker(ctx, api.WorkerInfo{Cores: 8, TaskTypes: types})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w2, err := s.AddWorker(ctx, api.WorkerInfo{Cores: 8, TaskTypes: types})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts.push(t, 0, sectorbuilder.WorkerTask{Type: sectorbuilder.WorkerPreCommit, TaskID: 1, SectorID: 5})
+	recvTask(t, w1)
+	ts.push(t, 1, sectorbuilder.WorkerTask{Type: sectorbuilder.WorkerPreCommit, TaskID: 2, SectorID: 6})
+	pc := recvTask(t, w2)
+
+	if err := s.TaskDone(ctx, pc.TaskID, sectorbuilder.SealRes{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ts.push(t, 2, sectorbuilder.WorkerTask{Type: sectorbuilder.WorkerPreCommit, TaskID: 3, SectorID: 7})
+	recvTask(t, w2)
+
+	// both workers run a task, w2 gets the commit as it holds the sector data
+	ts.push(t, 3, sectorbuilder.WorkerTask{Type: sectorbuilder.WorkerCommit, TaskID: 4, SectorID: 6})
+	c := recvTask(t, w2)
+	if c.SectorID != 6 || c.Type != api.TaskCommit {
+		t.Fatalf("unexpected task: %+v", c)
+	}
+	noTask(t, w1)
+}
+
+func TestSchedulerLocalLimits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := New(ctx, &testSealer{}, 1024, api.WorkerInfo{
+		Transfers: 1,
+		TaskTypes: []api.WorkerTaskType{api.TaskFetch},
+	})
+
+	release := make(chan struct{})
+	running := make(chan struct{})
+	go func() {
+		err := s.Schedule(ctx, api.TaskFetch, 1, func(ctx context.Context) error {
+			s.ReportProgress(ctx, 10, 100)
+			close(running)
+			<-release
+			return nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+	<-running
+
+	if tasks := s.List()[0].Tasks; len(tasks) != 1 || tasks[0].Progress != 10 || tasks[0].Size != 100 {
+		t.Fatalf("unexpected tasks: %+v", tasks)
+	}
+
+	// a second transfer waits for the first one
+	tctx, tcancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer tcancel()
+	err := s.Schedule(tctx, api.TaskFetch, 2, func(context.Context) error {
+		t.Error("transfer limit exceeded")
+		return nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	close(release)
+
+	if err := s.Schedule(ctx, api.TaskFetch, 2, func(context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -33,3 +33,19 @@ func (m *Miner) PledgeSector() error {
 func (m *Miner) ForceSectorState(ctx context.Context, id uint64, state api.SectorState) error {
 	return m.sealing.ForceSectorState(ctx, id, state)
 }
+
+func (m *Miner) RecoverSector(ctx context.Context, id uint64) error {
+	return m.sealing.RecoverSector(ctx, id)
+}
+
+func (m *Miner) RemoveSector(ctx context.Context, id uint64, removeData sealing.RemoveDataFn) error {
+	return m.sealing.RemoveSector(ctx, id, removeData)
+}
+
+func (m *Miner) TerminateSector(ctx context.Context, id uint64, removeData sealing.RemoveDataFn) error {
+	return m.sealing.TerminateSector(ctx, id, removeData)
+}
+
+func (m *Miner) SectorUpdates(ctx context.Context) (<-chan api.SectorUpdate, error) {
+	return m.sealing.Updates(ctx)
+}
@@ -395,7 +395,7 @@ func (t *SectorInfo) MarshalCBOR(w io.Writer) error {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write([]byte{174}); err != nil {
+	if _, err := w.Write([]byte{176}); err != nil {
 		return err
 	}
 
@@ -472,6 +472,22 @@ func (t *SectorInfo) MarshalCBOR(w io.Writer) error {
 		}
 	}
 
+	// t.WaitDealsStart (uint64) (uint64)
+	if len("WaitDealsStart") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"WaitDealsStart\" was too long")
+	}
+
+	if _, err := w.Write(cbg.CborEncodeMajorType(cbg.MajTextString, uint64(len("WaitDealsStart")))); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte("WaitDealsStart")); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(cbg.CborEncodeMajorType(cbg.MajUnsignedInt, uint64(t.WaitDealsStart))); err != nil {
+		return err
+	}
+
 	// t.CommD ([]uint8) (slice)
 	if len("CommD") > cbg.MaxLength {
 		return xerrors.Errorf("Value in field \"CommD\" was too long")
@@ -639,6 +655,28 @@ func (t *SectorInfo) MarshalCBOR(w io.Writer) error {
 		}
 	}
 
+	// t.RecoveryMsg (cid.Cid) (struct)
+	if len("RecoveryMsg") > cbg.MaxLength {
+		return xerrors.Errorf("Value in field \"RecoveryMsg\" was too long")
+	}
+
+	if _, err := w.Write(cbg.CborEncodeMajorType(cbg.MajTextString, uint64(len("RecoveryMsg")))); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte("RecoveryMsg")); err != nil {
+		return err
+	}
+
+	if t.RecoveryMsg == nil {
+		if _, err := w.Write(cbg.CborNull); err != nil {
+			return err
+		}
+	} else {
+		if err := cbg.WriteCid(w, *t.RecoveryMsg); err != nil {
+			return xerrors.Errorf("failed to write cid field t.RecoveryMsg: %w", err)
+		}
+	}
+
 	// t.LastErr (string) (string)
 	if len("LastErr") > cbg.MaxLength {
 		return xerrors.Errorf("Value in field \"LastErr\" was too long")
@@ -780,6 +818,17 @@ func (t *SectorInfo) UnmarshalCBOR(r io.Reader) error {
 				t.Pieces[i] = v
 			}
 
+			// t.WaitDealsStart (uint64) (uint64)
+		case "WaitDealsStart":
+
+			maj, extra, err = cbg.CborReadHeader(br)
+			if err != nil {
+				return err
+			}
+			if maj != cbg.MajUnsignedInt {
+				return fmt.Errorf("wrong type for uint64 field")
+			}
+			t.WaitDealsStart = uint64(extra)
 			// t.CommD ([]uint8) (slice)
 		case "CommD":
 
@@ -928,6 +977,31 @@ func (t *SectorInfo) UnmarshalCBOR(r io.Reader) error {
 					t.FaultReportMsg = &c
 				}
 
+			}
+			// t.RecoveryMsg (cid.Cid) (struct)
+		case "RecoveryMsg":
+
+			{
+
+				pb, err := br.PeekByte()
+				if err != nil {
+					return err
+				}
+				if pb == cbg.CborNull[0] {
+					var nbuf [1]byte
+					if _, err := br.Read(nbuf[:]); err != nil {
+						return err
+					}
+				} else {
+
+					c, err := cbg.ReadCid(br)
+					if err != nil {
+						return xerrors.Errorf("failed to read cid field t.RecoveryMsg: %w", err)
+					}
+
+					t.RecoveryMsg = &c
+				}
+
 			}
 			// t.LastErr (string) (string)
 		case "LastErr":
@@ -0,0 +1,305 @@
+package sealing
+
+import (
+	"context"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	sectorbuilder "github.com/filecoin-project/go-sectorbuilder"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/lib/padreader"
+	"github.com/filecoin-project/lotus/lib/statemachine"
+)
+
+type Config struct {
+	// WaitDealsDelay is how long a sector accepts new deals before it's sealed
+	WaitDealsDelay time.Duration
+
+	// StartEpochSealingBuffer is the number of epochs before the earliest deal
+	// proposal expiration in a sector at which sealing is started, even if the
+	// sector could accept more deals
+	StartEpochSealingBuffer uint64
+}
+
+// openSector is a sector in the WaitDeals state, which new pieces can be
+// added to
+type openSector struct {
+	pieceSizes []uint64 // including filler pieces
+	opened     time.Time
+
+	// pending is set while a piece allocated in the sector is being added.
+	// Only one piece is added to a sector at a time, so that pieces are
+	// written at the offsets they were allocated at.
+	pending bool
+	// packWhenDone is set when the sector should be packed once the pending
+	// piece is added
+	packWhenDone bool
+	// packing is set once the sector stops accepting pieces, until it leaves
+	// the WaitDeals state
+	packing bool
+
+	timer *time.Timer
+}
+
+func (s *openSector) used() uint64 {
+	return sumSizes(s.pieceSizes)
+}
+
+// alignmentGap returns the number of bytes of filler pieces required before
+// a piece of the given size, as pieces are aligned to their padded size
+func alignmentGap(used uint64, size uint64) uint64 {
+	pused := used + used/127
+	psize := size + size/127
+
+	rem := pused % psize
+	if rem == 0 {
+		return 0
+	}
+
+	gap := psize - rem
+	return gap - gap/128
+}
+
+func (m *Sealing) AllocatePiece(size uint64) (sectorID uint64, offset uint64, err error) {
+	if padreader.PaddedSize(size) != size {
+		return 0, 0, xerrors.Errorf("cannot allocate unpadded piece")
+	}
+
+	ubytes := sectorbuilder.UserBytesForSectorSize(m.sb.SectorSize())
+	if size > ubytes {
+		return 0, 0, xerrors.Errorf("piece bigger than sector: %d > %d", size, ubytes)
+	}
+
+	m.openLk.Lock()
+	defer m.openLk.Unlock()
+
+	sids := make([]uint64, 0, len(m.openSectors))
+	for sid := range m.openSectors {
+		sids = append(sids, sid)
+	}
+	sort.Slice(sids, func(i, j int) bool {
+		return sids[i] < sids[j]
+	})
+
+	for _, sid := range sids {
+		s := m.openSectors[sid]
+		if s.pending || s.packWhenDone || s.packing {
+			continue
+		}
+
+		used := s.used()
+		gap := alignmentGap(used, size)
+		if used+gap+size > ubytes {
+			continue
+		}
+
+		s.pending = true
+		return sid, used + gap, nil
+	}
+
+	sid, err := m.sb.AcquireSectorId()
+	if err != nil {
+		return 0, 0, xerrors.Errorf("acquiring sector ID: %w", err)
+	}
+
+	s := &openSector{
+		opened:  time.Now(),
+		pending: true,
+	}
+	m.openSectors[sid] = s
+
+	log.Infof("Creating sector %d for deals", sid)
+	if err := m.sectors.Send(sid, SectorStartDeals{id: sid, start: s.opened}); err != nil {
+		delete(m.openSectors, sid)
+		return 0, 0, xerrors.Errorf("starting sector: %w", err)
+	}
+
+	return sid, 0, nil
+}
+
+func (m *Sealing) SealPiece(ctx context.Context, size uint64, r io.Reader, sectorID uint64, dealID uint64) error {
+	log.Infof("Seal piece for deal %d", dealID)
+
+	m.openLk.Lock()
+	s, ok := m.openSectors[sectorID]
+	if !ok || !s.pending {
+		m.openLk.Unlock()
+		return xerrors.Errorf("no piece allocated in sector %d", sectorID)
+	}
+	existing := append([]uint64{}, s.pieceSizes...)
+	m.openLk.Unlock()
+
+	pieces, err := m.addPiece(ctx, sectorID, existing, size, r, dealID)
+
+	if len(pieces) > 0 {
+		if serr := m.sectors.Send(sectorID, SectorAddPiece{pieces: pieces}); serr != nil {
+			err = xerrors.Errorf("adding pieces to sector state: %w", serr)
+		}
+	}
+
+	m.openLk.Lock()
+	defer m.openLk.Unlock()
+
+	s.pending = false
+	for _, p := range pieces {
+		s.pieceSizes = append(s.pieceSizes, p.Size)
+	}
+
+	if s.packWhenDone || s.used() >= sectorbuilder.UserBytesForSectorSize(m.sb.SectorSize()) {
+		m.startPackingLocked(sectorID, s)
+	}
+
+	return err
+}
+
+// addPiece writes filler pieces aligning the new piece, and the piece itself,
+// to the sector. Pieces which were written are returned even on errors.
+func (m *Sealing) addPiece(ctx context.Context, sectorID uint64, existing []uint64, size uint64, r io.Reader, dealID uint64) ([]Piece, error) {
+	var out []Piece
+
+	if gap := alignmentGap(sumSizes(existing), size); gap > 0 {
+		fillerSizes, err := fillersFromRem(gap)
+		if err != nil {
+			return nil, err
+		}
+
+		fillers, err := m.pledgeSector(ctx, sectorID, existing, fillerSizes...)
+		if err != nil {
+			return nil, xerrors.Errorf("aligning piece (%v): %w", fillerSizes, err)
+		}
+
+		out = append(out, fillers...)
+		existing = append(existing, fillerSizes...)
+	}
+
+	ppi, err := m.sb.AddPiece(ctx, size, sectorID, r, existing)
+	if err != nil {
+		return out, xerrors.Errorf("adding piece to sector: %w", err)
+	}
+
+	return append(out, Piece{
+		DealID: dealID,
+
+		Size:  ppi.Size,
+		CommP: ppi.CommP[:],
+	}), nil
+}
+
+func (m *Sealing) handleWaitDeals(ctx statemachine.Context, sector SectorInfo) error {
+	deadline, err := m.dealsDeadline(ctx.Context(), sector)
+	if err != nil {
+		log.Warnf("getting deal deadline for sector %d: %+v", sector.SectorID, err)
+	}
+
+	m.openLk.Lock()
+	defer m.openLk.Unlock()
+
+	s, ok := m.openSectors[sector.SectorID]
+	if !ok {
+		// after a restart
+		s = &openSector{
+			pieceSizes: sector.existingPieces(),
+			opened:     time.Unix(int64(sector.WaitDealsStart), 0),
+		}
+		if sector.WaitDealsStart == 0 {
+			s.opened = time.Now() // opened before the start time was recorded
+		}
+		m.openSectors[sector.SectorID] = s
+	}
+
+	if s.packing {
+		return nil
+	}
+
+	wait := s.opened.Add(m.cfg.WaitDealsDelay)
+	if !deadline.IsZero() && deadline.Before(wait) {
+		wait = deadline
+	}
+
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+
+	sid := sector.SectorID
+	s.timer = time.AfterFunc(time.Until(wait), func() {
+		m.openLk.Lock()
+		defer m.openLk.Unlock()
+
+		if s, ok := m.openSectors[sid]; ok {
+			m.startPackingLocked(sid, s)
+		}
+	})
+
+	return nil
+}
+
+// dealsDeadline returns the time at which the sector should start sealing to
+// get the deals in it pre-committed before they expire, or zero time if there
+// are no deals in the sector
+func (m *Sealing) dealsDeadline(ctx context.Context, sector SectorInfo) (time.Time, error) {
+	if len(sector.Pieces) == 0 {
+		return time.Time{}, nil
+	}
+
+	head, err := m.api.ChainHead(ctx)
+	if err != nil {
+		return time.Time{}, xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	var earliest uint64
+	for _, piece := range sector.Pieces {
+		deal, err := m.api.StateMarketStorageDeal(ctx, piece.DealID, types.EmptyTSK)
+		if err != nil {
+			return time.Time{}, xerrors.Errorf("getting deal %d: %w", piece.DealID, err)
+		}
+
+		if earliest == 0 || deal.ProposalExpiration < earliest {
+			earliest = deal.ProposalExpiration
+		}
+	}
+
+	if earliest <= head.Height()+m.cfg.StartEpochSealingBuffer {
+		return time.Now(), nil
+	}
+
+	epochs := earliest - head.Height() - m.cfg.StartEpochSealingBuffer
+	if max := uint64(math.MaxInt64/time.Second) / build.BlockDelay; epochs > max {
+		epochs = max // filler deals never expire
+	}
+	return time.Now().Add(time.Duration(epochs*build.BlockDelay) * time.Second), nil
+}
+
+// startPackingLocked stops accepting new pieces into the sector, and moves
+// it to the Packing state. Must be called with openLk held.
+func (m *Sealing) startPackingLocked(sid uint64, s *openSector) {
+	if s.pending {
+		s.packWhenDone = true
+		return
+	}
+	if s.packing {
+		return
+	}
+
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.packing = true
+
+	log.Infow("sector done waiting for deals, starting packing", "sector", sid, "pieces", len(s.pieceSizes))
+	if err := m.sectors.Send(sid, SectorStartPacking{}); err != nil {
+		log.Errorf("starting packing of sector %d: %+v", sid, err)
+	}
+}
+
+func sumSizes(sizes []uint64) uint64 {
+	var sum uint64
+	for _, size := range sizes {
+		sum += size
+	}
+	return sum
+}
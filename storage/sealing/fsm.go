@@ -30,8 +30,12 @@ func (m *Sealing) Plan(events []statemachine.Event, user interface{}) (interface
 }
 
 var fsmPlanners = []func(events []statemachine.Event, state *SectorInfo) error{
-	api.UndefinedSectorState: planOne(on(SectorStart{}, api.Packing)),
-	api.Packing:              planOne(on(SectorPacked{}, api.Unsealed)),
+	api.UndefinedSectorState: planOne(
+		on(SectorStart{}, api.Packing),
+		on(SectorStartDeals{}, api.WaitDeals),
+	),
+	api.WaitDeals: planWaitDeals,
+	api.Packing:   planOne(on(SectorPacked{}, api.Unsealed)),
 	api.Unsealed: planOne(
 		on(SectorSealed{}, api.PreCommitting),
 		on(SectorSealFailed{}, api.SealFailed),
@@ -54,11 +58,13 @@ var fsmPlanners = []func(events []statemachine.Event, state *SectorInfo) error{
 
 	api.FinalizeSector: planOne(
 		on(SectorFinalized{}, api.Proving),
+		on(SectorFinalizeFailed{}, api.FinalizeFailed),
 	),
 
 	api.Proving: planOne(
 		on(SectorFaultReported{}, api.FaultReported),
 		on(SectorFaulty{}, api.Faulty),
+		on(SectorRecover{}, api.Recovering),
 	),
 
 	api.SealFailed: planOne(
@@ -69,11 +75,48 @@ var fsmPlanners = []func(events []statemachine.Event, state *SectorInfo) error{
 		on(SectorRetryWaitSeed{}, api.WaitSeed),
 		on(SectorSealFailed{}, api.SealFailed),
 	),
+	api.SealCommitFailed: planOne(
+		on(SectorRetryComputeProof{}, api.Committing),
+		on(SectorRetryWaitSeed{}, api.WaitSeed),
+		on(SectorRetryPreCommit{}, api.PreCommitting),
+	),
+	api.CommitFailed: planOne(
+		on(SectorRetryComputeProof{}, api.Committing),
+		on(SectorRetryCommitWait{}, api.CommitWait),
+		on(SectorRetryWaitSeed{}, api.WaitSeed),
+		on(SectorRetryPreCommit{}, api.PreCommitting),
+		on(SectorProving{}, api.FinalizeSector),
+	),
+	api.PackingFailed: planOne(
+		on(SectorRetrySeal{}, api.Unsealed),
+	),
+	api.FinalizeFailed: planOne(
+		on(SectorRetryFinalize{}, api.FinalizeSector),
+	),
 
 	api.Faulty: planOne(
 		on(SectorFaultReported{}, api.FaultReported),
 	),
-	api.FaultedFinal: final,
+	api.FaultReported: planOne(
+		on(SectorFaultedFinal{}, api.FaultedFinal),
+		on(SectorRetryFaultReported{}, api.FaultReported),
+		on(SectorRetryFaulty{}, api.Faulty),
+	),
+	api.FaultedFinal: planOne(
+		on(SectorRecover{}, api.Recovering),
+	),
+
+	api.Recovering: planOne(
+		on(SectorRecoveryReported{}, api.RecoveryReported),
+		on(SectorRecovered{}, api.Proving),
+		on(SectorRetryRecover{}, api.Recovering),
+		on(SectorFaultedFinal{}, api.FaultedFinal),
+	),
+	api.RecoveryReported: planOne(
+		on(SectorRecovered{}, api.Proving),
+		on(SectorRetryRecover{}, api.Recovering),
+		on(SectorFaultedFinal{}, api.FaultedFinal),
+	),
 }
 
 func (m *Sealing) plan(events []statemachine.Event, state *SectorInfo) (func(statemachine.Context, SectorInfo) error, error) {
@@ -99,9 +142,11 @@ func (m *Sealing) plan(events []statemachine.Event, state *SectorInfo) (func(sta
 		return nil, xerrors.Errorf("planner for state %s not found", api.SectorStates[state.State])
 	}
 
+	from := state.State
 	if err := p(events, state); err != nil {
 		return nil, xerrors.Errorf("running planner for state %s failed: %w", api.SectorStates[state.State], err)
 	}
+	m.notify(state.SectorID, from, state.State, events)
 
 	/////
 	// Now decide what to do next
@@ -111,11 +156,14 @@ func (m *Sealing) plan(events []statemachine.Event, state *SectorInfo) (func(sta
 		*   Empty
 		|   |
 		|   v
+		*<- WaitDeals <- incoming deals
+		|   |
+		|   v
 		*<- Packing <- incoming
 		|   |
 		|   v
 		*<- Unsealed <--> SealFailed
-		|   |
+		|   |    ^--> PackingFailed
 		|   v
 		*   PreCommitting <--> PreCommitFailed
 		|   |                  ^
@@ -129,10 +177,22 @@ func (m *Sealing) plan(events []statemachine.Event, state *SectorInfo) (func(sta
 		*<- CommitWait ---/
 		|   |
 		|   v
-		*<- Proving
+		*<- FinalizeSector <--> FinalizeFailed
+		|   |
+		|   v
+		*<- Proving <-------------\
+		|   |                     |
+		|   v                     |
+		|   Faulty                RecoveryReported
+		|   |                     ^
+		|   v                     |
+		|   FaultReported         Recovering
+		|   |                     ^
+		|   v                     |
+		|   FaultedFinal ---------/
 		|
 		v
-		FailedUnrecoverable
+		FailedUnrecoverable <- failed states, after maxRetries
 
 		UndefinedSectorState <- ¯\_(ツ)_/¯
 		    |                     ^
@@ -142,6 +202,8 @@ func (m *Sealing) plan(events []statemachine.Event, state *SectorInfo) (func(sta
 
 	switch state.State {
 	// Happy path
+	case api.WaitDeals:
+		return m.handleWaitDeals, nil
 	case api.Packing:
 		return m.handlePacking, nil
 	case api.Unsealed:
@@ -166,15 +228,23 @@ func (m *Sealing) plan(events []statemachine.Event, state *SectorInfo) (func(sta
 	case api.PreCommitFailed:
 		return m.handlePreCommitFailed, nil
 	case api.SealCommitFailed:
-		log.Warnf("sector %d entered unimplemented state 'SealCommitFailed'", state.SectorID)
+		return m.handleSealCommitFailed, nil
 	case api.CommitFailed:
-		log.Warnf("sector %d entered unimplemented state 'CommitFailed'", state.SectorID)
+		return m.handleCommitFailed, nil
+	case api.PackingFailed:
+		return m.handlePackingFailed, nil
+	case api.FinalizeFailed:
+		return m.handleFinalizeFailed, nil
 
 		// Faults
 	case api.Faulty:
 		return m.handleFaulty, nil
 	case api.FaultReported:
 		return m.handleFaultReported, nil
+	case api.Recovering:
+		return m.handleRecovering, nil
+	case api.RecoveryReported:
+		return m.handleRecoveryReported, nil
 
 	// Fatal errors
 	case api.UndefinedSectorState:
@@ -188,6 +258,27 @@ func (m *Sealing) plan(events []statemachine.Event, state *SectorInfo) (func(sta
 	return nil, nil
 }
 
+// planWaitDeals handles pieces being added to the sector, which may happen
+// multiple times before the sector is packed
+func planWaitDeals(events []statemachine.Event, state *SectorInfo) error {
+	for _, event := range events {
+		switch e := event.User.(type) {
+		case globalMutator:
+			if e.applyGlobal(state) {
+				return nil
+			}
+		case SectorAddPiece:
+			e.apply(state)
+		case SectorStartPacking:
+			e.apply(state)
+			state.State = api.Packing
+		default:
+			return xerrors.Errorf("planWaitDeals got event of unknown type %T, events: %+v", event.User, events)
+		}
+	}
+	return nil
+}
+
 func planCommitting(events []statemachine.Event, state *SectorInfo) error {
 	for _, event := range events {
 		switch e := event.User.(type) {
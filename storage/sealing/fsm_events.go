@@ -1,6 +1,9 @@
 package sealing
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/ipfs/go-cid"
 
 	"github.com/filecoin-project/lotus/api"
@@ -54,6 +57,28 @@ func (evt SectorStart) apply(state *SectorInfo) {
 	state.Pieces = evt.pieces
 }
 
+type SectorStartDeals struct {
+	id    uint64
+	start time.Time
+}
+
+func (evt SectorStartDeals) apply(state *SectorInfo) {
+	state.SectorID = evt.id
+	state.WaitDealsStart = uint64(evt.start.Unix())
+}
+
+type SectorAddPiece struct {
+	pieces []Piece
+}
+
+func (evt SectorAddPiece) apply(state *SectorInfo) {
+	state.Pieces = append(state.Pieces, evt.pieces...)
+}
+
+type SectorStartPacking struct{}
+
+func (evt SectorStartPacking) apply(*SectorInfo) {}
+
 type SectorPacked struct{ pieces []Piece }
 
 func (evt SectorPacked) apply(state *SectorInfo) {
@@ -74,6 +99,7 @@ func (evt SectorSealed) apply(state *SectorInfo) {
 	state.CommD = evt.commD
 	state.CommR = evt.commR
 	state.Ticket = evt.ticket
+	state.Nonce = 0
 }
 
 type SectorSealFailed struct{ error }
@@ -98,6 +124,7 @@ type SectorSeedReady struct {
 
 func (evt SectorSeedReady) apply(state *SectorInfo) {
 	state.Seed = evt.seed
+	state.Nonce = 0 // the sector is pre-committed
 }
 
 type SectorComputeProofFailed struct{ error }
@@ -118,11 +145,15 @@ func (evt SectorCommitted) apply(state *SectorInfo) {
 
 type SectorProving struct{}
 
-func (evt SectorProving) apply(*SectorInfo) {}
+func (evt SectorProving) apply(state *SectorInfo) {
+	state.Nonce = 0 // the sector is committed
+}
 
 type SectorFinalized struct{}
 
-func (evt SectorFinalized) apply(*SectorInfo) {}
+func (evt SectorFinalized) apply(state *SectorInfo) {
+	state.Nonce = 0
+}
 
 type SectorFinalizeFailed struct{ error }
 
@@ -132,15 +163,38 @@ func (evt SectorFinalizeFailed) apply(*SectorInfo) {}
 
 type SectorRetrySeal struct{}
 
-func (evt SectorRetrySeal) apply(state *SectorInfo) {}
+func (evt SectorRetrySeal) apply(state *SectorInfo) { state.Nonce++ }
 
 type SectorRetryPreCommit struct{}
 
-func (evt SectorRetryPreCommit) apply(state *SectorInfo) {}
+func (evt SectorRetryPreCommit) apply(state *SectorInfo) { state.Nonce++ }
 
 type SectorRetryWaitSeed struct{}
 
-func (evt SectorRetryWaitSeed) apply(state *SectorInfo) {}
+func (evt SectorRetryWaitSeed) apply(state *SectorInfo) { state.Nonce++ }
+
+type SectorRetryComputeProof struct{}
+
+func (evt SectorRetryComputeProof) apply(state *SectorInfo) { state.Nonce++ }
+
+type SectorRetryCommitWait struct{}
+
+func (evt SectorRetryCommitWait) apply(state *SectorInfo) { state.Nonce++ }
+
+type SectorRetryFinalize struct{}
+
+func (evt SectorRetryFinalize) apply(state *SectorInfo) { state.Nonce++ }
+
+// SectorUnrecoverable moves the sector to FailedUnrecoverable, either because
+// it ran out of retries, or because the failure can't be recovered from
+type SectorUnrecoverable struct{ error }
+
+func (evt SectorUnrecoverable) applyGlobal(state *SectorInfo) bool {
+	log.Errorf("sector %d failed unrecoverably: %+v", state.SectorID, evt.error)
+	state.LastErr = fmt.Sprint(evt.error)
+	state.State = api.FailedUnrecoverable
+	return true
+}
 
 // Faults
 
@@ -154,4 +208,55 @@ func (evt SectorFaultReported) apply(state *SectorInfo) {
 	state.FaultReportMsg = &evt.reportMsg
 }
 
+type SectorRetryFaulty struct{}
+
+func (evt SectorRetryFaulty) apply(state *SectorInfo) {
+	state.FaultReportMsg = nil
+	state.Nonce++
+}
+
+type SectorRetryFaultReported struct{}
+
+func (evt SectorRetryFaultReported) apply(state *SectorInfo) { state.Nonce++ }
+
 type SectorFaultedFinal struct{}
+
+func (evt SectorFaultedFinal) apply(state *SectorInfo) {
+	state.Nonce = 0
+}
+
+// Fault recovery
+
+type SectorRecover struct{}
+
+func (evt SectorRecover) apply(state *SectorInfo) {
+	// retries left from reporting the fault don't count towards recovery
+	state.Nonce = 0
+}
+
+type SectorRecoveryReported struct{ recoveryMsg cid.Cid }
+
+func (evt SectorRecoveryReported) apply(state *SectorInfo) {
+	state.RecoveryMsg = &evt.recoveryMsg
+}
+
+type SectorRetryRecover struct{}
+
+func (evt SectorRetryRecover) apply(state *SectorInfo) {
+	state.RecoveryMsg = nil
+	state.Nonce++
+}
+
+type SectorRecovered struct{}
+
+func (evt SectorRecovered) apply(state *SectorInfo) {
+	state.FaultReportMsg = nil
+	state.RecoveryMsg = nil
+	state.Nonce = 0
+}
+
+// Removal
+
+// SectorRemoved isn't sent to the state machine, which is ended with the
+// sector. It's only published to sector update subscribers.
+type SectorRemoved struct{}
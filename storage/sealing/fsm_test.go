@@ -1,10 +1,14 @@
 package sealing
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/stretchr/testify/require"
+	lps "github.com/whyrusleeping/pubsub"
+	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/lib/statemachine"
@@ -56,6 +60,34 @@ func TestHappyPath(t *testing.T) {
 	require.Equal(m.t, m.state.State, api.Proving)
 }
 
+func TestWaitDeals(t *testing.T) {
+	m := test{
+		s:     &Sealing{},
+		t:     t,
+		state: &SectorInfo{State: api.UndefinedSectorState},
+	}
+
+	m.planSingle(SectorStartDeals{id: 5, start: time.Unix(1000, 0)})
+	require.Equal(m.t, m.state.State, api.WaitDeals)
+	require.Equal(m.t, uint64(5), m.state.SectorID)
+	require.Equal(m.t, uint64(1000), m.state.WaitDealsStart)
+
+	m.planSingle(SectorAddPiece{pieces: []Piece{{DealID: 1}}})
+	require.Equal(m.t, m.state.State, api.WaitDeals)
+
+	// pieces added while the previous step is running are planned together
+	_, err := m.s.plan([]statemachine.Event{
+		{SectorAddPiece{pieces: []Piece{{DealID: 2}, {DealID: 3}}}},
+		{SectorStartPacking{}},
+	}, m.state)
+	require.NoError(t, err)
+	require.Equal(m.t, m.state.State, api.Packing)
+	require.Equal(m.t, []uint64{1, 2, 3}, m.state.deals())
+
+	m.planSingle(SectorPacked{})
+	require.Equal(m.t, m.state.State, api.Unsealed)
+}
+
 func TestSeedRevert(t *testing.T) {
 	m := test{
 		s:     &Sealing{},
@@ -103,3 +135,159 @@ func TestPlanCommittingHandlesSectorCommitFailed(t *testing.T) {
 
 	require.Equal(t, api.SectorStates[api.CommitFailed], api.SectorStates[m.state.State])
 }
+
+func TestRecoveryTransitions(t *testing.T) {
+	cases := []struct {
+		name  string
+		from  api.SectorState
+		event interface{}
+		to    api.SectorState
+	}{
+		{"packing failed", api.Unsealed, SectorPackingFailed{}, api.PackingFailed},
+		{"packing retry", api.PackingFailed, SectorRetrySeal{}, api.Unsealed},
+
+		{"compute proof failed", api.Committing, SectorComputeProofFailed{}, api.SealCommitFailed},
+		{"seal commit retry proof", api.SealCommitFailed, SectorRetryComputeProof{}, api.Committing},
+		{"seal commit retry seed", api.SealCommitFailed, SectorRetryWaitSeed{}, api.WaitSeed},
+		{"seal commit retry precommit", api.SealCommitFailed, SectorRetryPreCommit{}, api.PreCommitting},
+
+		{"commit wait failed", api.CommitWait, SectorCommitFailed{}, api.CommitFailed},
+		{"commit retry proof", api.CommitFailed, SectorRetryComputeProof{}, api.Committing},
+		{"commit retry wait", api.CommitFailed, SectorRetryCommitWait{}, api.CommitWait},
+		{"commit retry seed", api.CommitFailed, SectorRetryWaitSeed{}, api.WaitSeed},
+		{"commit retry precommit", api.CommitFailed, SectorRetryPreCommit{}, api.PreCommitting},
+		{"commit found on chain", api.CommitFailed, SectorProving{}, api.FinalizeSector},
+
+		{"finalize failed", api.FinalizeSector, SectorFinalizeFailed{}, api.FinalizeFailed},
+		{"finalize retry", api.FinalizeFailed, SectorRetryFinalize{}, api.FinalizeSector},
+
+		{"fault declared", api.FaultReported, SectorFaultedFinal{}, api.FaultedFinal},
+		{"fault wait retry", api.FaultReported, SectorRetryFaultReported{}, api.FaultReported},
+		{"fault declare retry", api.FaultReported, SectorRetryFaulty{}, api.Faulty},
+
+		{"gave up", api.CommitFailed, SectorUnrecoverable{xerrors.New("retries")}, api.FailedUnrecoverable},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := test{
+				s:     &Sealing{},
+				t:     t,
+				state: &SectorInfo{State: c.from},
+			}
+
+			m.planSingle(c.event)
+			require.Equal(t, api.SectorStates[c.to], api.SectorStates[m.state.State])
+		})
+	}
+}
+
+func TestRetryCount(t *testing.T) {
+	m := test{
+		s:     &Sealing{},
+		t:     t,
+		state: &SectorInfo{State: api.CommitFailed},
+	}
+
+	m.planSingle(SectorRetryComputeProof{})
+	m.planSingle(SectorComputeProofFailed{})
+	m.planSingle(SectorRetryComputeProof{})
+	require.Equal(t, uint64(2), m.state.Nonce)
+
+	// the counter is reset once the commit lands, finalizing is retried
+	// separately
+	m.planSingle(SectorCommitted{})
+	m.planSingle(SectorProving{})
+	require.Equal(t, uint64(0), m.state.Nonce)
+	m.planSingle(SectorFinalizeFailed{})
+	m.planSingle(SectorRetryFinalize{})
+	require.Equal(t, uint64(1), m.state.Nonce)
+
+	m.planSingle(SectorFinalized{})
+	require.Equal(t, api.Proving, m.state.State)
+	require.Equal(t, uint64(0), m.state.Nonce)
+
+	m.planSingle(SectorFaultReported{})
+	m.planSingle(SectorRetryFaulty{})
+	require.Equal(t, api.Faulty, m.state.State)
+	require.Nil(t, m.state.FaultReportMsg)
+	require.Equal(t, uint64(1), m.state.Nonce)
+}
+
+func TestFaultRecovery(t *testing.T) {
+	m := test{
+		s:     &Sealing{},
+		t:     t,
+		state: &SectorInfo{State: api.Proving},
+	}
+
+	m.planSingle(SectorFaulty{})
+	require.Equal(m.t, m.state.State, api.Faulty)
+
+	m.planSingle(SectorFaultReported{})
+	require.Equal(m.t, m.state.State, api.FaultReported)
+
+	m.planSingle(SectorRetryFaultReported{})
+	require.Equal(m.t, uint64(1), m.state.Nonce)
+
+	m.planSingle(SectorFaultedFinal{})
+	require.Equal(m.t, m.state.State, api.FaultedFinal)
+
+	m.planSingle(SectorRecover{})
+	require.Equal(m.t, m.state.State, api.Recovering)
+	require.Equal(m.t, uint64(0), m.state.Nonce)
+
+	m.planSingle(SectorRecoveryReported{})
+	require.Equal(m.t, m.state.State, api.RecoveryReported)
+	require.NotNil(m.t, m.state.RecoveryMsg)
+
+	m.planSingle(SectorRetryRecover{})
+	require.Equal(m.t, m.state.State, api.Recovering)
+	require.Nil(m.t, m.state.RecoveryMsg)
+	require.Equal(m.t, uint64(1), m.state.Nonce)
+
+	// giving up on the recovery leaves the sector faulty
+	m.planSingle(SectorRecoveryReported{})
+	m.planSingle(SectorFaultedFinal{})
+	require.Equal(m.t, m.state.State, api.FaultedFinal)
+	require.Equal(m.t, uint64(0), m.state.Nonce)
+
+	m.planSingle(SectorRecover{})
+	require.Equal(m.t, m.state.State, api.Recovering)
+
+	m.planSingle(SectorRecoveryReported{})
+	m.planSingle(SectorRecovered{})
+	require.Equal(m.t, m.state.State, api.Proving)
+	require.Nil(m.t, m.state.FaultReportMsg)
+	require.Equal(m.t, uint64(0), m.state.Nonce)
+
+	// faults declared by the PoSt scheduler leave sectors in Proving
+	m.planSingle(SectorRecover{})
+	require.Equal(m.t, m.state.State, api.Recovering)
+
+	m.planSingle(SectorRecovered{})
+	require.Equal(m.t, m.state.State, api.Proving)
+}
+
+func TestSectorUpdates(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := test{
+		s:     &Sealing{changes: lps.New(10)},
+		t:     t,
+		state: &SectorInfo{SectorID: 3, State: api.Unsealed},
+	}
+
+	updates, err := m.s.Updates(ctx)
+	require.NoError(t, err)
+
+	m.planSingle(SectorSealFailed{xerrors.New("test error")})
+
+	u := <-updates
+	require.Equal(t, uint64(3), u.SectorID)
+	require.Equal(t, api.Unsealed, u.From)
+	require.Equal(t, api.SealFailed, u.To)
+	require.Equal(t, "SectorSealFailed", u.Event)
+	require.Contains(t, u.Error, "test error")
+}
@@ -0,0 +1,65 @@
+package sealing
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/lib/statemachine"
+)
+
+const sectorUpdates = "sectorUpdates"
+
+// notify publishes a state transition of a sector, for each of the events
+// which caused it
+func (m *Sealing) notify(sectorID uint64, from, to api.SectorState, events []statemachine.Event) {
+	if m.changes == nil {
+		return
+	}
+
+	for _, event := range events {
+		u := api.SectorUpdate{
+			SectorID:  sectorID,
+			From:      from,
+			To:        to,
+			Event:     reflect.TypeOf(event.User).Name(),
+			Timestamp: time.Now(),
+		}
+		if err, ok := event.User.(error); ok {
+			u.Error = fmt.Sprintf("%+v", err)
+		}
+
+		m.changes.Pub(u, sectorUpdates)
+	}
+}
+
+// Updates streams sector state transitions. Updates are dropped when the
+// subscriber doesn't keep up, so that a slow subscriber can't block sealing.
+func (m *Sealing) Updates(ctx context.Context) (<-chan api.SectorUpdate, error) {
+	out := make(chan api.SectorUpdate, 64)
+	sub := m.changes.Sub(sectorUpdates)
+
+	go func() {
+		defer m.changes.Unsub(sub, sectorUpdates)
+		defer close(out)
+
+		for {
+			select {
+			case u := <-sub:
+				select {
+				case out <- u.(api.SectorUpdate):
+				case <-ctx.Done():
+					return
+				default:
+					log.Warnw("dropping sector update, subscriber too slow", "sector", u.(api.SectorUpdate).SectorID)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
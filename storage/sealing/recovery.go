@@ -0,0 +1,144 @@
+package sealing
+
+import (
+	"context"
+	"time"
+
+	ffi "github.com/filecoin-project/filecoin-ffi"
+	sectorbuilder "github.com/filecoin-project/go-sectorbuilder"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// faultScrubInterval is how often sectors which are faulty on chain are
+// checked for recovery
+const faultScrubInterval = 10 * time.Minute
+
+func (m *Sealing) watchFaults(ctx context.Context) {
+	tick := time.NewTicker(faultScrubInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-tick.C:
+			if err := m.recoverFaults(ctx); err != nil {
+				log.Errorf("checking faulty sectors: %+v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// recoverFaults scrubs sectors which are faulty on chain, and starts the
+// recovery of ones which are readable again
+func (m *Sealing) recoverFaults(ctx context.Context) error {
+	canDeclare, err := m.canDeclareRecoveries(ctx)
+	if err != nil {
+		return err
+	}
+	if !canDeclare {
+		return nil
+	}
+
+	faults, err := m.api.StateMinerFaults(ctx, m.maddr, types.EmptyTSK)
+	if err != nil {
+		return xerrors.Errorf("getting miner faults: %w", err)
+	}
+
+	var faulty []SectorInfo
+	for _, id := range faults {
+		sector, err := m.GetSectorInfo(id)
+		if err != nil {
+			log.Warnf("faulty sector %d isn't tracked: %+v", id, err)
+			continue
+		}
+
+		if canRecover(sector.State) {
+			faulty = append(faulty, sector)
+		}
+	}
+
+	if len(faulty) == 0 {
+		return nil
+	}
+
+	scrubbed := m.scrub(faulty)
+	for _, sector := range faulty {
+		if err, ok := scrubbed[sector.SectorID]; ok {
+			log.Debugf("sector %d is still faulty: %s", sector.SectorID, err)
+			continue
+		}
+
+		log.Infof("faulty sector %d is readable again, declaring recovery", sector.SectorID)
+		if err := m.sectors.Send(sector.SectorID, SectorRecover{}); err != nil {
+			log.Errorf("recovering sector %d: %+v", sector.SectorID, err)
+		}
+	}
+
+	return nil
+}
+
+func (m *Sealing) RecoverSector(ctx context.Context, id uint64) error {
+	sector, err := m.GetSectorInfo(id)
+	if err != nil {
+		return xerrors.Errorf("getting sector info: %w", err)
+	}
+
+	if !canRecover(sector.State) {
+		return xerrors.Errorf("can't recover sector %d in state %s", id, api.SectorStates[sector.State])
+	}
+
+	canDeclare, err := m.canDeclareRecoveries(ctx)
+	if err != nil {
+		return err
+	}
+	if !canDeclare {
+		return xerrors.Errorf("recoveries can't be declared before height %d", build.ForkWintergraspHeight)
+	}
+
+	if err, ok := m.scrub([]SectorInfo{sector})[id]; ok {
+		return xerrors.Errorf("sector %d is still faulty: %s", id, err)
+	}
+
+	return m.sectors.Send(id, SectorRecover{})
+}
+
+// scrub checks that the sectors can be read, returning errors for ones which
+// can't
+func (m *Sealing) scrub(sectors []SectorInfo) map[uint64]error {
+	sbsi := make([]ffi.PublicSectorInfo, len(sectors))
+	for i, sector := range sectors {
+		var commR [sectorbuilder.CommLen]byte
+		copy(commR[:], sector.CommR)
+
+		sbsi[i] = ffi.PublicSectorInfo{
+			SectorID: sector.SectorID,
+			CommR:    commR,
+		}
+	}
+
+	out := map[uint64]error{}
+	for _, fault := range m.sb.Scrub(sectorbuilder.NewSortedPublicSectorInfo(sbsi)) {
+		out[fault.SectorID] = fault.Err
+	}
+	return out
+}
+
+// canDeclareRecoveries checks whether the chain is past the fork which added
+// DeclareRecoveries. Before it, faulty sectors are dropped at the next PoSt.
+func (m *Sealing) canDeclareRecoveries(ctx context.Context) (bool, error) {
+	head, err := m.api.ChainHead(ctx)
+	if err != nil {
+		return false, xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	return head.Height() >= build.ForkWintergraspHeight, nil
+}
+
+func canRecover(state api.SectorState) bool {
+	return state == api.Proving || state == api.FaultedFinal
+}
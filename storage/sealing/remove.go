@@ -0,0 +1,201 @@
+package sealing
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/lib/statemachine"
+)
+
+// RemoveDataFn deletes the data of a sector from storage
+type RemoveDataFn func(sectorID uint64) error
+
+// RemoveSector removes a sector which isn't committed on chain, deleting its
+// data and its state
+func (m *Sealing) RemoveSector(ctx context.Context, id uint64, removeData RemoveDataFn) error {
+	sector, err := m.GetSectorInfo(id)
+	if err != nil {
+		return xerrors.Errorf("getting sector info: %w", err)
+	}
+
+	if !canRemove(sector.State) {
+		return xerrors.Errorf("can't remove sector %d in state %s", id, api.SectorStates[sector.State])
+	}
+
+	sector, err = m.halt(ctx, id, canRemove)
+	if err != nil {
+		return err
+	}
+
+	// checked with the sector halted, so that it can't get committed anymore
+	onChain, err := m.sectorOnChain(ctx, id)
+	if err != nil {
+		m.restart(id)
+		return err
+	}
+	if onChain {
+		m.restart(id)
+		return xerrors.Errorf("sector %d is committed on chain, it has to be terminated", id)
+	}
+
+	return m.removeSector(ctx, sector, removeData)
+}
+
+// TerminateSector terminates a committed sector on chain, removing its power,
+// and then deletes its data and its state
+func (m *Sealing) TerminateSector(ctx context.Context, id uint64, removeData RemoveDataFn) error {
+	sector, err := m.GetSectorInfo(id)
+	if err != nil {
+		return xerrors.Errorf("getting sector info: %w", err)
+	}
+
+	if !canTerminate(sector.State) {
+		return xerrors.Errorf("can't terminate sector %d in state %s", id, api.SectorStates[sector.State])
+	}
+
+	onChain, err := m.sectorOnChain(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !onChain {
+		return xerrors.Errorf("sector %d isn't committed on chain, it can be removed without terminating it", id)
+	}
+
+	// no fault or recovery handling while the sector is terminated
+	sector, err = m.halt(ctx, id, canTerminate)
+	if err != nil {
+		return err
+	}
+
+	if err := m.terminate(ctx, sector); err != nil {
+		m.restart(id)
+		return err
+	}
+
+	return m.removeSector(ctx, sector, removeData)
+}
+
+// terminate fails while the sector stores client deals which didn't expire yet
+func (m *Sealing) terminate(ctx context.Context, sector SectorInfo) error {
+	id := sector.SectorID
+
+	bf := types.NewBitField()
+	bf.Set(id)
+
+	enc, aerr := actors.SerializeParams(&actors.TerminateSectorsParams{
+		Sectors: bf,
+		Deals:   []actors.SectorDeals{{SectorID: id, DealIDs: sector.deals()}},
+	})
+	if aerr != nil {
+		return xerrors.Errorf("failed to serialize terminate sectors params: %w", aerr)
+	}
+
+	msg := &types.Message{
+		To:     m.maddr,
+		From:   m.worker,
+		Method: actors.MAMethods.TerminateSectors,
+		Params: enc,
+		Value:  types.NewInt(0),
+		// gas is left for MpoolPushMessage to estimate
+	}
+
+	smsg, err := m.api.MpoolPushMessage(ctx, msg)
+	if err != nil {
+		return xerrors.Errorf("pushing terminate sectors message: %w", err)
+	}
+
+	log.Infof("terminating sector %d in message %s", id, smsg.Cid())
+
+	mw, err := m.api.StateWaitMsg(ctx, smsg.Cid())
+	if err != nil {
+		return xerrors.Errorf("waiting for terminate sectors message: %w", err)
+	}
+
+	if mw.Receipt.ExitCode != 0 {
+		return xerrors.Errorf("terminating sector %d failed (exit=%d, msg=%s)", id, mw.Receipt.ExitCode, smsg.Cid())
+	}
+
+	return nil
+}
+
+// removeSector deletes the data and the state of a sector with a halted state
+// machine
+func (m *Sealing) removeSector(ctx context.Context, sector SectorInfo, removeData RemoveDataFn) error {
+	if err := removeData(sector.SectorID); err != nil {
+		m.restart(sector.SectorID)
+		return xerrors.Errorf("removing data of sector %d: %w", sector.SectorID, err)
+	}
+
+	if err := m.sectors.End(ctx, sector.SectorID); err != nil {
+		return xerrors.Errorf("removing state of sector %d: %w", sector.SectorID, err)
+	}
+
+	log.Infof("removed sector %d", sector.SectorID)
+	m.notify(sector.SectorID, sector.State, api.UndefinedSectorState, []statemachine.Event{{User: SectorRemoved{}}})
+	return nil
+}
+
+// halt stops the state machine of a sector, and checks that the state it
+// stopped in still allows the operation. The state may have changed since it
+// was first checked.
+func (m *Sealing) halt(ctx context.Context, id uint64, allowed func(api.SectorState) bool) (SectorInfo, error) {
+	if err := m.sectors.Halt(ctx, id); err != nil {
+		return SectorInfo{}, xerrors.Errorf("stopping sector %d: %w", id, err)
+	}
+
+	sector, err := m.GetSectorInfo(id)
+	if err != nil {
+		m.restart(id)
+		return SectorInfo{}, xerrors.Errorf("getting sector info: %w", err)
+	}
+
+	if !allowed(sector.State) {
+		m.restart(id)
+		return SectorInfo{}, xerrors.Errorf("sector %d moved to state %s", id, api.SectorStates[sector.State])
+	}
+
+	return sector, nil
+}
+
+func (m *Sealing) restart(id uint64) {
+	if err := m.sectors.Send(id, SectorRestart{}); err != nil {
+		log.Errorf("restarting sector %d: %+v", id, err)
+	}
+}
+
+func (m *Sealing) sectorOnChain(ctx context.Context, id uint64) (bool, error) {
+	sectors, err := m.api.StateMinerSectors(ctx, m.maddr, types.EmptyTSK)
+	if err != nil {
+		return false, xerrors.Errorf("getting miner sectors: %w", err)
+	}
+
+	for _, s := range sectors {
+		if s.SectorID == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// canRemove is true for failed sectors, which may not have been committed
+func canRemove(state api.SectorState) bool {
+	switch state {
+	case api.SealFailed, api.PreCommitFailed, api.SealCommitFailed, api.CommitFailed, api.PackingFailed, api.FailedUnrecoverable:
+		return true
+	}
+	return false
+}
+
+// canTerminate is true for committed sectors which aren't being recovered,
+// and for failed sectors which may have been committed
+func canTerminate(state api.SectorState) bool {
+	switch state {
+	case api.Proving, api.FinalizeFailed, api.Faulty, api.FaultReported, api.FaultedFinal:
+		return true
+	}
+	return canRemove(state)
+}
@@ -14,6 +14,10 @@ import (
 )
 
 func (m *Sealing) handlePacking(ctx statemachine.Context, sector SectorInfo) error {
+	m.openLk.Lock()
+	delete(m.openSectors, sector.SectorID)
+	m.openLk.Unlock()
+
 	log.Infow("performing filling up rest of the sector...", "sector", sector.SectorID)
 
 	var allocated uint64
@@ -287,13 +291,98 @@ func (m *Sealing) handleFaultReported(ctx statemachine.Context, sector SectorInf
 
 	mw, err := m.api.StateWaitMsg(ctx.Context(), *sector.FaultReportMsg)
 	if err != nil {
-		return xerrors.Errorf("failed to wait for fault declaration: %w", err)
+		log.Errorf("failed to wait for fault declaration (sector %d): %+v", sector.SectorID, err)
+		return retryFault(ctx, sector, SectorRetryFaultReported{})
 	}
 
 	if mw.Receipt.ExitCode != 0 {
-		log.Errorf("UNHANDLED: declaring sector fault failed (exit=%d, msg=%s) (id: %d)", mw.Receipt.ExitCode, *sector.FaultReportMsg, sector.SectorID)
-		return xerrors.Errorf("UNHANDLED: submitting fault declaration failed (exit %d)", mw.Receipt.ExitCode)
+		log.Errorf("declaring sector fault failed (exit=%d, msg=%s) (id: %d), declaring again", mw.Receipt.ExitCode, *sector.FaultReportMsg, sector.SectorID)
+		return retryFault(ctx, sector, SectorRetryFaulty{})
 	}
 
 	return ctx.Send(SectorFaultedFinal{})
 }
+
+func (m *Sealing) handleRecovering(ctx statemachine.Context, sector SectorInfo) error {
+	committed, err := m.checkCommitted(ctx, sector)
+	if err != nil {
+		log.Errorf("handleRecovering(%d): api error: %+v", sector.SectorID, err)
+		return retryRecovery(ctx, sector)
+	}
+	if !committed {
+		return ctx.Send(SectorUnrecoverable{xerrors.Errorf("sector %d was removed from the miner sector set", sector.SectorID)})
+	}
+
+	faults, err := m.api.StateMinerFaults(ctx.Context(), m.maddr, types.EmptyTSK)
+	if err != nil {
+		log.Errorf("handleRecovering(%d): getting miner faults: %+v", sector.SectorID, err)
+		return retryRecovery(ctx, sector)
+	}
+
+	var faulty bool
+	for _, fault := range faults {
+		if fault == sector.SectorID {
+			faulty = true
+			break
+		}
+	}
+	if !faulty {
+		log.Infof("sector %d isn't faulty on chain, nothing to recover", sector.SectorID)
+		return ctx.Send(SectorRecovered{})
+	}
+
+	canDeclare, err := m.canDeclareRecoveries(ctx.Context())
+	if err != nil {
+		log.Errorf("handleRecovering(%d): %+v", sector.SectorID, err)
+		return retryRecovery(ctx, sector)
+	}
+	if !canDeclare {
+		log.Warnf("sector %d: recoveries can't be declared before height %d", sector.SectorID, build.ForkWintergraspHeight)
+		return ctx.Send(SectorFaultedFinal{})
+	}
+
+	bf := types.NewBitField()
+	bf.Set(sector.SectorID)
+
+	enc, aerr := actors.SerializeParams(&actors.DeclareRecoveriesParams{Recoveries: bf})
+	if aerr != nil {
+		return xerrors.Errorf("failed to serialize declare recoveries params: %w", aerr)
+	}
+
+	msg := &types.Message{
+		To:     m.maddr,
+		From:   m.worker,
+		Method: actors.MAMethods.DeclareRecoveries,
+		Params: enc,
+		Value:  types.NewInt(0),
+		// gas is left for MpoolPushMessage to estimate
+	}
+
+	smsg, err := m.api.MpoolPushMessage(ctx.Context(), msg)
+	if err != nil {
+		log.Errorf("failed to push declare recoveries message (sector %d): %+v", sector.SectorID, err)
+		return retryRecovery(ctx, sector)
+	}
+
+	return ctx.Send(SectorRecoveryReported{recoveryMsg: smsg.Cid()})
+}
+
+func (m *Sealing) handleRecoveryReported(ctx statemachine.Context, sector SectorInfo) error {
+	if sector.RecoveryMsg == nil {
+		return xerrors.Errorf("entered recovery reported state without a RecoveryMsg cid")
+	}
+
+	mw, err := m.api.StateWaitMsg(ctx.Context(), *sector.RecoveryMsg)
+	if err != nil {
+		log.Errorf("failed to wait for recovery declaration (sector %d): %+v", sector.SectorID, err)
+		return retryRecovery(ctx, sector)
+	}
+
+	if mw.Receipt.ExitCode != 0 {
+		log.Errorf("declaring sector recovery failed (exit=%d, msg=%s) (id: %d)", mw.Receipt.ExitCode, *sector.RecoveryMsg, sector.SectorID)
+		return retryRecovery(ctx, sector)
+	}
+
+	log.Infof("sector %d recovered, it will be proven again after the next PoSt", sector.SectorID)
+	return ctx.Send(SectorRecovered{})
+}
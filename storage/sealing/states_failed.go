@@ -15,9 +15,17 @@ import (
 
 const minRetryTime = 1 * time.Minute
 
+// maxRetries is the number of times a failed step is retried before a sector
+// is considered unrecoverable. Retries are counted until the step succeeds.
+const maxRetries = 10
+
 func failedCooldown(ctx statemachine.Context, sector SectorInfo) error {
+	if len(sector.Log) == 0 {
+		return nil
+	}
+
 	retryStart := time.Unix(int64(sector.Log[len(sector.Log)-1].Timestamp), 0).Add(minRetryTime)
-	if len(sector.Log) > 0 && !time.Now().After(retryStart) {
+	if !time.Now().After(retryStart) {
 		log.Infof("%s(%d), waiting %s before retrying", api.SectorStates[sector.State], sector.SectorID, time.Until(retryStart))
 		select {
 		case <-time.After(time.Until(retryStart)):
@@ -29,6 +37,43 @@ func failedCooldown(ctx statemachine.Context, sector SectorInfo) error {
 	return nil
 }
 
+// retry sends the retry event after failedCooldown, or gives up on the sector
+// once it ran out of retries
+func retry(ctx statemachine.Context, sector SectorInfo, evt interface{}) error {
+	if sector.Nonce >= maxRetries {
+		return ctx.Send(SectorUnrecoverable{xerrors.Errorf("%s: giving up after %d retries", api.SectorStates[sector.State], sector.Nonce)})
+	}
+
+	if err := failedCooldown(ctx, sector); err != nil {
+		return err
+	}
+
+	return ctx.Send(evt)
+}
+
+// retryFault retries reporting a fault of a committed sector. Committed
+// sectors aren't given up on because fault reporting failed, so there is no
+// retry limit.
+func retryFault(ctx statemachine.Context, sector SectorInfo, evt interface{}) error {
+	if err := failedCooldown(ctx, sector); err != nil {
+		return err
+	}
+
+	return ctx.Send(evt)
+}
+
+// retryRecovery retries declaring the recovery of a committed sector. Once
+// it ran out of retries, the sector stays faulty, and the recovery is tried
+// again when the sector is next found readable.
+func retryRecovery(ctx statemachine.Context, sector SectorInfo) error {
+	if sector.Nonce >= maxRetries {
+		log.Errorf("sector %d: giving up on recovery after %d retries, it stays faulty", sector.SectorID, sector.Nonce)
+		return ctx.Send(SectorFaultedFinal{})
+	}
+
+	return retry(ctx, sector, SectorRetryRecover{})
+}
+
 func (m *Sealing) checkPreCommitted(ctx statemachine.Context, sector SectorInfo) (*actors.PreCommittedSector, bool) {
 	act, err := m.api.StateGetActor(ctx.Context(), m.maddr, types.EmptyTSK)
 	if err != nil {
@@ -65,11 +110,7 @@ func (m *Sealing) handleSealFailed(ctx statemachine.Context, sector SectorInfo)
 		return nil // noop, for now
 	}
 
-	if err := failedCooldown(ctx, sector); err != nil {
-		return err
-	}
-
-	return ctx.Send(SectorRetrySeal{})
+	return retry(ctx, sector, SectorRetrySeal{})
 }
 
 func (m *Sealing) handlePreCommitFailed(ctx statemachine.Context, sector SectorInfo) error {
@@ -101,20 +142,97 @@ func (m *Sealing) handlePreCommitFailed(ctx statemachine.Context, sector SectorI
 		// TODO: we could compare more things, but I don't think we really need to
 		//  CommR tells us that CommD (and CommPs), and the ticket are all matching
 
-		if err := failedCooldown(ctx, sector); err != nil {
-			return err
-		}
-
-		return ctx.Send(SectorRetryWaitSeed{})
+		return retry(ctx, sector, SectorRetryWaitSeed{})
 	}
 
 	if sector.PreCommitMessage != nil {
 		log.Warn("retrying precommit even though the message failed to apply")
 	}
 
-	if err := failedCooldown(ctx, sector); err != nil {
-		return err
+	return retry(ctx, sector, SectorRetryPreCommit{})
+}
+
+func (m *Sealing) checkCommitted(ctx statemachine.Context, sector SectorInfo) (bool, error) {
+	sectors, err := m.api.StateMinerSectors(ctx.Context(), m.maddr, types.EmptyTSK)
+	if err != nil {
+		return false, xerrors.Errorf("getting miner sectors: %w", err)
+	}
+
+	for _, s := range sectors {
+		if s.SectorID == sector.SectorID {
+			return true, nil
+		}
 	}
 
-	return ctx.Send(SectorRetryPreCommit{})
+	return false, nil
+}
+
+func (m *Sealing) handlePackingFailed(ctx statemachine.Context, sector SectorInfo) error {
+	if err := checkPieces(ctx.Context(), sector, m.api); err != nil {
+		switch err.(type) {
+		case *ErrApi:
+			log.Errorf("handlePackingFailed: api error, not proceeding: %+v", err)
+			return nil
+		case *ErrInvalidDeals, *ErrExpiredDeals:
+			// deal data is already written to the sector, there's no way to
+			// seal it without the deals
+			return ctx.Send(SectorUnrecoverable{xerrors.Errorf("checking pieces: %w", err)})
+		default:
+			return xerrors.Errorf("checkPieces sanity check error: %w", err)
+		}
+	}
+
+	return retry(ctx, sector, SectorRetrySeal{})
+}
+
+func (m *Sealing) handleSealCommitFailed(ctx statemachine.Context, sector SectorInfo) error {
+	return m.retryCommit(ctx, sector)
+}
+
+func (m *Sealing) handleCommitFailed(ctx statemachine.Context, sector SectorInfo) error {
+	committed, err := m.checkCommitted(ctx, sector)
+	if err != nil {
+		log.Errorf("handleCommitFailed(%d): api error, not proceeding: %+v", sector.SectorID, err)
+		return nil
+	}
+
+	if committed {
+		if sector.CommitMessage == nil {
+			log.Warnf("sector %d is committed on chain, but we don't have the commit message", sector.SectorID)
+			return ctx.Send(SectorProving{})
+		}
+
+		// the proof is on chain, only waiting for the message failed
+		return retry(ctx, sector, SectorRetryCommitWait{})
+	}
+
+	return m.retryCommit(ctx, sector)
+}
+
+// retryCommit recomputes the proof if the sector is still pre-committed,
+// and pre-commits it again otherwise
+func (m *Sealing) retryCommit(ctx statemachine.Context, sector SectorInfo) error {
+	pci, is := m.checkPreCommitted(ctx, sector)
+	if !is {
+		log.Warnf("sector %d is no longer pre-committed on chain, retrying precommit", sector.SectorID)
+		return retry(ctx, sector, SectorRetryPreCommit{})
+	}
+	if pci == nil {
+		return nil // api error, logged in checkPreCommitted
+	}
+
+	if string(pci.Info.CommR) != string(sector.CommR) {
+		log.Warnf("sector %d is precommitted on chain, with different CommR: %x != %x", sector.SectorID, pci.Info.CommR, sector.CommR)
+		return nil // TODO: remove when the actor allows re-precommit
+	}
+
+	if len(sector.Seed.TicketBytes) == 0 {
+		return retry(ctx, sector, SectorRetryWaitSeed{})
+	}
+
+	return retry(ctx, sector, SectorRetryComputeProof{})
+}
+
+func (m *Sealing) handleFinalizeFailed(ctx statemachine.Context, sector SectorInfo) error {
+	return retry(ctx, sector, SectorRetryFinalize{})
 }
@@ -58,11 +58,12 @@ type Log struct {
 type SectorInfo struct {
 	State    api.SectorState
 	SectorID uint64
-	Nonce    uint64 // TODO: remove
+	Nonce    uint64 // failed step retries, reset when a step succeeds
 
 	// Packing
 
-	Pieces []Piece
+	Pieces         []Piece
+	WaitDealsStart uint64 // unix time at which the sector started accepting deals
 
 	// PreCommit
 	CommD  []byte
@@ -80,6 +81,7 @@ type SectorInfo struct {
 
 	// Faults
 	FaultReportMsg *cid.Cid
+	RecoveryMsg    *cid.Cid
 
 	// Debug
 	LastErr string
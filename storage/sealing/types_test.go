@@ -19,9 +19,10 @@ func TestSectorInfoSelialization(t *testing.T) {
 			Size:   5,
 			CommP:  []byte{3},
 		}},
-		CommD: []byte{32, 4},
-		CommR: nil,
-		Proof: nil,
+		WaitDealsStart: 456,
+		CommD:          []byte{32, 4},
+		CommR:          nil,
+		Proof:          nil,
 		Ticket: SealTicket{
 			BlockHeight: 345,
 			TicketBytes: []byte{87, 78, 7, 87},
@@ -48,6 +49,7 @@ func TestSectorInfoSelialization(t *testing.T) {
 	assert.Equal(t, si.SectorID, si2.SectorID)
 
 	assert.Equal(t, si.Pieces, si2.Pieces)
+	assert.Equal(t, si.WaitDealsStart, si2.WaitDealsStart)
 	assert.Equal(t, si.CommD, si2.CommD)
 	assert.Equal(t, si.Ticket, si2.Ticket)
 
@@ -45,6 +45,26 @@ func TestFillersFromRem(t *testing.T) {
 	}
 }
 
+func TestAlignmentGap(t *testing.T) {
+	ub := func(padded uint64) uint64 {
+		return sectorbuilder.UserBytesForSectorSize(padded)
+	}
+
+	assert.Equal(t, uint64(0), alignmentGap(0, ub(512)))
+	assert.Equal(t, uint64(0), alignmentGap(ub(128), ub(128)))
+	assert.Equal(t, uint64(0), alignmentGap(ub(256)+ub(256), ub(512)))
+
+	assert.Equal(t, ub(128), alignmentGap(ub(128), ub(256)))
+	assert.Equal(t, ub(128), alignmentGap(ub(128)+ub(256), ub(512)))
+	assert.Equal(t, ub(128)+ub(256), alignmentGap(ub(128), ub(512)))
+
+	// the gap can always be filled with filler pieces
+	gap := alignmentGap(ub(128), ub(1024))
+	fillers, err := fillersFromRem(gap)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{ub(128), ub(256), ub(512)}, fillers)
+}
+
 func TestFastPledge(t *testing.T) {
 	sz := uint64(16 << 20)
 
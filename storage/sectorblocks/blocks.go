@@ -24,6 +24,7 @@ import (
 	"github.com/filecoin-project/lotus/lib/padreader"
 	"github.com/filecoin-project/lotus/node/modules/dtypes"
 	"github.com/filecoin-project/lotus/storage"
+	"github.com/filecoin-project/lotus/storage/sched"
 )
 
 type SealSerialization uint8
@@ -39,7 +40,8 @@ var ErrNotFound = errors.New("not found")
 
 type SectorBlocks struct {
 	*storage.Miner
-	sb sectorbuilder.Interface
+	sb    sectorbuilder.Interface
+	sched *sched.Scheduler
 
 	intermediate blockstore.Blockstore // holds intermediate nodes TODO: consider combining with the staging blockstore
 
@@ -47,10 +49,11 @@ type SectorBlocks struct {
 	keyLk sync.Mutex
 }
 
-func NewSectorBlocks(miner *storage.Miner, ds dtypes.MetadataDS, sb sectorbuilder.Interface) *SectorBlocks {
+func NewSectorBlocks(miner *storage.Miner, ds dtypes.MetadataDS, sb sectorbuilder.Interface, sched *sched.Scheduler) *SectorBlocks {
 	sbc := &SectorBlocks{
 		Miner: miner,
 		sb:    sb,
+		sched: sched,
 
 		intermediate: blockstore.NewBlockstore(namespace.Wrap(ds, imBlocksPrefix)),
 
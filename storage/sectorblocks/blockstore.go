@@ -95,22 +95,29 @@ func (s *SectorBlockStore) Get(c cid.Cid) (blocks.Block, error) {
 
 	log.Infof("reading block %s from sector %d(+%d;%d)", c, best.SectorID, best.Offset, best.Size)
 
-	r, err := s.sectorBlocks.sb.ReadPieceFromSealedSector(
-		context.TODO(),
-		best.SectorID,
-		best.Offset,
-		best.Size,
-		bestSi.Ticket.TicketBytes,
-		bestSi.CommD,
-	)
-	if err != nil {
-		return nil, xerrors.Errorf("unsealing block: %w", err)
-	}
-	defer r.Close()
+	var data []byte
+	err = s.sectorBlocks.sched.Schedule(context.TODO(), api.TaskUnseal, best.SectorID, func(ctx context.Context) error {
+		r, err := s.sectorBlocks.sb.ReadPieceFromSealedSector(
+			ctx,
+			best.SectorID,
+			best.Offset,
+			best.Size,
+			bestSi.Ticket.TicketBytes,
+			bestSi.CommD,
+		)
+		if err != nil {
+			return xerrors.Errorf("unsealing block: %w", err)
+		}
+		defer r.Close()
 
-	data, err := ioutil.ReadAll(r)
+		data, err = ioutil.ReadAll(r)
+		if err != nil {
+			return xerrors.Errorf("reading block data: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, xerrors.Errorf("reading block data: %w", err)
+		return nil, err
 	}
 	if uint64(len(data)) != best.Size {
 		return nil, xerrors.Errorf("got wrong amount of data: %d != !d", len(data), best.Size)
@@ -0,0 +1,472 @@
+package stores
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/filecoin-project/go-sectorbuilder/fs"
+	"github.com/ipfs/go-datastore"
+	"github.com/mitchellh/go-homedir"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/lib/checksum"
+)
+
+const partSuffix = ".part"
+
+// sectorPaths finds sector data in the storage paths of the sectorbuilder
+type sectorPaths interface {
+	SectorPath(typ fs.DataType, sectorID uint64) (fs.SectorPath, error)
+}
+
+// Manager attaches and detaches storage paths at runtime, and moves sealed
+// sectors between them.
+//
+// The sectorbuilder only picks up paths when it starts, so sectors moved to a
+// path it doesn't know yet are linked from their old location. Each file is
+// replaced with a link atomically, so that proofs and unsealing always find
+// complete sector data. The links are removed by PruneLinks on the next start.
+type Manager struct {
+	ds datastore.Batching
+	sb sectorPaths
+
+	lk     sync.Mutex
+	paths  []fs.PathConfig
+	active map[string]bool // paths the sectorbuilder was started with
+	moving map[string]int  // destination path -> running moves
+
+	moveLk sync.Mutex
+}
+
+func New(ds datastore.Batching, sb sectorPaths, paths []fs.PathConfig) *Manager {
+	m := &Manager{
+		ds: ds,
+		sb: sb,
+
+		paths:  append([]fs.PathConfig{}, paths...),
+		active: map[string]bool{},
+		moving: map[string]int{},
+	}
+	for _, p := range paths {
+		m.active[p.Path] = true
+	}
+	return m
+}
+
+// Attach adds a storage path. Sectors can be moved to the path right away,
+// the sectorbuilder allocates new sectors in it after a restart.
+func (m *Manager) Attach(cfg fs.PathConfig) error {
+	var err error
+	cfg.Path, err = absPath(cfg.Path)
+	if err != nil {
+		return err
+	}
+
+	fi, err := os.Stat(cfg.Path)
+	if err != nil {
+		return xerrors.Errorf("opening storage path: %w", err)
+	}
+	if !fi.IsDir() {
+		return xerrors.Errorf("storage path %s is not a directory", cfg.Path)
+	}
+
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	if _, ok := m.findLocked(cfg.Path); ok {
+		return xerrors.Errorf("storage path %s already attached", cfg.Path)
+	}
+
+	for _, typ := range sectorTypes {
+		if err := os.MkdirAll(filepath.Join(cfg.Path, string(typ)), 0755); err != nil {
+			return err
+		}
+	}
+
+	sp, err := loadPaths(m.ds)
+	if err != nil {
+		return err
+	}
+	sp.attach(cfg)
+	if err := sp.save(m.ds); err != nil {
+		return xerrors.Errorf("saving storage paths: %w", err)
+	}
+
+	log.Infof("attached storage path %s", cfg.Path)
+	m.paths = append(m.paths, cfg)
+	return nil
+}
+
+// Detach removes a storage path which holds no sector data. While the miner
+// runs, the sectorbuilder may still allocate sectors in the path; a path
+// holding sector data on the next start is kept.
+func (m *Manager) Detach(path string) error {
+	path, err := absPath(path)
+	if err != nil {
+		return err
+	}
+
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	i, ok := m.findLocked(path)
+	if !ok {
+		return xerrors.Errorf("storage path %s not attached", path)
+	}
+	if len(m.paths) == 1 {
+		return xerrors.Errorf("can't detach the last storage path")
+	}
+	if m.moving[path] > 0 {
+		return xerrors.Errorf("sectors are being moved to %s", path)
+	}
+
+	has, err := hasSectorData(path)
+	if err != nil {
+		return xerrors.Errorf("checking storage path: %w", err)
+	}
+	if has {
+		return xerrors.Errorf("storage path %s holds sector data, move the sectors to another path first", path)
+	}
+
+	sp, err := loadPaths(m.ds)
+	if err != nil {
+		return err
+	}
+	sp.detach(path)
+	if err := sp.save(m.ds); err != nil {
+		return xerrors.Errorf("saving storage paths: %w", err)
+	}
+
+	log.Infof("detached storage path %s", path)
+	m.paths = append(m.paths[:i], m.paths[i+1:]...)
+	return nil
+}
+
+// List returns storage paths with their disk usage
+func (m *Manager) List() ([]api.StoragePathInfo, error) {
+	m.lk.Lock()
+	paths := append([]fs.PathConfig{}, m.paths...)
+	active := map[string]bool{}
+	for p := range m.active {
+		active[p] = true
+	}
+	m.lk.Unlock()
+
+	out := make([]api.StoragePathInfo, len(paths))
+	for i, p := range paths {
+		info := api.StoragePathInfo{
+			Path:   p.Path,
+			Weight: p.Weight,
+			Cache:  p.Cache,
+			Active: active[p.Path],
+		}
+
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(p.Path, &st); err != nil {
+			return nil, xerrors.Errorf("statfs %s: %w", p.Path, err)
+		}
+		info.Capacity = uint64(st.Blocks) * uint64(st.Bsize)
+		info.Available = uint64(st.Bavail) * uint64(st.Bsize)
+
+		for _, typ := range sectorTypes {
+			dir := filepath.Join(p.Path, string(typ))
+			err := filepath.Walk(dir, func(f string, fi os.FileInfo, err error) error {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				if !fi.Mode().IsRegular() {
+					return nil
+				}
+
+				info.Used += uint64(fi.Size())
+				if typ == fs.DataSealed && filepath.Dir(f) == dir {
+					if id, ok := sectorID(fi.Name()); ok {
+						info.Sectors = append(info.Sectors, id)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, xerrors.Errorf("getting usage of %s: %w", dir, err)
+			}
+		}
+
+		sort.Slice(info.Sectors, func(i, j int) bool {
+			return info.Sectors[i] < info.Sectors[j]
+		})
+		out[i] = info
+	}
+
+	return out, nil
+}
+
+// Move moves the sealed sector and cache of a sector to a storage path.
+// Progress is reported in bytes copied.
+func (m *Manager) Move(ctx context.Context, sectorID uint64, path string, progress func(done, size uint64)) error {
+	path, err := absPath(path)
+	if err != nil {
+		return err
+	}
+
+	m.lk.Lock()
+	_, ok := m.findLocked(path)
+	if ok {
+		m.moving[path]++
+	}
+	m.lk.Unlock()
+	if !ok {
+		return xerrors.Errorf("storage path %s not attached", path)
+	}
+
+	defer func() {
+		m.lk.Lock()
+		m.moving[path]--
+		m.lk.Unlock()
+	}()
+
+	m.moveLk.Lock()
+	defer m.moveLk.Unlock()
+
+	root, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+
+	var moves []fileMove
+	for _, typ := range []fs.DataType{fs.DataSealed, fs.DataCache} {
+		sp, err := m.sb.SectorPath(typ, sectorID)
+		if err != nil {
+			return xerrors.Errorf("finding %s data of sector %d: %w", typ, sectorID, err)
+		}
+
+		mv, err := planMoves(string(sp), filepath.Join(root, string(typ), filepath.Base(string(sp))))
+		if err != nil {
+			return xerrors.Errorf("planning move of %s data of sector %d: %w", typ, sectorID, err)
+		}
+		moves = append(moves, mv...)
+	}
+
+	var size uint64
+	for _, mv := range moves {
+		size += mv.size
+	}
+
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(root, &st); err != nil {
+		return xerrors.Errorf("statfs %s: %w", root, err)
+	}
+	if avail := uint64(st.Bavail) * uint64(st.Bsize); avail < size {
+		return xerrors.Errorf("not enough space in %s: %d bytes needed, %d available", path, size, avail)
+	}
+
+	log.Infof("moving sector %d to %s (%d files, %d bytes)", sectorID, path, len(moves), size)
+
+	var done uint64
+	for _, mv := range moves {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := mv.run(func(n uint64) {
+			progress(done+n, size)
+		})
+		if err != nil {
+			return xerrors.Errorf("moving %s: %w", mv.src, err)
+		}
+		done += mv.size
+	}
+
+	return nil
+}
+
+// Remove deletes all data of a sector from the storage paths, including
+// links left by moves and partially copied files
+func (m *Manager) Remove(id uint64) error {
+	m.moveLk.Lock()
+	defer m.moveLk.Unlock()
+
+	m.lk.Lock()
+	paths := append([]fs.PathConfig{}, m.paths...)
+	m.lk.Unlock()
+
+	var removed int
+	for _, p := range paths {
+		for _, typ := range sectorTypes {
+			dir := filepath.Join(p.Path, string(typ))
+			entries, err := readDir(dir)
+			if err != nil {
+				return xerrors.Errorf("reading %s: %w", dir, err)
+			}
+
+			for _, fi := range entries {
+				sid, ok := sectorID(strings.TrimSuffix(fi.Name(), partSuffix))
+				if !ok || sid != id {
+					continue
+				}
+
+				if err := os.RemoveAll(filepath.Join(dir, fi.Name())); err != nil {
+					return xerrors.Errorf("removing %s data of sector %d: %w", typ, id, err)
+				}
+				removed++
+			}
+		}
+	}
+
+	log.Infof("removed %d files of sector %d", removed, id)
+	return nil
+}
+
+func (m *Manager) findLocked(path string) (int, bool) {
+	for i, p := range m.paths {
+		if p.Path == path {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func absPath(path string) (string, error) {
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(path)
+}
+
+// sectorID parses the ID from a sector file name, like s-t01000-1
+func sectorID(name string) (uint64, bool) {
+	i := strings.LastIndexByte(name, '-')
+	if i < 0 {
+		return 0, false
+	}
+
+	id, err := strconv.ParseUint(name[i+1:], 10, 64)
+	return id, err == nil
+}
+
+// fileMove moves a sector file, replacing it with a link to its new location
+type fileMove struct {
+	src  string // with links resolved
+	dst  string
+	size uint64
+}
+
+// planMoves lists files to move from src, a sector file or directory, to
+// dst. Files already in place are skipped.
+func planMoves(src string, dst string) ([]fileMove, error) {
+	resolved, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(resolved)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		if resolved == dst {
+			return nil, nil
+		}
+		return []fileMove{{src: resolved, dst: dst, size: uint64(fi.Size())}}, nil
+	}
+
+	entries, err := readDir(resolved)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []fileMove
+	for _, e := range entries {
+		mv, err := planMoves(filepath.Join(resolved, e.Name()), filepath.Join(dst, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, mv...)
+	}
+	return out, nil
+}
+
+func (mv fileMove) run(progress func(uint64)) error {
+	if err := os.MkdirAll(filepath.Dir(mv.dst), 0755); err != nil {
+		return err
+	}
+
+	sums, err := checksum.Files(mv.src)
+	if err != nil {
+		return err
+	}
+
+	part := mv.dst + partSuffix
+	if err := copyFile(mv.src, part, progress); err != nil {
+		return xerrors.Errorf("copying to %s: %w", part, err)
+	}
+
+	if err := checksum.Verify(part, sums); err != nil {
+		if rerr := os.Remove(part); rerr != nil {
+			log.Errorf("removing corrupt copy %s: %+v", part, rerr)
+		}
+		return err
+	}
+
+	if err := os.Rename(part, mv.dst); err != nil {
+		return err
+	}
+
+	// replace the file with a link in one rename, so that it can be opened at
+	// any point
+	link := mv.src + ".link"
+	if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Symlink(mv.dst, link); err != nil {
+		return err
+	}
+	return os.Rename(link, mv.src)
+}
+
+func copyFile(src, dst string, progress func(uint64)) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(&progressWriter{w: out, progress: progress}, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+type progressWriter struct {
+	w        io.Writer
+	n        uint64
+	progress func(uint64)
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.n += uint64(n)
+	pw.progress(pw.n)
+	return n, err
+}
@@ -0,0 +1,178 @@
+package stores
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/filecoin-project/go-sectorbuilder/fs"
+	"github.com/ipfs/go-datastore"
+)
+
+type testPaths string
+
+func (tp testPaths) SectorPath(typ fs.DataType, sectorID uint64) (fs.SectorPath, error) {
+	return fs.SectorPath(filepath.Join(string(tp), string(typ), "s-t01000-1")), nil
+}
+
+func writeFile(t *testing.T, path string, data string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkFile(t *testing.T, path string, data string, link bool) {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != data {
+		t.Fatalf("%s: expected %q, got %q", path, data, b)
+	}
+
+	fi, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isLink := fi.Mode()&os.ModeSymlink != 0; isLink != link {
+		t.Fatalf("%s: expected link %t, got %t", path, link, isLink)
+	}
+}
+
+func TestMoveSector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stores")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+
+	writeFile(t, filepath.Join(a, "sealed", "s-t01000-1"), "sealed")
+	writeFile(t, filepath.Join(a, "cache", "s-t01000-1", "p_aux"), "p_aux")
+	writeFile(t, filepath.Join(a, "cache", "s-t01000-1", "t_aux"), "t_aux")
+	if err := os.MkdirAll(b, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ds := datastore.NewMapDatastore()
+	m := New(ds, testPaths(a), []fs.PathConfig{{Path: a, Weight: 1}})
+
+	if err := m.Attach(fs.PathConfig{Path: b, Weight: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Attach(fs.PathConfig{Path: b}); err == nil {
+		t.Fatal("expected attaching a path twice to fail")
+	}
+
+	var progress uint64
+	err = m.Move(context.Background(), 1, b, func(done, size uint64) {
+		if size != 16 {
+			t.Errorf("expected size 16, got %d", size)
+		}
+		progress = done
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress != 16 {
+		t.Fatalf("expected progress 16, got %d", progress)
+	}
+
+	// the sectorbuilder still finds the data in the old path
+	checkFile(t, filepath.Join(a, "sealed", "s-t01000-1"), "sealed", true)
+	checkFile(t, filepath.Join(a, "cache", "s-t01000-1", "p_aux"), "p_aux", true)
+	checkFile(t, filepath.Join(b, "sealed", "s-t01000-1"), "sealed", false)
+	checkFile(t, filepath.Join(b, "cache", "s-t01000-1", "t_aux"), "t_aux", false)
+
+	list, err := m.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || !list[0].Active || list[0].Used != 0 || list[1].Active || list[1].Used != 16 || len(list[1].Sectors) != 1 {
+		t.Fatalf("unexpected paths: %+v", list)
+	}
+
+	if err := m.Detach(b); err == nil {
+		t.Fatal("expected detaching a path with sector data to fail")
+	}
+	if err := m.Detach(a); err != nil {
+		t.Fatal(err)
+	}
+
+	// after a restart, the sectorbuilder only uses the new path
+	paths, err := Paths(ds, []fs.PathConfig{{Path: a, Weight: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0].Path != b {
+		t.Fatalf("unexpected paths: %+v", paths)
+	}
+
+	// with both paths used, links are removed
+	if err := PruneLinks([]fs.PathConfig{{Path: a}, {Path: b}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{filepath.Join(a, "sealed", "s-t01000-1"), filepath.Join(a, "cache", "s-t01000-1")} {
+		if _, err := os.Lstat(p); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be removed, got %v", p, err)
+		}
+	}
+	checkFile(t, filepath.Join(b, "cache", "s-t01000-1", "p_aux"), "p_aux", false)
+}
+
+func TestRemoveSector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stores")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+
+	writeFile(t, filepath.Join(a, "sealed", "s-t01000-1"), "sealed")
+	writeFile(t, filepath.Join(a, "cache", "s-t01000-1", "p_aux"), "p_aux")
+	writeFile(t, filepath.Join(a, "staging", "s-t01000-1"), "staged")
+	writeFile(t, filepath.Join(a, "sealed", "s-t01000-12"), "other")
+	if err := os.MkdirAll(b, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(datastore.NewMapDatastore(), testPaths(a), []fs.PathConfig{{Path: a, Weight: 1}})
+	if err := m.Attach(fs.PathConfig{Path: b, Weight: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Move(context.Background(), 1, b, func(done, size uint64) {}); err != nil {
+		t.Fatal(err)
+	}
+	// left by an interrupted move
+	writeFile(t, filepath.Join(b, "unsealed", "s-t01000-1.part"), "unseal")
+
+	if err := m.Remove(1); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []string{
+		filepath.Join(a, "sealed", "s-t01000-1"),
+		filepath.Join(a, "cache", "s-t01000-1"),
+		filepath.Join(a, "staging", "s-t01000-1"),
+		filepath.Join(b, "sealed", "s-t01000-1"),
+		filepath.Join(b, "unsealed", "s-t01000-1.part"),
+		filepath.Join(b, "cache", "s-t01000-1"),
+	} {
+		if _, err := os.Lstat(p); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be removed, got %v", p, err)
+		}
+	}
+	checkFile(t, filepath.Join(a, "sealed", "s-t01000-12"), "other", false)
+}
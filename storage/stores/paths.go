@@ -0,0 +1,251 @@
+package stores
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/filecoin-project/go-sectorbuilder/fs"
+	"github.com/ipfs/go-datastore"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+)
+
+var log = logging.Logger("stores")
+
+var pathsKey = datastore.NewKey("/storage/paths")
+
+// sectorTypes are the directories the sectorbuilder keeps in each storage
+// path
+var sectorTypes = []fs.DataType{fs.DataCache, fs.DataStaging, fs.DataSealed, fs.DataUnsealed}
+
+// storedPaths records paths attached and detached at runtime, on top of the
+// paths in the config file
+type storedPaths struct {
+	Attached []fs.PathConfig
+	Detached []string
+}
+
+func loadPaths(ds datastore.Batching) (storedPaths, error) {
+	var out storedPaths
+
+	b, err := ds.Get(pathsKey)
+	if err == datastore.ErrNotFound {
+		return out, nil
+	}
+	if err != nil {
+		return out, xerrors.Errorf("getting storage paths: %w", err)
+	}
+
+	if err := json.Unmarshal(b, &out); err != nil {
+		return out, xerrors.Errorf("decoding storage paths: %w", err)
+	}
+	return out, nil
+}
+
+func (sp storedPaths) save(ds datastore.Batching) error {
+	b, err := json.Marshal(sp)
+	if err != nil {
+		return err
+	}
+	return ds.Put(pathsKey, b)
+}
+
+func (sp *storedPaths) attach(cfg fs.PathConfig) {
+	sp.Attached = append(removePath(sp.Attached, cfg.Path), cfg)
+	sp.Detached = removeString(sp.Detached, cfg.Path)
+}
+
+func (sp *storedPaths) detach(path string) {
+	sp.Attached = removePath(sp.Attached, path)
+	sp.Detached = append(removeString(sp.Detached, path), path)
+}
+
+// Paths returns the storage paths the sectorbuilder should use: paths from
+// the config file, without the ones detached at runtime, and with the ones
+// attached at runtime. Detached paths which still hold sector data are kept,
+// so that no sectors are lost.
+func Paths(ds datastore.Batching, config []fs.PathConfig) ([]fs.PathConfig, error) {
+	sp, err := loadPaths(ds)
+	if err != nil {
+		return nil, err
+	}
+
+	detached := map[string]bool{}
+	for _, p := range sp.Detached {
+		detached[p] = true
+	}
+
+	var out []fs.PathConfig
+	seen := map[string]bool{}
+	for _, p := range append(append([]fs.PathConfig{}, config...), sp.Attached...) {
+		if seen[p.Path] {
+			continue
+		}
+		seen[p.Path] = true
+
+		if detached[p.Path] {
+			has, err := hasSectorData(p.Path)
+			if err != nil {
+				return nil, xerrors.Errorf("checking detached path %s: %w", p.Path, err)
+			}
+			if !has {
+				continue
+			}
+			log.Errorf("storage path %s was detached, but holds sector data; keeping it", p.Path)
+		}
+
+		out = append(out, p)
+	}
+
+	return out, nil
+}
+
+// PruneLinks removes links left in place of sector files moved to another
+// path, when the sectorbuilder finds the files in their new path. It must be
+// called before the sectorbuilder is started.
+func PruneLinks(paths []fs.PathConfig) error {
+	roots := make([]string, len(paths))
+	for i, p := range paths {
+		root, err := filepath.EvalSymlinks(p.Path)
+		if err != nil {
+			return xerrors.Errorf("resolving storage path %s: %w", p.Path, err)
+		}
+		roots[i] = root
+	}
+
+	for _, root := range roots {
+		for _, typ := range []fs.DataType{fs.DataSealed, fs.DataCache} {
+			dir := filepath.Join(root, string(typ))
+			entries, err := readDir(dir)
+			if err != nil {
+				return err
+			}
+
+			for _, e := range entries {
+				p := filepath.Join(dir, e.Name())
+				moved, err := movedTo(p, roots)
+				if err != nil {
+					return xerrors.Errorf("checking %s: %w", p, err)
+				}
+				if !moved {
+					continue
+				}
+
+				log.Infof("removing link to moved sector data %s", p)
+				if err := os.RemoveAll(p); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// movedTo checks if p is a link, or a directory of links, to sector data
+// stored under another one of the roots
+func movedTo(p string, roots []string) (bool, error) {
+	fi, err := os.Lstat(p)
+	if err != nil {
+		return false, err
+	}
+
+	if !fi.IsDir() {
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return false, nil
+		}
+
+		target, err := filepath.EvalSymlinks(p)
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return underAny(target, roots) && filepath.Base(target) == filepath.Base(p), nil
+	}
+
+	entries, err := readDir(p)
+	if err != nil {
+		return false, err
+	}
+	for _, e := range entries {
+		moved, err := movedTo(filepath.Join(p, e.Name()), roots)
+		if err != nil || !moved {
+			return false, err
+		}
+	}
+	return len(entries) > 0, nil
+}
+
+var errFound = xerrors.New("found")
+
+// hasSectorData checks if a storage path stores any sector files, not
+// counting links to files in other paths
+func hasSectorData(root string) (bool, error) {
+	for _, typ := range sectorTypes {
+		err := filepath.Walk(filepath.Join(root, string(typ)), func(p string, fi os.FileInfo, err error) error {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if fi.Mode().IsRegular() && !strings.HasSuffix(p, partSuffix) {
+				return errFound
+			}
+			return nil
+		})
+		if err == errFound {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+func readDir(dir string) ([]os.FileInfo, error) {
+	f, err := os.Open(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return f.Readdir(-1)
+}
+
+func underAny(p string, roots []string) bool {
+	for _, root := range roots {
+		if strings.HasPrefix(p, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func removePath(l []fs.PathConfig, path string) []fs.PathConfig {
+	out := l[:0]
+	for _, p := range l {
+		if p.Path != path {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func removeString(l []string, s string) []string {
+	out := l[:0]
+	for _, e := range l {
+		if e != s {
+			out = append(out, e)
+		}
+	}
+	return out
+}